@@ -0,0 +1,104 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import "fmt"
+
+const (
+	actorLabelPrefix = "actor:"
+	validActorTries  = 100
+)
+
+// Actors keeps track of dynamically created instances (like connections
+// or sessions) of a state machine test. Instances are identified by ordinals,
+// assigned in order of creation, which lets the shrinker remove whole instances
+// (together with every step which has created, used or destroyed them)
+// from the failing test case.
+//
+// Actors should be created in Init; the kind is used in the test output,
+// and should be unique within a state machine.
+type Actors struct {
+	kind string
+	live map[int]interface{}
+	next int
+}
+
+// NewActors creates an empty set of instances of the given kind.
+func NewActors(kind string) *Actors {
+	assertf(kind != "", "actor kind should not be empty")
+
+	return &Actors{
+		kind: kind,
+		live: map[int]interface{}{},
+	}
+}
+
+// Create adds a new instance v, and returns its ordinal.
+func (a *Actors) Create(t *T, v interface{}) int {
+	id := a.next
+	a.next++
+	a.live[id] = v
+	t.trace.actor(true, a.kind, id)
+
+	return id
+}
+
+// Destroy removes the instance with the given ordinal.
+func (a *Actors) Destroy(id int) {
+	delete(a.live, id)
+}
+
+// Get returns the instance with the given ordinal, and whether it is alive.
+func (a *Actors) Get(id int) (interface{}, bool) {
+	v, ok := a.live[id]
+	return v, ok
+}
+
+// Len returns the number of live instances.
+func (a *Actors) Len() int {
+	return len(a.live)
+}
+
+// Draw chooses one of the live instances, and returns its ordinal together
+// with the instance itself. When there are no live instances, Draw skips the
+// test case (in state machine tests, the current action) like SkipNow.
+func (a *Actors) Draw(t *T) (int, interface{}) {
+	if len(a.live) == 0 {
+		t.skip(fmt.Sprintf("no live %v", a.kind))
+	}
+
+	for n := 0; ; n++ {
+		if n == validActorTries {
+			t.skip(fmt.Sprintf("can't find a live %v", a.kind))
+		}
+
+		i := t.s.beginGroup(actorLabelPrefix+a.kind, false)
+		id := int(genUintNUnbiased(t.s, uint64(a.next-1)))
+		v, ok := a.live[id]
+		t.s.endGroup(i, !ok)
+		if ok {
+			t.trace.actor(false, a.kind, id)
+			if t.shouldLog() {
+				if t.tbLog && t.tb != nil {
+					t.tb.Helper()
+				}
+				t.Logf("[rapid] draw %v: %v", a.kind, id)
+			}
+			return id, v
+		}
+	}
+}
+
+// actorRef identifies an instance by its kind and ordinal.
+type actorRef struct {
+	kind string
+	id   int
+}
+
+func (r actorRef) String() string {
+	return fmt.Sprintf("%v=%v", r.kind, r.id)
+}
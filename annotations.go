@@ -0,0 +1,70 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const githubWorkspaceEnv = "GITHUB_WORKSPACE"
+
+var (
+	githubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// githubAnnotation formats the failure of the check as a GitHub Actions error
+// annotation (workflow command), which shows it inline on the diff of the pull
+// request: at the location of the failure in the property, with the minimized
+// test case.
+func githubAnnotation(name string, meta PropertyMetadata, valid int, err1 *testError, err2 *testError, repro string, draws []drawnValue, maxValueLen int) string {
+	var msg string
+	switch {
+	case traceback(err1) != traceback(err2):
+		msg = fmt.Sprintf("flaky test, can not reproduce a failure: %v", err2)
+	case err2.isStopTest():
+		msg = fmt.Sprintf("failed after %v tests: %v", valid, err2)
+	default:
+		msg = fmt.Sprintf("panic after %v tests: %v", valid, err2)
+	}
+	if !meta.empty() {
+		msg += "\nProperty: " + meta.String()
+	}
+	msg += "\nTo reproduce, specify " + repro
+	if len(draws) > 0 {
+		msg += "\nMinimized test case:"
+		for _, d := range draws {
+			msg += fmt.Sprintf("\n%v: %v", d.label, d.truncated(maxValueLen))
+		}
+	}
+
+	props := []string{"title=" + githubPropertyEscaper.Replace("[rapid] "+name)}
+	if err2.file != "" {
+		props = append(props,
+			"file="+githubPropertyEscaper.Replace(workspacePath(err2.file)),
+			fmt.Sprintf("line=%d", err2.line))
+	}
+
+	return fmt.Sprintf("::error %v::%v\n", strings.Join(props, ","), githubDataEscaper.Replace(msg))
+}
+
+// workspacePath makes the path relative to the GitHub Actions workspace
+// (the root of the repository), if it is inside of it.
+func workspacePath(path string) string {
+	ws := os.Getenv(githubWorkspaceEnv)
+	if ws == "" {
+		return path
+	}
+	rel, err := filepath.Rel(ws, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
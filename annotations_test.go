@@ -0,0 +1,56 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGitHubAnnotation(t *testing.T) {
+	prop := func(t *T) {
+		n := IntRange(0, 100).Draw(t, "n").(int)
+		if n > 10 {
+			t.Fatalf("n = %v,\n100%% wrong", n)
+		}
+	}
+	_, file, line, _ := runtime.Caller(0)
+	line -= 3
+
+	_, _, _, buf, err1, err2 := doCheck(t, newSettings(nil), baseSeed(), prop)
+	if err2 == nil {
+		t.Fatalf("no failure found")
+	}
+	draws, _ := topLevelDraws(t, prop, buf)
+	a := githubAnnotation("TestX/a,b", PropertyMetadata{}, 3, err1, err2, "-run=TestX", draws, 0)
+
+	want := fmt.Sprintf("::error title=[rapid] TestX/a%%2Cb,file=%v,line=%v::failed after 3 tests: n = 11,%%0A100%%25 wrong%%0ATo reproduce, specify -run=TestX%%0AMinimized test case:%%0An: 11\n",
+		strings.ReplaceAll(file, ":", "%3A"), line)
+	if a != want {
+		t.Errorf("got annotation\n%q\nwant\n%q", a, want)
+	}
+}
+
+func TestWorkspacePath(t *testing.T) {
+	ws := os.Getenv(githubWorkspaceEnv)
+	defer os.Setenv(githubWorkspaceEnv, ws)
+
+	root := filepath.Join(string(filepath.Separator), "src", "repo")
+	_ = os.Setenv(githubWorkspaceEnv, root)
+	for path, want := range map[string]string{
+		filepath.Join(root, "pkg", "a_test.go"): "pkg/a_test.go",
+		filepath.Join(root, "..", "a_test.go"):  filepath.Join(root, "..", "a_test.go"),
+	} {
+		if got := workspacePath(path); got != want {
+			t.Errorf("workspacePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"math"
+	"reflect"
+)
+
+const (
+	cardinalitySamples  = 200
+	cardinalityCoverage = 0.9
+)
+
+// cardinalityEstimator is implemented by the generators which know the size of their domain.
+type cardinalityEstimator interface {
+	cardinality() float64
+}
+
+// EstimateCardinality returns the number of distinct values g can produce:
+// exact for the finite domains of the built-in generators (like IntRange or
+// SampledFrom), and +Inf for the unbounded ones (like SliceOf). The domains
+// of Custom and other opaque generators, and the part of the domain a Filter
+// leaves, are estimated from a sample of the values, up to an order of magnitude.
+// For example, a Filter which leaves an implausibly tiny domain has a tiny
+// cardinality, and checks with more test cases than the cardinality of the
+// generators of the property repeat the test cases.
+func (g *Generator) EstimateCardinality() float64 {
+	if e, ok := g.impl.(cardinalityEstimator); ok {
+		return e.cardinality()
+	}
+	n, _, _ := sampleCardinality(g, nil)
+	return n
+}
+
+// sampleCardinality estimates the number of distinct values g produces (which
+// satisfy accept, if it is not nil) from the frequencies of the sampled ones,
+// using the Chao1 estimator. Like the checks, it gives up on the values which
+// are rarely accepted after a number of tries. It also returns the ratio of the sampled values
+// which have been accepted, and the Good-Turing estimate of the share of the
+// accepted values (by probability) the sample has covered.
+func sampleCardinality(g *Generator, accept func(value) bool) (float64, float64, float64) {
+	t := newT(nil, newRandomBitStream(baseSeed(), false), false, nil)
+	counts := map[string]int{}
+	tries, accepted := 0, 0
+	for ; accepted < cardinalitySamples && tries < cardinalitySamples*invalidChecksMult; tries++ {
+		v, err := recoverValue(g, t)
+		if err == nil && (accept == nil || accept(v)) {
+			counts[printValue(v)]++
+			accepted++
+		}
+	}
+
+	f1, f2 := 0, 0
+	for _, n := range counts {
+		switch n {
+		case 1:
+			f1++
+		case 2:
+			f2++
+		}
+	}
+	n := float64(len(counts)) + float64(f1*(f1-1))/float64(2*(f2+1))
+	coverage := 0.0
+	if accepted > 0 {
+		coverage = 1 - float64(f1)/float64(accepted)
+	}
+	return n, float64(accepted) / float64(tries), coverage
+}
+
+// sumPowers returns the number of sequences of n elements, for n between
+// minLen and maxLen (unbounded if negative); every element is one of c values,
+// distinct from the previous ones if distinct is set.
+func sumPowers(c float64, minLen int, maxLen int, distinct bool) float64 {
+	if minLen < 0 {
+		minLen = 0
+	}
+	if c <= 1 && !distinct && maxLen >= 0 {
+		if c == 0 {
+			return float64(1 - minLen)
+		}
+		return float64(maxLen - minLen + 1)
+	}
+	if distinct && (maxLen < 0 || float64(maxLen) > c) {
+		maxLen = int(math.Min(c, math.MaxInt32))
+	}
+	if maxLen < 0 {
+		if c == 0 {
+			return 1
+		}
+		return math.Inf(1)
+	}
+
+	sum, p := 0.0, 1.0
+	for n := 0; n <= maxLen && !math.IsInf(sum, 1); n++ {
+		if n >= minLen {
+			sum += p
+		}
+		if distinct {
+			p *= c - float64(n)
+		} else {
+			p *= c
+		}
+	}
+	return sum
+}
+
+// distinctValues returns the number of distinct values in the slice.
+func distinctValues(slice reflect.Value) float64 {
+	seen := map[string]bool{}
+	for i := 0; i < slice.Len(); i++ {
+		seen[printValue(slice.Index(i).Interface())] = true
+	}
+	return float64(len(seen))
+}
+
+func (g *boolGen) cardinality() float64 {
+	return 2
+}
+
+func (g *integerGen) cardinality() float64 {
+	if g.signed {
+		return float64(uint64(g.smax)-uint64(g.smin)) + 1
+	}
+	return float64(g.umax-g.umin) + 1
+}
+
+func (g *floatGen) cardinality() float64 {
+	if g.typ == float32Type {
+		return float64(orderedFloat32(float32(g.max))-orderedFloat32(float32(g.min))) + 1
+	}
+	return float64(orderedFloat64(g.max)-orderedFloat64(g.min)) + 1
+}
+
+// orderedFloat64 maps the floats to integers in the same order.
+func orderedFloat64(f float64) uint64 {
+	b := math.Float64bits(f)
+	if f < 0 || (f == 0 && math.Signbit(f)) {
+		return ^b
+	}
+	return b | 1<<63
+}
+
+func orderedFloat32(f float32) uint32 {
+	b := math.Float32bits(f)
+	if f < 0 || (f == 0 && math.Signbit(float64(f))) {
+		return ^b
+	}
+	return b | 1<<31
+}
+
+func (g *runeGen) cardinality() float64 {
+	seen := map[rune]bool{}
+	for _, r := range g.runes {
+		seen[r] = true
+	}
+	for _, table := range g.tables {
+		for _, r := range table {
+			seen[r] = true
+		}
+	}
+	return float64(len(seen))
+}
+
+func (g *stringGen) cardinality() float64 {
+	maxElems := g.maxElems
+	if maxElems < 0 || (g.maxLen >= 0 && g.maxLen < maxElems) {
+		maxElems = g.maxLen // every element takes at least a byte
+	}
+	return sumPowers(g.elem.EstimateCardinality(), g.minElems, maxElems, false)
+}
+
+func (g *sliceGen) cardinality() float64 {
+	return sumPowers(g.elem.EstimateCardinality(), g.minLen, g.maxLen, g.keyTyp != nil)
+}
+
+func (g *arrayGen) cardinality() float64 {
+	return math.Pow(g.elem.EstimateCardinality(), float64(g.count))
+}
+
+func (g *mapGen) cardinality() float64 {
+	if g.keyTyp != nil {
+		// sets of values with distinct keys, like SliceOfNDistinct up to the order
+		n := g.val.EstimateCardinality()
+		return sumBinomials(n, 1, g.minLen, g.maxLen)
+	}
+	return sumBinomials(g.key.EstimateCardinality(), g.val.EstimateCardinality(), g.minLen, g.maxLen)
+}
+
+// sumBinomials returns the number of maps of n elements, for n between minLen
+// and maxLen (unbounded if negative), with keys from the k values and the values
+// from the v values.
+func sumBinomials(k float64, v float64, minLen int, maxLen int) float64 {
+	if minLen < 0 {
+		minLen = 0
+	}
+	if maxLen < 0 || float64(maxLen) > k {
+		maxLen = int(math.Min(k, math.MaxInt32))
+	}
+
+	sum, p := 0.0, 1.0 // p is the number of maps of size n
+	for n := 0; n <= maxLen && !math.IsInf(sum, 1); n++ {
+		if n >= minLen {
+			sum += p
+		}
+		p *= (k - float64(n)) / float64(n+1) * v
+	}
+	return sum
+}
+
+func (g *sampledGen) cardinality() float64 {
+	return distinctValues(g.slice)
+}
+
+func (g *oneOfGen) cardinality() float64 {
+	sum := 0.0
+	for _, gen := range g.gens {
+		sum += gen.EstimateCardinality()
+	}
+	return sum
+}
+
+func (g *ptrGen) cardinality() float64 {
+	n := g.elem.EstimateCardinality()
+	if g.allowNil {
+		n++
+	}
+	return n
+}
+
+func (g *mappedGen) cardinality() float64 {
+	return g.g.EstimateCardinality() // the function can only merge the values
+}
+
+func (g *filteredGen) cardinality() float64 {
+	accepted, ratio, coverage := sampleCardinality(g.g, g.fn)
+	n := g.g.EstimateCardinality() * ratio
+	if coverage >= cardinalityCoverage && accepted < n {
+		return accepted // the sample has seen most of the domain
+	}
+	return n
+}
+
+func (g *shrinkHintGen) cardinality() float64 {
+	return g.g.EstimateCardinality()
+}
+
+func (g *sensitiveGen) cardinality() float64 {
+	return g.g.EstimateCardinality()
+}
+
+func (g *faultGen) cardinality() float64 {
+	faults := append([]Fault{FaultNone}, g.faults...)
+	return distinctValues(reflect.ValueOf(faults))
+}
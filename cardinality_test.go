@@ -0,0 +1,60 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateCardinality(t *testing.T) {
+	t.Parallel()
+
+	inf := math.Inf(1)
+	for _, tt := range []struct {
+		g    *Generator
+		want float64
+	}{
+		{Bool(), 2},
+		{IntRange(0, 9), 10},
+		{Uint8(), 256},
+		{Int8Range(-128, 127), 256},
+		{SampledFrom([]int{1, 2, 2, 3}), 3},
+		{Just(1), 1},
+		{OneOf(IntRange(0, 1), Just(5)), 3},
+		{Ptr(Bool(), true), 3},
+		{SliceOfN(Bool(), 0, 2), 1 + 2 + 4},
+		{SliceOfNDistinct(IntRange(0, 2), 2, 2, nil), 6},
+		{MapOfN(IntRange(0, 1), Bool(), -1, -1), 1 + 2*2 + 2*2},
+		{ArrayOf(3, Bool()), 8},
+		{StringOfN(RuneFrom([]rune("ab")), 0, 2, -1), 7},
+		{IntRange(0, 9).Map(func(i int) int { return i / 2 }), 10},
+		{SliceOf(Bool()), inf},
+		{String(), inf},
+	} {
+		if got := tt.g.EstimateCardinality(); got != tt.want {
+			t.Errorf("%v: got cardinality %v, want %v", tt.g, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateCardinality_Sampled(t *testing.T) {
+	t.Parallel()
+
+	tiny := Int().Filter(func(i int) bool { return i >= 0 && i < 3 })
+	if n := tiny.EstimateCardinality(); n > 10 {
+		t.Errorf("%v: got cardinality %v, want about 3", tiny, n)
+	}
+	if n := Int().Filter(isEven).EstimateCardinality(); n < 1e18 {
+		t.Errorf("cardinality of the even ints is %v", n)
+	}
+
+	small := Custom(func(t *T) int { return IntRange(0, 4).Draw(t, "i").(int) * 2 })
+	if n := small.EstimateCardinality(); n < 4 || n > 7 {
+		t.Errorf("%v: got cardinality %v, want about 5", small, n)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package clock provides a fake clock for property-based testing of
+// time-dependent code (timeouts, TTL caches, rate limiters and the like).
+//
+// Code under test should read the time from a Clock: Real in production,
+// and a Fake driven by rapid in tests. The Fake clock moves by a drawn amount
+// every time it is read: it can stand still, tick, leap forward, or even
+// go backwards (like a wall clock adjusted by NTP). Failing test cases are
+// minimized towards a clock which moves as little as possible.
+package clock
+
+import (
+	"time"
+
+	"pgregory.net/rapid"
+)
+
+// Clock is the source of the current time.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+}
+
+// Real is the system clock.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                  { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration { return time.Since(t) }
+
+// Start is the time Fake clocks start at.
+var Start = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// advances draws how much the clock moves between two readings: most often it ticks
+// (by up to a millisecond), sometimes it is frozen, leaps forward (by up to an hour)
+// or jumps backwards (by up to a second).
+var advances = rapid.Custom(func(t *rapid.T) time.Duration {
+	switch n := rapid.IntRange(0, 19).Draw(t, "n").(int); {
+	case n < 3:
+		return 0
+	case n < 16:
+		return time.Duration(rapid.Int64Range(1, int64(time.Millisecond)).Draw(t, "tick").(int64))
+	case n < 19:
+		return time.Duration(rapid.Int64Range(1, int64(time.Hour)).Draw(t, "leap").(int64))
+	default:
+		return -time.Duration(rapid.Int64Range(1, int64(time.Second)).Draw(t, "jump").(int64))
+	}
+})
+
+// Fake is a clock which moves by a drawn amount every time it is read.
+// It is not safe for concurrent use.
+type Fake struct {
+	t   *rapid.T
+	now time.Time
+}
+
+// NewFake creates a fake clock, which starts at Start and draws its movement from t.
+func NewFake(t *rapid.T) *Fake {
+	return &Fake{
+		t:   t,
+		now: Start,
+	}
+}
+
+// Now advances the clock by a drawn amount, and returns the new time.
+func (c *Fake) Now() time.Time {
+	c.now = c.now.Add(advances.Draw(c.t, "clock").(time.Duration))
+	return c.now
+}
+
+// Since returns the time elapsed since t, like time.Since.
+func (c *Fake) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Advance moves the clock by d (e.g. to simulate a sleep) without drawing.
+func (c *Fake) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,55 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+)
+
+func TestFake_Advances(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		c := NewFake(t)
+		prev := Start
+		for i := 0; i < 10; i++ {
+			now := c.Now()
+			if d := now.Sub(prev); d < -time.Second || d > time.Hour {
+				t.Fatalf("clock has moved by %v", d)
+			}
+			prev = now
+		}
+
+		d := time.Duration(rapid.Int64Min(0).Draw(t, "d").(int64))
+		c.Advance(d)
+		if c.now != prev.Add(d) {
+			t.Fatalf("clock has not advanced by %v", d)
+		}
+	})
+}
+
+func TestFake_JumpsBackwards(t *testing.T) {
+	gen := rapid.Custom(func(t *rapid.T) bool {
+		c := NewFake(t)
+		return c.Since(c.Now()) < 0
+	})
+
+	for seed := 0; seed < 1000; seed++ {
+		if gen.Example(seed).(bool) {
+			return
+		}
+	}
+	t.Fatal("clock has never jumped backwards")
+}
+
+func TestReal(t *testing.T) {
+	start := Real.Now()
+	if d := Real.Since(start); d < 0 {
+		t.Fatalf("real clock has moved by %v", d)
+	}
+}
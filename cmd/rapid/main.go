@@ -0,0 +1,319 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Command rapid manages the failing test cases persisted by rapid: fail files
+// under testdata/rapid, and the entries of directory example databases.
+//
+// Usage:
+//
+//   rapid list [dir...]        list the persisted failing test cases
+//   rapid inspect file...      print the metadata, the draws and the recorded output
+//   rapid replay file          re-run the test with the failing test case
+//   rapid minimize [dir...]    remove the test cases which no longer fail,
+//                              keeping only the smallest one for every test
+//                              (test cases are only removed when the test has
+//                              run and passed, never because of build errors
+//                              or tests which no longer exist)
+//   rapid delete file...       remove the persisted failing test cases
+//
+// Directories default to testdata/rapid.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pgregory.net/rapid/internal/failfile"
+)
+
+const defaultDir = "testdata/rapid"
+
+var (
+	drawRe    = regexp.MustCompile(`\[rapid\] draw (.+?): (.*)$`)
+	failureRe = regexp.MustCompile(`\[rapid\] (failed after|panic after|flaky test)`)
+)
+
+// entry is a persisted failing test case.
+type entry struct {
+	path string
+	test string // test name, as recorded in the output (or the directory name)
+	ff   failfile.File
+}
+
+// draw is a labeled value drawn by the test case, as logged by rapid.
+type draw struct {
+	label string
+	value string
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: rapid list|inspect|replay|minimize|delete [args]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	args := flag.Args()[1:]
+	switch flag.Arg(0) {
+	case "list":
+		err = list(args)
+	case "inspect":
+		err = inspect(args)
+	case "replay":
+		if len(args) != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		_, _, err = replay(args[0], true)
+	case "minimize":
+		err = minimize(args)
+	case "delete":
+		for _, path := range args {
+			if err = os.Remove(path); err != nil {
+				break
+			}
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rapid: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func list(dirs []string) error {
+	entries, err := findEntries(dirs)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		fmt.Printf("%v\t%v\t%v words\t%v\n", e.path, e.test, len(e.ff.Buf), e.ff.Version)
+	}
+
+	return nil
+}
+
+func inspect(paths []string) error {
+	for i, path := range paths {
+		e, err := readEntry(path)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("file:    %v\ntest:    %v\nversion: %v\nseed:    %v\nsize:    %v words\n", e.path, e.test, e.ff.Version, e.ff.Seed, len(e.ff.Buf))
+
+		// entries without recorded output (like the ones of example databases)
+		// are decoded by replaying them, as only the test knows its generators
+		draws := parseDraws(e.ff.Output)
+		if len(draws) == 0 {
+			_, out, err := replay(path, false)
+			if err != nil {
+				fmt.Printf("draws:   can not replay: %v\n", err)
+			}
+			draws = parseDraws(strings.Split(string(out), "\n"))
+		}
+		if len(draws) > 0 {
+			fmt.Println("draws:")
+			for _, d := range draws {
+				fmt.Printf("  %v = %v\n", d.label, d.value)
+			}
+		}
+
+		if len(e.ff.Output) > 0 {
+			fmt.Println("output:")
+			for _, s := range e.ff.Output {
+				fmt.Printf("  %v\n", s)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseDraws returns the draws logged in the test output.
+func parseDraws(output []string) []draw {
+	var draws []draw
+	for _, s := range output {
+		if m := drawRe.FindStringSubmatch(strings.TrimSpace(s)); m != nil {
+			draws = append(draws, draw{m[1], m[2]})
+		}
+	}
+	return draws
+}
+
+// replay runs the test of the entry using the entry as a fail file, and reports
+// whether the test still fails, together with the test output. Failures which
+// are not reported by rapid (like build errors), and runs in which the test
+// has not run at all, are errors instead: the entry can not be judged by them.
+func replay(path string, verbose bool) (bool, []byte, error) {
+	e, err := readEntry(path)
+	if err != nil {
+		return false, nil, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false, nil, err
+	}
+
+	args := []string{"test", "-v", "-count=1", "-run", runPattern(e.test), "-rapid.failfile=" + abs, "-rapid.nofailfile", "-rapid.checks=0"}
+	cmd := exec.Command("go", args...)
+	cmd.Dir = packageDir(abs)
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if verbose {
+		cmd.Stdout, cmd.Stderr = io.MultiWriter(os.Stdout, &out), io.MultiWriter(os.Stderr, &out)
+	}
+
+	err = cmd.Run()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return false, nil, err
+	}
+	return judgeReplay(e.test, out.Bytes(), err != nil)
+}
+
+// judgeReplay decides whether the replayed test still fails, based on its
+// verbose output and on whether go test has failed.
+func judgeReplay(test string, out []byte, failed bool) (bool, []byte, error) {
+	ran := regexp.MustCompile(`(?m)^=== RUN\s+` + regexp.QuoteMeta(test) + `$`).Match(out)
+	if !ran {
+		return false, out, fmt.Errorf("test %v has not run", test)
+	}
+	if failed != failureRe.Match(out) {
+		if failed {
+			return false, out, fmt.Errorf("test %v has failed without a rapid failure", test)
+		}
+		return false, out, fmt.Errorf("test %v has reported a rapid failure, but passed", test)
+	}
+	return failed, out, nil
+}
+
+func minimize(dirs []string) error {
+	entries, err := findEntries(dirs)
+	if err != nil {
+		return err
+	}
+
+	smallest := map[string]entry{}
+	var failing []entry
+	for _, e := range entries {
+		fails, _, err := replay(e.path, false)
+		if err != nil {
+			fmt.Printf("keeping %v: %v\n", e.path, err)
+			continue
+		}
+		if !fails {
+			fmt.Printf("removing %v: no longer fails\n", e.path)
+			if err := os.Remove(e.path); err != nil {
+				return err
+			}
+			continue
+		}
+		failing = append(failing, e)
+		if s, ok := smallest[e.test]; !ok || len(e.ff.Buf) < len(s.ff.Buf) {
+			smallest[e.test] = e
+		}
+	}
+
+	for _, e := range failing {
+		if smallest[e.test].path != e.path {
+			fmt.Printf("removing %v: %v is smaller\n", e.path, smallest[e.test].path)
+			if err := os.Remove(e.path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func findEntries(dirs []string) ([]entry, error) {
+	if len(dirs) == 0 {
+		dirs = []string{defaultDir}
+	}
+
+	var entries []entry
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(path) != ".fail" {
+				return err
+			}
+			e, err := readEntry(path)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, e)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+	return entries, nil
+}
+
+func readEntry(path string) (entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return entry{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	ff, err := failfile.Read(f)
+	if err != nil {
+		return entry{}, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+
+	e := entry{path: path, test: filepath.Base(filepath.Dir(path)), ff: ff}
+	if len(ff.Output) > 0 {
+		if fields := strings.Fields(ff.Output[0]); len(fields) > 0 {
+			e.test = fields[0] // output lines are prefixed with the test name
+		}
+	}
+
+	return e, nil
+}
+
+// packageDir returns the directory of the package which has the testdata
+// directory the file is in.
+func packageDir(path string) string {
+	dir := filepath.Dir(path)
+	for d := dir; d != filepath.Dir(d); d = filepath.Dir(d) {
+		if filepath.Base(d) == "testdata" {
+			return filepath.Dir(d)
+		}
+	}
+	return dir
+}
+
+func runPattern(test string) string {
+	parts := strings.Split(test, "/")
+	for i, p := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(p) + "$"
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,96 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadEntry(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "rapid-cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	testData := []struct {
+		name     string
+		contents string
+		test     string
+		size     int
+	}{
+		{"current", "# TestFoo/bar 2026/01/01 00:00:00 [rapid] draw x: 11\n# \nrapid-failfile 2\nversion v0.4.6\ngenerators 0000000000000000\nseed 42\ndata varint:AwAAAA", "TestFoo/bar", 3},
+		{"varint", "v0.4.6#42\nvarint:AwAAAA", "TestBaz", 3},
+		{"legacy", "v0.4.6#42\n0x0\n0x1", "TestBaz", 2},
+	}
+
+	for _, td := range testData {
+		path := filepath.Join(dir, td.name, "TestBaz", "TestBaz-1-1.fail")
+		_ = os.MkdirAll(filepath.Dir(path), 0775)
+		if err := ioutil.WriteFile(path, []byte(td.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		e, err := readEntry(path)
+		if err != nil {
+			t.Fatalf("%v: %v", td.name, err)
+		}
+		if e.test != td.test || len(e.ff.Buf) != td.size || e.ff.Version != "v0.4.6" || e.ff.Seed != 42 {
+			t.Errorf("%v: got %+v", td.name, e)
+		}
+	}
+}
+
+func TestJudgeReplay(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name   string
+		out    string
+		failed bool
+		fails  bool
+		err    bool
+	}{
+		{"fails", "=== RUN   TestFoo/bar\n    foo_test.go:1: [rapid] failed after 0 tests: boom\n--- FAIL: TestFoo/bar\nFAIL\n", true, true, false},
+		{"passes", "=== RUN   TestFoo/bar\n--- PASS: TestFoo/bar\nok\n", false, false, false},
+		{"no test", "testing: warning: no tests to run\nPASS\n", false, false, true},
+		{"build error", "./foo_test.go:1:1: syntax error\nFAIL\n", true, false, true},
+		{"other failure", "=== RUN   TestFoo/bar\n    foo_test.go:1: boom\n--- FAIL: TestFoo/bar\nFAIL\n", true, false, true},
+	}
+
+	for _, td := range testData {
+		fails, _, err := judgeReplay("TestFoo/bar", []byte(td.out), td.failed)
+		if fails != td.fails || (err != nil) != td.err {
+			t.Errorf("%v: got %v, %v", td.name, fails, err)
+		}
+	}
+}
+
+func TestParseDraws(t *testing.T) {
+	t.Parallel()
+
+	draws := parseDraws([]string{"TestFoo 2026/01/01 00:00:00 [rapid] draw x: 11", "    foo_test.go:12: [rapid] draw y: \"a: b\"", "other"})
+	if len(draws) != 2 || draws[0] != (draw{"x", "11"}) || draws[1] != (draw{"y", `"a: b"`}) {
+		t.Errorf("got draws %v", draws)
+	}
+}
+
+func TestRunPattern(t *testing.T) {
+	t.Parallel()
+
+	if p := runPattern("TestFoo/a.b"); p != `^TestFoo$/^a\.b$` {
+		t.Errorf("got pattern %q", p)
+	}
+	if d := packageDir(filepath.Join("pkg", "testdata", "rapid", "TestFoo", "x.fail")); d != "pkg" {
+		t.Errorf("got package dir %q", d)
+	}
+}
@@ -11,6 +11,11 @@ import (
 	"reflect"
 )
 
+const (
+	mapKeyLabel   = "mapkey"
+	mapValueLabel = "mapvalue"
+)
+
 func SliceOf(elem *Generator) *Generator {
 	return SliceOfN(elem, -1, -1)
 }
@@ -69,7 +74,9 @@ func (g *sliceGen) String() string {
 		}
 	} else {
 		key := ""
-		if g.keyFn.IsValid() {
+		if name := funcName(g.keyFn); name != "" {
+			key = ", key=" + name
+		} else if g.keyFn.IsValid() {
 			key = fmt.Sprintf(", key=func(%v) %v", g.elem.type_(), g.keyTyp)
 		}
 
@@ -93,8 +100,12 @@ func (g *sliceGen) value(t *T) value {
 		seen = reflect.MakeMapWithSize(reflect.MapOf(g.keyTyp, emptyStructType), repeat.avg())
 	}
 
+	path := t.path
+	defer func() { t.path = path }()
+
 	sl := reflect.MakeSlice(g.typ, 0, repeat.avg())
 	for repeat.more(t.s, g.elem.String()) {
+		t.path = elemPath(path, sl.Len())
 		e := reflect.ValueOf(g.elem.value(t))
 		if g.keyTyp == nil {
 			sl = reflect.Append(sl, e)
@@ -177,7 +188,9 @@ func (g *mapGen) String() string {
 		}
 	} else {
 		key := ""
-		if g.keyFn.IsValid() {
+		if name := funcName(g.keyFn); name != "" {
+			key = ", key=" + name
+		} else if g.keyFn.IsValid() {
 			key = fmt.Sprintf(", key=func(%v) %v", g.val.type_(), g.keyTyp)
 		}
 
@@ -201,14 +214,27 @@ func (g *mapGen) value(t *T) value {
 
 	repeat := newRepeat(g.minLen, g.maxLen, -1)
 
+	path := t.path
+	defer func() { t.path = path }()
+
 	m := reflect.MakeMapWithSize(g.typ, repeat.avg())
 	for repeat.more(t.s, label) {
 		var k, v reflect.Value
+		entry := elemPath(path, m.Len())
 		if g.keyTyp == nil {
+			i := t.s.beginGroup(mapKeyLabel, false)
+			t.path = subPath(entry, "key")
 			k = reflect.ValueOf(g.key.value(t))
+			t.s.endGroup(i, false)
+			i = t.s.beginGroup(mapValueLabel, false)
+			t.path = subPath(entry, "value")
 			v = reflect.ValueOf(g.val.value(t))
+			t.s.endGroup(i, false)
 		} else {
+			i := t.s.beginGroup(mapValueLabel, false)
+			t.path = entry
 			v = reflect.ValueOf(g.val.value(t))
+			t.s.endGroup(i, false)
 			k = v
 			if g.keyFn.IsValid() {
 				k = g.keyFn.Call([]reflect.Value{v})[0]
@@ -255,7 +281,11 @@ func (g *arrayGen) value(t *T) value {
 	if g.count == 0 {
 		t.s.drawBits(0)
 	} else {
+		path := t.path
+		defer func() { t.path = path }()
+
 		for i := 0; i < g.count; i++ {
+			t.path = elemPath(path, i)
 			e := reflect.ValueOf(g.elem.value(t))
 			a.Index(i).Set(e)
 		}
@@ -9,10 +9,23 @@ package rapid
 import (
 	"fmt"
 	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
 	"strings"
 )
 
-const tryLabel = "try"
+const (
+	tryLabel        = "try"
+	shrinkHintLabel = "shrinkhint"
+
+	shrinkHintBits = 65 // wide draw: random data is always math.MaxUint64, and does not consume the seed
+	shrinkHintMax  = 16
+
+	sampledDescLen = 64 // maximum length of the values of SampledFrom in its description
+)
+
+var anonymousFuncRe = regexp.MustCompile(`\.func\d+(\.\d+)*$`)
 
 var (
 	boolType           = reflect.TypeOf(false)
@@ -38,6 +51,9 @@ type customGen struct {
 }
 
 func (g *customGen) String() string {
+	if name := funcName(g.fn); name != "" {
+		return fmt.Sprintf("Custom(%v)", name)
+	}
 	return fmt.Sprintf("Custom(%v)", g.typ)
 }
 
@@ -49,10 +65,11 @@ func (g *customGen) value(t *T) value {
 	return find(g.maybeValue, t, small)
 }
 
-func (g *customGen) maybeValue(t *T) value {
-	t = newT(t.tb, t.s, flags.debug, nil)
+func (g *customGen) maybeValue(pt *T) value {
+	t := pt.nested()
 
 	defer func() {
+		pt.sensitive = pt.sensitive || t.sensitive
 		if r := recover(); r != nil {
 			if _, ok := r.(invalidData); !ok {
 				panic(r)
@@ -60,7 +77,13 @@ func (g *customGen) maybeValue(t *T) value {
 		}
 	}()
 
-	return call(g.fn, reflect.ValueOf(t))
+	n := t.s.size()
+	v := call(g.fn, reflect.ValueOf(t))
+	assertf(t.s.size() > n, "%v has generated a value without drawing from any generator (draw %v): "+
+		"the Custom function should build the value from the values it draws using its *rapid.T "+
+		"(use Just for constants)", g, t.path)
+
+	return v
 }
 
 func filter(g *Generator, fn interface{}) *Generator {
@@ -75,15 +98,20 @@ func filter(g *Generator, fn interface{}) *Generator {
 		fn: func(v value) bool {
 			return call(f, reflect.ValueOf(v)).(bool)
 		},
+		name: funcName(f),
 	})
 }
 
 type filteredGen struct {
-	g  *Generator
-	fn func(value) bool
+	g    *Generator
+	fn   func(value) bool
+	name string // of fn, if it is a named function
 }
 
 func (g *filteredGen) String() string {
+	if g.name != "" {
+		return fmt.Sprintf("%v.Filter(%v)", g.g, g.name)
+	}
 	return fmt.Sprintf("%v.Filter(...)", g.g)
 }
 
@@ -97,7 +125,11 @@ func (g *filteredGen) value(t *T) value {
 
 func (g *filteredGen) maybeValue(t *T) value {
 	v := g.g.value(t)
-	if g.fn(v) {
+	ok := g.fn(v)
+	if t.stats != nil {
+		t.recordFilter(g.String(), !ok)
+	}
+	if ok {
 		return v
 	} else {
 		return nil
@@ -114,6 +146,9 @@ func find(gen func(*T) value, t *T, tries int) value {
 		if ok {
 			return v
 		}
+		if s, ok := t.s.(*bufBitStream); ok {
+			s.rejections++
+		}
 	}
 
 	panic(invalidData(fmt.Sprintf("failed to find suitable value in %d tries", tries)))
@@ -139,6 +174,9 @@ type mappedGen struct {
 }
 
 func (g *mappedGen) String() string {
+	if name := funcName(g.fn); name != "" {
+		return fmt.Sprintf("%v.Map(%v)", g.g, name)
+	}
 	return fmt.Sprintf("%v.Map(func(...) %v)", g.g, g.typ)
 }
 
@@ -151,6 +189,49 @@ func (g *mappedGen) value(t *T) value {
 	return call(g.fn, v)
 }
 
+func shrinkWith(g *Generator, fn interface{}) *Generator {
+	f := reflect.ValueOf(fn)
+	t := f.Type()
+
+	assertCallable(t, g.type_(), "fn")
+	assertf(t.Out(0) == reflect.SliceOf(g.type_()), "fn should return %v, not %v", reflect.SliceOf(g.type_()), t.Out(0))
+
+	return newGenerator(&shrinkHintGen{
+		g:  g,
+		fn: f,
+	})
+}
+
+// shrinkHintGen prefixes the value with a hint block, see genShrinkHint.
+type shrinkHintGen struct {
+	g  *Generator
+	fn reflect.Value
+}
+
+func (g *shrinkHintGen) String() string {
+	return fmt.Sprintf("%v.ShrinkWith(...)", g.g)
+}
+
+func (g *shrinkHintGen) type_() reflect.Type {
+	return g.g.type_()
+}
+
+func (g *shrinkHintGen) value(t *T) value {
+	h := genShrinkHint(t.s)
+
+	v := g.g.value(t)
+	if h < 0 {
+		return v
+	}
+
+	candidates := reflect.ValueOf(call(g.fn, reflect.ValueOf(v)))
+	if h >= candidates.Len() {
+		panic(invalidData(fmt.Sprintf("no shrink candidate %v", h)))
+	}
+
+	return candidates.Index(h).Interface()
+}
+
 func Just(val interface{}) *Generator {
 	return SampledFrom([]interface{}{val})
 }
@@ -169,25 +250,84 @@ func SampledFrom(slice interface{}) *Generator {
 	})
 }
 
+// weightedSampledFrom is like SampledFrom, but does not favor the first elements,
+// and chooses them in proportion to their weights, given as cumulative sums
+// (so that the last one is the total weight).
+func weightedSampledFrom(slice interface{}, cumWeights []int) *Generator {
+	g := SampledFrom(slice)
+	g.impl.(*sampledGen).cumWeights = cumWeights
+	return g
+}
+
 type sampledGen struct {
-	typ   reflect.Type
-	slice reflect.Value
-	n     int
+	typ        reflect.Type
+	slice      reflect.Value
+	n          int
+	cumWeights []int // if not nil, cumulative weights of the elements
 }
 
+// String includes the values only if they are printed the same way on every
+// run, as the descriptions of the generators are hashed to recognize the
+// fail files recorded with different generators.
 func (g *sampledGen) String() string {
+	seen := map[reflect.Type]bool{}
+	for i := 0; i < g.n; i++ {
+		v := g.slice.Index(i)
+		if v.Kind() == reflect.Interface {
+			v = v.Elem() // like Just, which samples from []interface{}
+		}
+		if !v.IsValid() || !holdsAddresses(v.Type(), seen) {
+			continue
+		}
+		if g.n == 1 {
+			return fmt.Sprintf("Just(%v)", v.Type())
+		}
+		return fmt.Sprintf("SampledFrom(%v %v)", g.n, g.typ)
+	}
+
 	if g.n == 1 {
-		return fmt.Sprintf("Just(%v)", g.slice.Index(0).Interface())
+		return fmt.Sprintf("Just(%v)", printValue(g.slice.Index(0).Interface()))
 	} else {
-		return fmt.Sprintf("SampledFrom(%v %v)", g.n, g.typ)
+		return fmt.Sprintf("SampledFrom(%v)", truncateValue(g.slice.Interface(), sampledDescLen))
 	}
 }
 
+// holdsAddresses reports whether the values of typ can contain pointers, or
+// other values which are printed differently depending on their addresses.
+func holdsAddresses(typ reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[typ] {
+		return false
+	}
+	seen[typ] = true
+
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Func, reflect.Chan, reflect.Interface:
+		return true
+	case reflect.Array, reflect.Slice:
+		return holdsAddresses(typ.Elem(), seen)
+	case reflect.Map:
+		return holdsAddresses(typ.Key(), seen) || holdsAddresses(typ.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			if holdsAddresses(typ.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func (g *sampledGen) type_() reflect.Type {
 	return g.typ
 }
 
 func (g *sampledGen) value(t *T) value {
+	if g.cumWeights != nil {
+		w := genIndex(t.s, g.cumWeights[g.n-1], false)
+		return g.slice.Index(sort.SearchInts(g.cumWeights, w+1)).Interface()
+	}
+
 	i := genIndex(t.s, g.n, true)
 
 	return g.slice.Index(i).Interface()
@@ -248,6 +388,24 @@ type ptrGen struct {
 	allowNil bool
 }
 
+// funcName returns the name of the function, like "strconv.Itoa",
+// or an empty string if the function is anonymous.
+func funcName(fn reflect.Value) string {
+	if !fn.IsValid() || fn.Kind() != reflect.Func || fn.IsNil() {
+		return ""
+	}
+	f := runtime.FuncForPC(fn.Pointer())
+	if f == nil {
+		return ""
+	}
+	name := f.Name()
+	if anonymousFuncRe.MatchString(name) {
+		return ""
+	}
+	name = strings.TrimSuffix(name, "-fm") // method value
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
 func (g *ptrGen) String() string {
 	return fmt.Sprintf("Ptr(%v, allowNil=%v)", g.elem, g.allowNil)
 }
@@ -96,3 +96,17 @@ func ExamplePtr() {
 	// (*int) 590
 	// <nil>
 }
+
+func ExamplePrintSampleN() {
+	gen := rapid.Custom(func(t *rapid.T) string {
+		return fmt.Sprintf("%v-%v", rapid.IntRange(1, 9).Draw(t, "a"), rapid.SampledFrom([]string{"x", "y"}).Draw(t, "b"))
+	})
+
+	rapid.PrintSampleN(gen, 1, 5)
+	// Output:
+	// "5-y"
+	// "2-y"
+	// "2-x"
+	// "7-y"
+	// "1-x"
+}
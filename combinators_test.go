@@ -6,7 +6,11 @@
 
 package rapid
 
-import "testing"
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
 
 type intPair struct {
 	x int
@@ -24,3 +28,36 @@ func BenchmarkHeavyChain3(b *testing.B) {
 		g.value(t)
 	}
 }
+
+func TestCustom_NoDraws(t *testing.T) {
+	t.Parallel()
+
+	g := Custom(func(t *T) int { return 1 })
+	err := checkOnce(newT(t, newRandomBitStream(baseSeed(), false), false, nil), func(t *T) {
+		_ = SliceOfN(g, 1, 1).Draw(t, "s")
+	})
+	want := "Custom(int) has generated a value without drawing from any generator (draw s[0])"
+	if err == nil || !strings.HasPrefix(err.Error(), want) || !strings.Contains(err.Error(), "use Just for constants") {
+		t.Errorf("got %v, want %q", err, want)
+	}
+}
+
+func TestShrinkWith_SameDistribution(t *testing.T) {
+	t.Parallel()
+
+	never := func(i int) []int { panic("shrink candidates requested for random data") }
+	plain := SliceOf(Int())
+	hinted := SliceOf(Int().ShrinkWith(never))
+
+	for seed := 0; seed < 1000; seed++ {
+		a, b := plain.Example(seed).([]int), hinted.Example(seed).([]int)
+		if fmt.Sprint(a) != fmt.Sprint(b) {
+			t.Fatalf("seed %v: got %v instead of %v", seed, b, a)
+		}
+	}
+
+	zero := newT(t, newBufBitStream(make([]uint64, 64), false), false, nil)
+	if v := Int().ShrinkWith(never).Draw(zero, "v").(int); v != 0 {
+		t.Fatalf("got %v from zero data instead of 0", v)
+	}
+}
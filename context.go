@@ -0,0 +1,146 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	contextMaxPolls    = 20
+	contextMaxDeadline = 60 // minutes
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// ContextValue describes a value which can be attached to the generated contexts.
+type ContextValue struct {
+	Key   interface{}
+	Value *Generator
+}
+
+// Context creates a generator of contexts. Most of the generated contexts are
+// live, but some are already cancelled, have a deadline (which can already
+// be exceeded), or are cancelled during the test: after the code under test
+// has polled them (by calling Done or Err) a drawn number of times, which keeps
+// the moment of cancellation reproducible. Each of the values is attached
+// to the context with probability 1/2.
+func Context(values ...ContextValue) *Generator {
+	for _, v := range values {
+		assertf(v.Key != nil, "context value key should not be nil")
+		assertf(v.Value != nil, "context value generator for key %v should not be nil", v.Key)
+	}
+
+	return newGenerator(&contextGen{
+		values: values,
+	})
+}
+
+type contextGen struct {
+	values []ContextValue
+}
+
+func (g *contextGen) String() string {
+	if len(g.values) == 0 {
+		return "Context()"
+	}
+
+	var vals []string
+	for _, v := range g.values {
+		vals = append(vals, fmt.Sprintf("%v: %v", v.Key, v.Value))
+	}
+	return fmt.Sprintf("Context(%v)", strings.Join(vals, ", "))
+}
+
+func (g *contextGen) type_() reflect.Type {
+	return contextType
+}
+
+func (g *contextGen) value(t *T) value {
+	ctx := context.Background()
+	var desc []string
+	for _, v := range g.values {
+		if flipBiasedCoin(t.s, 0.5) {
+			val := v.Value.value(t)
+			ctx = context.WithValue(ctx, v.Key, val)
+			desc = append(desc, fmt.Sprintf("%v=%v", v.Key, printValue(val)))
+		}
+	}
+
+	c := &drawnContext{}
+	var cancel context.CancelFunc
+	switch genIndex(t.s, 10, true) {
+	case 5:
+		ctx, cancel = context.WithCancel(ctx)
+		cancel()
+		desc = append(desc, "cancelled")
+	case 6:
+		ctx, cancel = context.WithDeadline(ctx, time.Now())
+		desc = append(desc, "deadline exceeded")
+	case 7:
+		d := time.Duration(genIndex(t.s, contextMaxDeadline, true)+1) * time.Minute
+		ctx, cancel = context.WithTimeout(ctx, d)
+		desc = append(desc, fmt.Sprintf("deadline in %v", d))
+	case 8, 9:
+		c.polls = genIndex(t.s, contextMaxPolls, true) + 1
+		ctx, cancel = context.WithCancel(ctx)
+		desc = append(desc, fmt.Sprintf("cancelled after %v polls", c.polls))
+	}
+	if cancel != nil {
+		t.Cleanup(cancel)
+	}
+
+	c.Context = ctx
+	c.cancel = cancel
+	c.desc = fmt.Sprintf("Context(%v)", strings.Join(desc, ", "))
+	return c
+}
+
+// drawnContext cancels itself after it has been polled a given number of times.
+type drawnContext struct {
+	context.Context
+	cancel context.CancelFunc
+	desc   string
+	mu     sync.Mutex
+	polls  int // number of polls left before cancellation, if positive
+}
+
+func (c *drawnContext) poll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.polls > 0 {
+		c.polls--
+		if c.polls == 0 {
+			c.cancel()
+		}
+	}
+}
+
+func (c *drawnContext) Done() <-chan struct{} {
+	c.poll()
+	return c.Context.Done()
+}
+
+func (c *drawnContext) Err() error {
+	c.poll()
+	return c.Context.Err()
+}
+
+func (c *drawnContext) String() string {
+	return c.desc
+}
+
+// GoString describes the context, to make the draws of contexts readable.
+func (c *drawnContext) GoString() string {
+	return c.desc
+}
@@ -0,0 +1,60 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type ctxKey string
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+
+	seen := map[string]bool{}
+	Check(t, func(t *T) {
+		ctx := Context(ContextValue{Key: ctxKey("user"), Value: StringN(1, 5, -1)}).Draw(t, "ctx").(context.Context)
+		if v := ctx.Value(ctxKey("user")); v != nil {
+			seen["value"] = true
+			if !strings.Contains(ctx.(interface{ String() string }).String(), "user=") {
+				t.Fatalf("value %q not described in %v", v, ctx)
+			}
+		}
+		if _, ok := ctx.Deadline(); ok {
+			seen["deadline"] = true
+		}
+
+		err := ctx.Err()
+		for i := 0; err == nil && i < contextMaxPolls; i++ {
+			err = ctx.Err()
+			if err != nil {
+				seen["cancelled during the test"] = true
+			}
+		}
+		switch err {
+		case nil:
+			seen["live"] = true
+		case context.Canceled, context.DeadlineExceeded:
+			select {
+			case <-ctx.Done():
+			default:
+				t.Fatalf("context is not done after %v", err)
+			}
+			seen[err.Error()] = true
+		default:
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+
+	for _, s := range []string{"value", "deadline", "cancelled during the test", "live", context.Canceled.Error(), context.DeadlineExceeded.Error()} {
+		if !seen[s] {
+			t.Errorf("no %q contexts generated", s)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+// Counterexample is the minimized failing test case of a check (see OnCounterexample).
+type Counterexample struct {
+	Test  string         // name of the test
+	Error string         // failure of the test case
+	Repro string         // flags to reproduce the failure, e.g. -run="TestFoo" -rapid.seed=1
+	Seed  uint64         // seed the test case has been generated from, 0 if unknown
+	Draws []LabeledValue // top-level draws of the test case, in order
+
+	Metadata PropertyMetadata // see Metadata
+}
+
+// LabeledValue is a value drawn by a test case, with the label of the draw.
+type LabeledValue struct {
+	Label string
+	Value interface{} // nil if the value is sensitive (see Generator.Sensitive and RegisterSensitive)
+	Repr  string      // value as shown in the output
+}
+
+// Values returns the drawn values, in order (e.g. to pass to Replay).
+func (c Counterexample) Values() []interface{} {
+	values := make([]interface{}, len(c.Draws))
+	for i, d := range c.Draws {
+		values[i] = d.Value
+	}
+	return values
+}
+
+func newCounterexample(tb tb, prop func(*T), err *testError, repro string, seed uint64, buf []uint64) Counterexample {
+	draws, _ := topLevelDraws(tb, prop, buf)
+
+	c := Counterexample{
+		Test:  tb.Name(),
+		Error: err.Error(),
+		Repro: repro,
+		Seed:  seed,
+	}
+	for _, d := range draws {
+		lv := LabeledValue{Label: d.label, Repr: d.String()}
+		if !d.redacted() {
+			lv.Value = d.v
+		}
+		c.Draws = append(c.Draws, lv)
+	}
+	return c
+}
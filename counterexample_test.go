@@ -0,0 +1,40 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOnCounterexample(t *testing.T) {
+	var ces []Counterexample
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		s := String().Sensitive().Draw(t, "secret").(string)
+		n := IntRange(0, 100).Draw(t, "n").(int)
+		if n > 10 {
+			t.Fatalf("too big: %v, %v", n, len(s))
+		}
+	}, OnCounterexample(func(c Counterexample) { ces = append(ces, c) }))
+	removeFailFiles(t.Name())
+
+	if len(ces) != 1 {
+		t.Fatalf("callback called %v times", len(ces))
+	}
+	c := ces[0]
+	if c.Test != t.Name() || c.Error != "too big: 11, 0" || !strings.HasPrefix(c.Repro, "-run=") {
+		t.Errorf("unexpected counterexample %+v", c)
+	}
+	if !reflect.DeepEqual(c.Values(), []interface{}{nil, 11}) {
+		t.Errorf("got values %v", c.Values())
+	}
+	if c.Draws[0].Label != "secret" || !strings.HasPrefix(c.Draws[0].Repr, "<sensitive ") || c.Draws[1].Label != "n" || c.Draws[1].Repr != "11" {
+		t.Errorf("got draws %+v", c.Draws)
+	}
+}
@@ -7,18 +7,38 @@
 package rapid
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
 	"math"
 	"math/bits"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
-var seedCounter uint32
+// shardMaxCases is the number of consecutive seeds a test can use
+// without leaving the seed range of its shard.
+const shardMaxCases = 1 << 32
+
+var (
+	seedBase        = randomSeedBase()
+	seedCounter     uint32
+	stableSeedMu    sync.Mutex
+	stableSeedCalls = map[string]uint64{}
+)
 
 type bitStream interface {
 	drawBits(n int) uint64
 	beginGroup(label string, standalone bool) int
 	endGroup(i int, discard bool)
+	recording() bool
+	bitsDrawn() int
+	size() int
 }
 
 func baseSeed() uint64 {
@@ -26,11 +46,84 @@ func baseSeed() uint64 {
 		return flags.seed
 	}
 
-	return uint64(time.Now().UnixNano())<<32 + uint64(atomic.AddUint32(&seedCounter, 1))
+	return (seedBase ^ uint64(time.Now().UnixNano())<<32) + uint64(atomic.AddUint32(&seedCounter, 1))
+}
+
+// randomSeedBase returns a random number to make the seeds of processes
+// started at the same time (e.g. parallel CI jobs) differ.
+func randomSeedBase() uint64 {
+	var b [8]byte
+	if _, err := cryptorand.Read(b[:]); err != nil {
+		return uint64(os.Getpid()) << 32
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// testSeed returns the seed to check the test with. With -rapid.epoch, it is
+// derived from the test name and the epoch instead of the current time, so that
+// e.g. a CI build can be reproduced by running it with the same epoch.
+// With -rapid.shards, the seed is moved to the seed range of -rapid.shard.
+func testSeed(name string) uint64 {
+	if flags.seed != 0 {
+		return flags.seed
+	}
+
+	var seed uint64
+	if flags.epoch < 0 {
+		seed = baseSeed()
+	} else {
+		stableSeedMu.Lock()
+		n := stableSeedCalls[name] // tests can run more than one check
+		stableSeedCalls[name]++
+		stableSeedMu.Unlock()
+
+		seed = stableSeed(name, uint64(flags.epoch), n)
+	}
+
+	return shardSeed(seed, flags.shard, flags.shards)
+}
+
+func stableSeed(name string, epoch uint64, n uint64) uint64 {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], epoch)
+	binary.LittleEndian.PutUint64(b[8:], n)
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	_, _ = h.Write(b[:])
+	seed := h.Sum64()
+	if seed == 0 {
+		seed = 1 // zero seed means the test case was not generated from a seed
+	}
+
+	return seed
+}
+
+// shardSeed maps seed to the range of seeds of the shard, so that the shards
+// explore disjoint seed sequences (as long as no test uses more than
+// shardMaxCases seeds).
+func shardSeed(seed uint64, shard int, shards int) uint64 {
+	if shards <= 1 {
+		return seed
+	}
+	assertf(shard >= 0 && shard < shards, "invalid shard %v of %v", shard, shards)
+
+	width := math.MaxUint64 / uint64(shards)
+	seed = uint64(shard)*width + seed%(width-shardMaxCases)
+	if seed == 0 {
+		seed = 1
+	}
+
+	return seed
 }
 
 type randomBitStream struct {
-	ctx jsf64ctx
+	ctx      jsf64ctx
+	seed     uint64
+	resume   []uint64 // data drawn before the random data, see ResumeFrom
+	forced   []uint64 // data to draw next instead of the random data
+	wide     []int    // positions of the draws of more than 64 bits, which do not use ctx
+	unforced int      // number of the draws of the random data since init
 	recordedBits
 }
 
@@ -43,49 +136,199 @@ func newRandomBitStream(seed uint64, persist bool) *randomBitStream {
 
 func (s *randomBitStream) init(seed uint64) {
 	s.ctx.init(seed)
+	s.seed = seed
+	s.wide = s.wide[:0]
+	s.forced = s.forced[:0]
+	s.unforced = 0
+	s.reset()
 }
 
 func (s *randomBitStream) drawBits(n int) uint64 {
 	assert(n >= 0)
 
 	var u uint64
-	if n <= 64 {
+	switch i := s.size(); {
+	case i < len(s.resume):
+		u = s.resume[i] & bitmask64(uint(n))
+	case len(s.forced) > 0:
+		u = s.forced[0] & bitmask64(uint(n))
+		s.forced = s.forced[1:]
+	case n <= 64:
 		u = s.ctx.rand() & bitmask64(uint(n))
-	} else {
+		s.unforced++
+	default:
 		u = math.MaxUint64
+		s.wide = append(s.wide, i)
+		s.unforced++
 	}
-	s.record(u)
+	s.record(u, n)
 
 	return u
 }
 
+// force makes the next draws return words, instead of the random data.
+func (s *randomBitStream) force(words ...uint64) {
+	s.forced = append(s.forced, words...)
+}
+
+// drawn returns the data drawn so far. When it is not recorded, it is
+// generated again from the seed (which is only possible when no data has been forced).
+func (s *randomBitStream) drawn() []uint64 {
+	if s.persist {
+		return append([]uint64(nil), s.data...)
+	}
+
+	var ctx jsf64ctx
+	ctx.init(s.seed)
+	buf := make([]uint64, s.dataLen)
+	w := 0
+	for i := range buf {
+		switch {
+		case i < len(s.resume):
+			buf[i] = s.resume[i]
+		case w < len(s.wide) && s.wide[w] == i:
+			buf[i] = math.MaxUint64
+			w++
+		default:
+			buf[i] = ctx.rand()
+		}
+	}
+
+	return buf
+}
+
 type bufBitStream struct {
-	buf []uint64
+	buf        []uint64
+	orig       []uint64 // buf before any data was drawn
+	rejections int      // number of values rejected by filters
+	zeroPad    bool     // draw zeroes instead of failing after the end of buf
 	recordedBits
 }
 
 func newBufBitStream(buf []uint64, persist bool) *bufBitStream {
 	s := &bufBitStream{
-		buf: buf,
+		buf:  buf,
+		orig: buf,
 	}
 	s.persist = persist
 	return s
 }
 
+// bufBitStreamPool holds non-persistent buffer bitstreams, so that checking
+// lots of candidates while shrinking does not allocate a stream for each one.
+var bufBitStreamPool = sync.Pool{
+	New: func() interface{} { return newBufBitStream(nil, false) },
+}
+
+func getBufBitStream(buf []uint64) *bufBitStream {
+	s := bufBitStreamPool.Get().(*bufBitStream)
+	s.buf = buf
+	s.orig = buf
+	s.rejections = 0
+	s.zeroPad = false
+	s.reset()
+	return s
+}
+
+func putBufBitStream(s *bufBitStream) {
+	s.buf = nil
+	s.orig = nil
+	bufBitStreamPool.Put(s)
+}
+
 func (s *bufBitStream) drawBits(n int) uint64 {
 	assert(n >= 0)
 
 	if len(s.buf) == 0 {
-		panic(invalidData("overrun"))
+		if !s.zeroPad {
+			s.overrun = true
+			panic(invalidData("overrun" + s.trail()))
+		}
+		s.record(0, n)
+		return 0
 	}
 
 	u := s.buf[0] & bitmask64(uint(n))
-	s.record(u)
+	s.record(u, n)
 	s.buf = s.buf[1:]
 
 	return u
 }
 
+// drawn returns the data drawn so far, including the zeroes drawn after the end of buf.
+func (s *bufBitStream) drawn() []uint64 {
+	if s.persist {
+		return append([]uint64(nil), s.data...)
+	}
+
+	buf := make([]uint64, s.dataLen)
+	copy(buf, s.orig)
+	return buf
+}
+
+// bytesToWords packs the bytes into little-endian words, padding the last one with zeroes.
+func bytesToWords(b []byte) []uint64 {
+	buf := make([]uint64, 0, (len(b)+7)/8)
+	for len(b) > 0 {
+		var tmp [8]byte
+		n := copy(tmp[:], b)
+		buf = append(buf, binary.LittleEndian.Uint64(tmp[:]))
+		b = b[n:]
+	}
+
+	return buf
+}
+
+// wordsToBytes is the inverse of bytesToWords.
+func wordsToBytes(buf []uint64) []byte {
+	b := make([]byte, 8*len(buf))
+	for i, u := range buf {
+		binary.LittleEndian.PutUint64(b[8*i:], u)
+	}
+
+	return b
+}
+
+// ByteStream is a source of data for the generators backed by raw bytes.
+// It allows external fuzzers (and custom mutators) to feed rapid generators directly.
+// Bytes are packed into the 64-bit words generators consume in little-endian order,
+// so the same input always produces the same values. Data of any length
+// is accepted: the stream is padded with zeroes, which generators map
+// to the simplest values possible.
+type ByteStream struct {
+	s *bufBitStream
+	t *T
+}
+
+// NewByteStream returns a stream which supplies data to the generators from data.
+func NewByteStream(data []byte) *ByteStream {
+	s := newBufBitStream(bytesToWords(data), true)
+	s.zeroPad = true
+
+	return &ByteStream{
+		s: s,
+		t: newT(nil, s, false, nil),
+	}
+}
+
+// Draw generates a value of g using the data from the stream. It returns
+// an error when the data is not suitable for g, e.g. when g filters it out.
+func (bs *ByteStream) Draw(g *Generator) (interface{}, error) {
+	v, err := recoverValue(g, bs.t)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Bytes returns the data used by the generators so far. It contains only
+// the bits generators have actually looked at, and generates the same values
+// when fed to a new stream.
+func (bs *ByteStream) Bytes() []byte {
+	return wordsToBytes(bs.s.data)
+}
+
 type groupInfo struct {
 	begin      int
 	end        int
@@ -99,9 +342,22 @@ type recordedBits struct {
 	groups  []groupInfo
 	dataLen int
 	persist bool
+	open    []openGroup // groups which have not ended yet, innermost last
+	begun   int         // number of groups which have begun
+	bits    int         // number of bits drawn
+	draws   int         // number of draws which have begun
+	overrun bool        // whether more data has been drawn than available
 }
 
-func (rec *recordedBits) record(u uint64) {
+type openGroup struct {
+	id    int // returned by beginGroup
+	begin int // amount of data drawn before the group
+	label string
+	draw  int // index of the draw, or -1 if the group is not a draw
+}
+
+func (rec *recordedBits) record(u uint64, n int) {
+	rec.bits += n
 	if rec.persist {
 		rec.data = append(rec.data, u)
 	} else {
@@ -109,23 +365,77 @@ func (rec *recordedBits) record(u uint64) {
 	}
 }
 
+// reset forgets everything recorded, keeping the allocated storage for reuse.
+func (rec *recordedBits) reset() {
+	rec.data = rec.data[:0]
+	rec.groups = rec.groups[:0]
+	rec.dataLen = 0
+	rec.open = rec.open[:0]
+	rec.begun = 0
+	rec.bits = 0
+	rec.draws = 0
+	rec.overrun = false
+}
+
+// size returns the number of words drawn.
+func (rec *recordedBits) size() int {
+	if rec.persist {
+		return len(rec.data)
+	}
+	return rec.dataLen
+}
+
+// bitsDrawn returns the number of bits drawn.
+func (rec *recordedBits) bitsDrawn() int {
+	return rec.bits
+}
+
+// recording reports whether the data and groups are recorded; group labels
+// are only used when they are (and to describe errors).
+func (rec *recordedBits) recording() bool {
+	return rec.persist
+}
+
 func (rec *recordedBits) beginGroup(label string, standalone bool) int {
-	if !rec.persist {
-		return rec.dataLen
+	draw := -1
+	if strings.HasPrefix(label, drawLabelPrefix) {
+		draw = rec.draws
+		rec.draws++
 	}
 
-	rec.groups = append(rec.groups, groupInfo{
-		begin:      len(rec.data),
-		end:        -1,
-		label:      label,
-		standalone: standalone,
-	})
+	id := rec.begun
+	rec.begun++
+	if rec.persist {
+		rec.groups = append(rec.groups, groupInfo{
+			begin:      len(rec.data),
+			end:        -1,
+			label:      label,
+			standalone: standalone,
+		})
+		id = len(rec.groups) - 1
+	}
+	rec.open = append(rec.open, openGroup{id: id, begin: rec.size(), label: label, draw: draw})
 
-	return len(rec.groups) - 1
+	return id
 }
 
 func (rec *recordedBits) endGroup(i int, discard bool) {
-	assertf((!rec.persist && rec.dataLen != i) || (rec.persist && len(rec.data) != rec.groups[i].begin), "group did not use any data from bitstream")
+	// groups left open by a recovered panic end together with the group
+	n := len(rec.open) - 1
+	for n >= 0 && rec.open[n].id != i {
+		n--
+	}
+	assert(n >= 0)
+	rec.open = rec.open[:n+1]
+
+	if rec.size() == rec.open[n].begin {
+		if rec.overrun {
+			// the overrun has been recovered from (e.g. by Custom), leaving the group empty
+			panic(invalidData("overrun" + rec.trail()))
+		}
+		panic("group did not use any data from bitstream" + rec.trail())
+	}
+	rec.open = rec.open[:n]
 
 	if !rec.persist {
 		return
@@ -135,43 +445,87 @@ func (rec *recordedBits) endGroup(i int, discard bool) {
 	rec.groups[i].discard = discard
 }
 
-func (rec *recordedBits) prune() {
-	assert(rec.persist)
-
-	for i := 0; i < len(rec.groups); {
-		if rec.groups[i].discard {
-			rec.removeGroup(i) // O(n^2)
-		} else {
-			i++
+// trail describes the innermost open draw and the labels of the open groups,
+// like " (draw 2: draw:s > @repeat > draw:#3)", to locate errors.
+func (rec *recordedBits) trail() string {
+	draw := -1
+	labels := make([]string, 0, len(rec.open))
+	for _, g := range rec.open {
+		if g.draw >= 0 {
+			draw = g.draw
+		}
+		if g.label != "" {
+			labels = append(labels, strings.TrimSuffix(g.label, ":"))
 		}
 	}
 
-	for _, g := range rec.groups {
-		assert(g.begin != g.end)
+	switch {
+	case draw >= 0:
+		return fmt.Sprintf(" (draw %v: %v)", draw, strings.Join(labels, " > "))
+	case len(labels) > 0:
+		return fmt.Sprintf(" (%v)", strings.Join(labels, " > "))
+	default:
+		return ""
 	}
 }
 
-func (rec *recordedBits) removeGroup(i int) {
-	g := rec.groups[i]
-	assert(g.end >= 0)
+// prune removes the discarded groups (together with the groups nested in them)
+// and their data in a single pass.
+func (rec *recordedBits) prune() {
+	assert(rec.persist)
 
-	j := i + 1
-	for j < len(rec.groups) && rec.groups[j].end <= g.end {
-		j++
+	var (
+		cuts   []groupInfo // discarded data ranges, in order
+		cutEnd = 0
+		groups = rec.groups[:0]
+	)
+	for _, g := range rec.groups {
+		if g.begin < cutEnd {
+			continue // nested in a discarded group
+		}
+		if g.discard {
+			assert(g.end >= 0)
+			cuts = append(cuts, g)
+			cutEnd = g.end
+			continue
+		}
+		groups = append(groups, g)
 	}
 
-	rec.data = append(rec.data[:g.begin], rec.data[g.end:]...)
-	rec.groups = append(rec.groups[:i], rec.groups[j:]...)
+	// removed[i] is the amount of data removed by cuts[:i+1]
+	removed := make([]int, len(cuts))
+	data := rec.data[:0]
+	prev := 0
+	for i, c := range cuts {
+		data = append(data, rec.data[prev:c.begin]...)
+		prev = c.end
+		removed[i] = c.end - c.begin
+		if i > 0 {
+			removed[i] += removed[i-1]
+		}
+	}
+	data = append(data, rec.data[prev:]...)
 
-	n := g.end - g.begin
-	for j := range rec.groups {
-		if rec.groups[j].begin >= g.end {
-			rec.groups[j].begin -= n
+	shift := func(pos int) int {
+		i := sort.Search(len(cuts), func(i int) bool { return cuts[i].end > pos })
+		if i == 0 {
+			return pos
 		}
-		if rec.groups[j].end >= g.end {
-			rec.groups[j].end -= n
+		return pos - removed[i-1]
+	}
+	for i := range groups {
+		groups[i].begin = shift(groups[i].begin)
+		if groups[i].end >= 0 {
+			groups[i].end = shift(groups[i].end)
 		}
 	}
+
+	rec.data = data
+	rec.groups = groups
+
+	for _, g := range rec.groups {
+		assert(g.begin != g.end)
+	}
 }
 
 // "A Small Noncryptographic PRNG" by Bob Jenkins
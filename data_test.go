@@ -8,8 +8,10 @@ package rapid
 
 import (
 	"fmt"
+	"math"
 	"math/bits"
 	"math/rand"
+	"strings"
 	"testing"
 )
 
@@ -103,3 +105,240 @@ func BenchmarkRandomBitStream_DrawBits64(b *testing.B) {
 		s.drawBits(64)
 	}
 }
+
+// pruneQuadratic is the straightforward reference implementation of prune.
+func pruneQuadratic(rec *recordedBits) {
+	for i := 0; i < len(rec.groups); {
+		if !rec.groups[i].discard {
+			i++
+			continue
+		}
+
+		g := rec.groups[i]
+		j := i + 1
+		for j < len(rec.groups) && rec.groups[j].end <= g.end {
+			j++
+		}
+
+		rec.data = append(rec.data[:g.begin], rec.data[g.end:]...)
+		rec.groups = append(rec.groups[:i], rec.groups[j:]...)
+
+		n := g.end - g.begin
+		for j := range rec.groups {
+			if rec.groups[j].begin >= g.end {
+				rec.groups[j].begin -= n
+			}
+			if rec.groups[j].end >= g.end {
+				rec.groups[j].end -= n
+			}
+		}
+	}
+}
+
+func recordDiscards(seed uint64, n int) *recordedBits {
+	s := newRandomBitStream(seed, true)
+
+	var group func(depth int)
+	group = func(depth int) {
+		i := s.beginGroup("", false)
+		s.drawBits(64)
+		for depth > 0 && s.drawBits(1) == 1 {
+			group(depth - 1)
+		}
+		s.endGroup(i, s.drawBits(2) == 0)
+	}
+	for i := 0; i < n; i++ {
+		group(3)
+	}
+
+	return &s.recordedBits
+}
+
+func TestRecordedBits_Prune(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 100; i++ {
+		rec := recordDiscards(uint64(i), 10)
+		ref := &recordedBits{
+			data:    append([]uint64(nil), rec.data...),
+			groups:  append([]groupInfo(nil), rec.groups...),
+			persist: true,
+		}
+
+		rec.prune()
+		pruneQuadratic(ref)
+
+		if fmt.Sprint(rec.data) != fmt.Sprint(ref.data) {
+			t.Fatalf("seed %v: got data %v instead of %v", i, rec.data, ref.data)
+		}
+		if fmt.Sprint(rec.groups) != fmt.Sprint(ref.groups) {
+			t.Fatalf("seed %v: got groups %v instead of %v", i, rec.groups, ref.groups)
+		}
+	}
+}
+
+func BenchmarkRecordedBits_Prune(b *testing.B) {
+	impls := []struct {
+		name  string
+		prune func(*recordedBits)
+	}{
+		{"single-pass", (*recordedBits).prune},
+		{"quadratic", pruneQuadratic},
+	}
+
+	for _, n := range []int{10, 100, 1000} {
+		rec := recordDiscards(baseSeed(), n)
+		for _, impl := range impls {
+			prune := impl.prune
+			b.Run(fmt.Sprintf("%v/n=%v", impl.name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					r := recordedBits{
+						data:    append([]uint64(nil), rec.data...),
+						groups:  append([]groupInfo(nil), rec.groups...),
+						persist: true,
+					}
+					prune(&r)
+				}
+			})
+		}
+	}
+}
+
+func TestRandomBitStream_InitResets(t *testing.T) {
+	t.Parallel()
+
+	s := newRandomBitStream(baseSeed(), true)
+	i := s.beginGroup("", false)
+	s.drawBits(64)
+	s.endGroup(i, false)
+
+	s.init(baseSeed())
+	if len(s.data) != 0 || len(s.groups) != 0 {
+		t.Fatalf("got %v data and %v groups after init", len(s.data), len(s.groups))
+	}
+}
+
+func BenchmarkShrinkerCheck(b *testing.B) {
+	buf := []uint64{1, 2, 3, 4, 5, 6, 7, 8}
+	s := &shrinker{prop: func(t *T) { SliceOfN(Int(), 4, 4).Draw(t, "s") }}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.check(buf)
+	}
+}
+
+func TestBytesToWords(t *testing.T) {
+	t.Parallel()
+
+	buf := bytesToWords([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	if fmt.Sprintf("%x", buf) != "[807060504030201 9]" {
+		t.Fatalf("got %x", buf)
+	}
+}
+
+func TestByteStream(t *testing.T) {
+	t.Parallel()
+
+	g := SliceOfN(Int8(), 3, 3)
+	data := make([]byte, 1024)
+	for i := range data {
+		data[i] = byte(i * 37)
+	}
+	s := NewByteStream(data)
+	v, err := s.Draw(g)
+	if err != nil {
+		t.Fatalf("failed to draw %v: %v", g, err)
+	}
+
+	v2, err := NewByteStream(s.Bytes()).Draw(g)
+	if err != nil {
+		t.Fatalf("failed to draw %v from used bytes: %v", g, err)
+	}
+	if fmt.Sprint(v) != fmt.Sprint(v2) {
+		t.Fatalf("got %v from used bytes instead of %v", v2, v)
+	}
+
+	v, err = NewByteStream(nil).Draw(g)
+	if err != nil {
+		t.Fatalf("failed to draw %v from empty stream: %v", g, err)
+	}
+	if fmt.Sprint(v) != "[0 0 0]" {
+		t.Fatalf("got %v from empty stream", v)
+	}
+}
+
+func TestBufBitStream_ZeroPad(t *testing.T) {
+	t.Parallel()
+
+	s := newBufBitStream([]uint64{3}, true)
+	s.zeroPad = true
+	for i, want := range []uint64{3, 0, 0} {
+		if u := s.drawBits(8); u != want {
+			t.Fatalf("draw %v: got %v instead of %v", i, u, want)
+		}
+	}
+	if len(s.data) != 3 {
+		t.Fatalf("recorded %v words instead of 3", len(s.data))
+	}
+}
+
+func TestStableSeed(t *testing.T) {
+	t.Parallel()
+
+	seed := stableSeed("TestFoo", 1, 0)
+	if s := stableSeed("TestFoo", 1, 0); s != seed {
+		t.Fatalf("got seeds %v and %v for the same test and epoch", seed, s)
+	}
+	for _, s := range []uint64{stableSeed("TestBar", 1, 0), stableSeed("TestFoo", 2, 0), stableSeed("TestFoo", 1, 1)} {
+		if s == seed {
+			t.Fatalf("got seed %v for different tests, epochs or checks", s)
+		}
+	}
+}
+
+func TestShardSeed(t *testing.T) {
+	t.Parallel()
+
+	const shards = 3
+	width := uint64(math.MaxUint64 / shards)
+	for _, seed := range []uint64{0, 1, shardMaxCases, math.MaxUint64 / 2, math.MaxUint64} {
+		for shard := 0; shard < shards; shard++ {
+			s := shardSeed(seed, shard, shards)
+			if s < uint64(shard)*width || s+shardMaxCases > uint64(shard+1)*width {
+				t.Fatalf("seed %v of shard %v is out of the shard range", s, shard)
+			}
+		}
+	}
+
+	if s := shardSeed(12345, 0, 1); s != 12345 {
+		t.Fatalf("got seed %v without sharding", s)
+	}
+}
+
+func TestRecordedBits_Trail(t *testing.T) {
+	t.Parallel()
+
+	overrun := func(t *T) {
+		_ = Int().Draw(t, "n")
+		_ = SliceOfN(Int(), 5, 5).Draw(t, "s")
+	}
+	customOverrun := func(t *T) {
+		_ = Custom(func(t *T) []int {
+			return SliceOfN(Int(), 5, 5).Draw(t, "s").([]int)
+		}).Draw(t, "c")
+	}
+
+	for _, persist := range []bool{true, false} {
+		err := checkOnce(newT(t, newBufBitStream(make([]uint64, 8), persist), false, nil), overrun)
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid data: overrun (draw 1: draw:s > ") {
+			t.Errorf("no trail of the open groups in %v (persist %v)", err, persist)
+		}
+
+		err = checkOnce(newT(t, newBufBitStream(make([]uint64, 4), persist), false, nil), customOverrun)
+		if err == nil || err.Error() != "invalid data: overrun (draw 0: draw:c > try)" {
+			t.Errorf("no trail of the open groups in %v (persist %v)", err, persist)
+		}
+	}
+}
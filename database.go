@@ -0,0 +1,260 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// ExampleDatabase stores failing test cases, so that they can be shared between
+// machines and runs. Keys are test names, and values are opaque to the database.
+// Implementations should be safe for concurrent use, and should not store the
+// same value twice for the same key.
+type ExampleDatabase interface {
+	Get(key string) ([][]byte, error)
+	Put(key string, value []byte) error
+	Delete(key string, value []byte) error
+}
+
+// saveToDatabase saves the failing test case to db, unless db already has
+// an entry which decodes to the same values. Raw bitstreams are not compared,
+// as after a change in the generators different bitstreams can produce
+// identical values.
+func saveToDatabase(tb tb, db ExampleDatabase, prop func(*T), ff failFile) error {
+	values, err := db.Get(tb.Name())
+	if err != nil {
+		return err
+	}
+
+	h := valuesHash(tb, prop, ff.buf)
+	for _, v := range values {
+		ff2, err := readFailFile(bytes.NewReader(v))
+		if err == nil && ff2.version == rapidVersion && valuesHash(tb, prop, ff2.buf) == h {
+			return nil
+		}
+	}
+
+	return db.Put(tb.Name(), formatFailFile(nil, ff))
+}
+
+// valuesHash hashes the labels and values of the top-level draws made by
+// the test case. Values are hashed by valueHash, so that pointers are compared
+// by the values they point to, and not by their addresses (which even the
+// printed values include, unless a printer is registered for the type).
+func valuesHash(tb tb, prop func(*T), buf []uint64) uint64 {
+	draws, _ := topLevelDraws(tb, prop, buf)
+
+	h := fnv.New64a()
+	for _, d := range draws {
+		_, _ = fmt.Fprintf(h, "%v\x00%x\x00", d.label, valueHash(d.v))
+	}
+
+	return h.Sum64()
+}
+
+type dirDatabase struct {
+	dir string
+}
+
+// NewDirectoryDatabase returns an example database which stores every value
+// as a separate file in a subdirectory of dir named after the key.
+func NewDirectoryDatabase(dir string) ExampleDatabase {
+	return &dirDatabase{dir: dir}
+}
+
+func (db *dirDatabase) keyDir(key string) string {
+	return filepath.Join(db.dir, kindaSafeFilename(key))
+}
+
+func (db *dirDatabase) Get(key string) ([][]byte, error) {
+	files, err := filepath.Glob(filepath.Join(db.keyDir(key), "*.fail"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var values [][]byte
+	for _, f := range files {
+		v, err := ioutil.ReadFile(f)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // removed concurrently
+			}
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+func (db *dirDatabase) valueFile(key string, value []byte) string {
+	h := fnv.New64a()
+	_, _ = h.Write(value)
+	return filepath.Join(db.keyDir(key), fmt.Sprintf("%016x.fail", h.Sum64()))
+}
+
+func (db *dirDatabase) Put(key string, value []byte) error {
+	return writeFileAtomic(db.valueFile(key, value), value)
+}
+
+func (db *dirDatabase) Delete(key string, value []byte) error {
+	err := os.Remove(db.valueFile(key, value))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+type memDatabase struct {
+	mu     sync.Mutex
+	values map[string][][]byte
+}
+
+// NewInMemoryDatabase returns an example database which keeps the values in memory.
+func NewInMemoryDatabase() ExampleDatabase {
+	return &memDatabase{values: map[string][][]byte{}}
+}
+
+func (db *memDatabase) Get(key string) ([][]byte, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	values := make([][]byte, len(db.values[key]))
+	for i, v := range db.values[key] {
+		values[i] = append([]byte(nil), v...)
+	}
+
+	return values, nil
+}
+
+func (db *memDatabase) Put(key string, value []byte) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, v := range db.values[key] {
+		if bytes.Equal(v, value) {
+			return nil
+		}
+	}
+	db.values[key] = append(db.values[key], append([]byte(nil), value...))
+
+	return nil
+}
+
+func (db *memDatabase) Delete(key string, value []byte) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	values := db.values[key][:0]
+	for _, v := range db.values[key] {
+		if !bytes.Equal(v, value) {
+			values = append(values, v)
+		}
+	}
+	db.values[key] = values
+
+	return nil
+}
+
+// MinimizeDatabase keeps the entries of db stored under key small and meaningful:
+// entries on which prop no longer fails are removed, entries on which it still fails
+// are minimized, and of the entries which fail the same way only the smallest is kept.
+func MinimizeDatabase(t *testing.T, db ExampleDatabase, key string, prop func(*T), opts ...Option) {
+	t.Helper()
+	minimizeDatabase(t, db, key, prop, opts...)
+}
+
+func minimizeDatabase(tb tb, db ExampleDatabase, key string, prop func(*T), opts ...Option) {
+	tb.Helper()
+
+	values, err := db.Get(key)
+	if err != nil {
+		tb.Fatalf("[rapid] failed to load example database entries: %v", err)
+		return
+	}
+
+	cfg := newSettings(opts)
+	var (
+		best   = map[string][]uint64{} // smallest failing test case for every traceback
+		seeds  = map[string]uint64{}
+		failed []string // tracebacks, in order of first failure
+	)
+	for i, v := range values {
+		ff, err := readFailFile(bytes.NewReader(v))
+		if err != nil || ff.version != rapidVersion {
+			continue // not ours to judge
+		}
+
+		s := newBufBitStream(ff.buf, true)
+		t := newT(tb, s, false, nil)
+		t.genHash = fnv.New64a()
+		err1 := checkOnce(t, prop)
+		if t.genHash.Sum64() != ff.genHash {
+			tb.Logf("[rapid] example database entry #%v was recorded with different generators", i)
+			continue
+		}
+		if err1 == nil || err1.isInvalidData() {
+			tb.Logf("[rapid] example database entry #%v no longer fails", i)
+			continue
+		}
+
+		buf, err2 := shrink(tb, cfg, s.recordedBits, err1, prop)
+		if !sameError(err1, err2) {
+			buf, err2 = ff.buf, err1 // can not minimize a flaky test case
+		}
+		seed := ff.seed
+		if compareData(buf, ff.buf) != 0 {
+			seed = 0 // the minimized test case is not generated from the seed
+		}
+		trace := traceback(err2)
+		if b, ok := best[trace]; !ok {
+			failed = append(failed, trace)
+			best[trace] = buf
+			seeds[trace] = seed
+		} else if compareData(buf, b) < 0 {
+			best[trace] = buf
+			seeds[trace] = seed
+		}
+	}
+
+	kept := map[string]bool{}
+	for _, trace := range failed {
+		_, genHash := captureTestOutput(tb, prop, best[trace])
+		v := formatFailFile(nil, failFile{version: rapidVersion, genHash: genHash, seed: seeds[trace], buf: best[trace]})
+		kept[string(v)] = true
+		if err := db.Put(key, v); err != nil {
+			tb.Errorf("[rapid] failed to save minimized example database entry: %v", err)
+			return
+		}
+	}
+
+	removed := 0
+	for _, v := range values {
+		if ff, err := readFailFile(bytes.NewReader(v)); err != nil || ff.version != rapidVersion {
+			continue // not ours to judge
+		}
+		if kept[string(v)] {
+			continue
+		}
+		if err := db.Delete(key, v); err != nil {
+			tb.Errorf("[rapid] failed to remove example database entry: %v", err)
+			return
+		}
+		removed++
+	}
+
+	tb.Logf("[rapid] minimized example database: kept %v entries, removed %v", len(failed), removed)
+}
@@ -0,0 +1,183 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestExampleDatabase(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "rapid-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	dbs := map[string]ExampleDatabase{
+		"directory": NewDirectoryDatabase(dir),
+		"in-memory": NewInMemoryDatabase(),
+	}
+	for name, db := range dbs {
+		t.Run(name, func(t *testing.T) {
+			for _, v := range []string{"a", "b", "a"} {
+				if err := db.Put("TestFoo/bar", []byte(v)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			values, err := db.Get("TestFoo/bar")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range values {
+				got = append(got, string(v))
+			}
+			sort.Strings(got)
+			if fmt.Sprint(got) != "[a b]" {
+				t.Fatalf("got values %q instead of a and b", got)
+			}
+
+			values, err = db.Get("TestFoo")
+			if err != nil || len(values) != 0 {
+				t.Fatalf("got values %q (error %v) for a different key", values, err)
+			}
+
+			if err := db.Delete("TestFoo/bar", []byte("a")); err != nil {
+				t.Fatal(err)
+			}
+			values, err = db.Get("TestFoo/bar")
+			if err != nil || fmt.Sprintf("%s", values) != "[b]" {
+				t.Fatalf("got values %q (error %v) after deleting a", values, err)
+			}
+		})
+	}
+}
+
+func TestCheckDatabase(t *testing.T) {
+	prop := func(t *T) {
+		if Int().Draw(t, "x").(int) > 10 {
+			t.Fatal("too big")
+		}
+	}
+
+	db := NewInMemoryDatabase()
+	checkTB(&logTB{T: t}, prop, Database(db))
+	removeFailFiles(t.Name())
+
+	values, _ := db.Get(t.Name())
+	if len(values) != 1 {
+		t.Fatalf("got %v values saved instead of 1", len(values))
+	}
+
+	_, _, _, _, err, _ := doCheck(t, newSettings([]Option{Database(db), func(s *settings) { s.checks = 0 }}), baseSeed(), prop)
+	if err == nil {
+		t.Fatal("failing test case saved in the database was not replayed")
+	}
+}
+
+func TestDatabaseDeduplication(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		if Bool().Draw(t, "b").(bool) {
+			t.Fail()
+		}
+	}
+
+	// only the lowest bit is used, so both bitstreams decode to true
+	db := NewInMemoryDatabase()
+	for _, buf := range [][]uint64{{1}, {3}} {
+		err := saveToDatabase(t, db, prop, testFailFile(t, prop, 0, buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if values, _ := db.Get(t.Name()); len(values) != 1 {
+		t.Fatalf("got %v entries instead of 1", len(values))
+	}
+
+	_ = db.Put(t.Name(), formatFailFile(nil, testFailFile(t, prop, 0, []uint64{3})))
+	_, _, err1, _ := checkDatabase(t, db, nil, prop)
+	if err1 == nil {
+		t.Fatal("failing test case saved in the database was not replayed")
+	}
+	if values, _ := db.Get(t.Name()); len(values) != 1 {
+		t.Fatalf("got %v entries instead of 1 after replay", len(values))
+	}
+}
+
+func TestValuesHashPointers(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		Ptr(IntRange(0, 9), false).Draw(t, "p")
+	}
+
+	s := newRandomBitStream(baseSeed(), true)
+	_ = checkOnce(newT(t, s, false, nil), prop)
+	if h1, h2 := valuesHash(t, prop, s.data), valuesHash(t, prop, s.data); h1 != h2 {
+		t.Fatalf("same pointer values hashed as %x and %x", h1, h2)
+	}
+}
+
+func TestMinimizeDatabase(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		if Int().Draw(t, "x").(int) > 10 {
+			t.Fatal("too big")
+		}
+	}
+
+	db := NewInMemoryDatabase()
+	var failing, passing int
+	for seed := uint64(0); failing < 3 || passing < 1; seed++ {
+		s := newRandomBitStream(seed, true)
+		err := checkOnce(newT(t, s, false, nil), prop)
+		if err == nil && passing < 1 {
+			passing++
+		} else if err != nil && failing < 3 {
+			failing++
+		} else {
+			continue
+		}
+		_ = db.Put(t.Name(), formatFailFile(nil, testFailFile(t, prop, seed, s.data)))
+	}
+	_ = db.Put(t.Name(), []byte("garbage"))
+
+	minimizeDatabase(t, db, t.Name(), prop)
+
+	values, _ := db.Get(t.Name())
+	if len(values) != 2 {
+		t.Fatalf("got %v entries instead of 2", len(values))
+	}
+	var ff failFile
+	for _, v := range values {
+		if string(v) == "garbage" {
+			continue
+		}
+		var err error
+		if ff, err = readFailFile(bytes.NewReader(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ff.seed != 0 {
+		t.Fatalf("got seed %v for the minimized entry", ff.seed)
+	}
+	draws, _ := topLevelDraws(t, prop, ff.buf)
+	if len(draws) != 1 || draws[0].v != 11 {
+		t.Fatalf("got draws %v instead of x = 11", draws)
+	}
+}
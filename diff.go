@@ -0,0 +1,299 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	diffMaxLines = 20
+	diffMissing  = "<missing>"
+	diffLine     = "%v: - %v + %v" // markers are spaced apart from values, which may be negative
+)
+
+// DiffOption changes how Diff and AssertEqual compare values.
+type DiffOption func(*diffConfig)
+
+type diffConfig struct {
+	comparers   map[reflect.Type]reflect.Value
+	ignored     map[reflect.Type]map[string]bool
+	equateEmpty bool
+}
+
+// Comparer makes values of type T compared using fn, which should have
+// the form func(T, T) bool (e.g. to compare floating-point numbers
+// approximately, or values of types with unexported fields using
+// their methods).
+func Comparer(fn interface{}) DiffOption {
+	f := reflect.ValueOf(fn)
+	typ := f.Type()
+	assertf(typ.Kind() == reflect.Func, "comparer should be a function, not %v", typ.Kind())
+	assertf(typ.NumIn() == 2 && typ.In(0) == typ.In(1), "comparer should have 2 arguments of the same type, not %v", typ)
+	assertf(typ.NumOut() == 1 && typ.Out(0).Kind() == reflect.Bool, "comparer should return bool, not %v", typ)
+
+	return func(c *diffConfig) {
+		c.comparers[typ.In(0)] = f
+	}
+}
+
+// IgnoreFields makes the named fields of the struct type of v ignored.
+func IgnoreFields(v interface{}, names ...string) DiffOption {
+	typ := reflect.TypeOf(v)
+	assertf(typ != nil && typ.Kind() == reflect.Struct, "fields can only be ignored in structs, not %v", typ)
+	for _, name := range names {
+		_, ok := typ.FieldByName(name)
+		assertf(ok, "%v has no field %v", typ, name)
+	}
+
+	return func(c *diffConfig) {
+		if c.ignored[typ] == nil {
+			c.ignored[typ] = map[string]bool{}
+		}
+		for _, name := range names {
+			c.ignored[typ][name] = true
+		}
+	}
+}
+
+// EquateEmpty makes nil and empty slices and maps equal.
+func EquateEmpty() DiffOption {
+	return func(c *diffConfig) {
+		c.equateEmpty = true
+	}
+}
+
+// Diff returns a description of the differences between want and got, one per line
+// in the form "path: -want +got" (with the first 20 differences only), or an empty
+// string when the values are equal. Without options, values are equal when they
+// are deeply equal (see reflect.DeepEqual).
+func Diff(want interface{}, got interface{}, opts ...DiffOption) string {
+	cfg := &diffConfig{
+		comparers: map[reflect.Type]reflect.Value{},
+		ignored:   map[reflect.Type]map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	d := &differ{cfg: cfg}
+	d.diff("", reflect.ValueOf(want), reflect.ValueOf(got))
+	if len(d.lines) == 0 {
+		return ""
+	}
+
+	if n := len(d.lines) - diffMaxLines; n > 0 {
+		d.lines = append(d.lines[:diffMaxLines], fmt.Sprintf("... and %v more differences", n))
+	}
+	return strings.Join(d.lines, "\n")
+}
+
+// AssertEqual fails the test case when want and got differ (see Diff),
+// reporting the differences.
+func AssertEqual(t *T, want interface{}, got interface{}, opts ...DiffOption) {
+	if d := Diff(want, got, opts...); d != "" {
+		if t.tbLog && t.tb != nil {
+			t.tb.Helper()
+		}
+		t.Fatalf("values differ (-want +got):\n%v", d)
+	}
+}
+
+// shrinkDiff describes how the top-level draws of the failing test case have changed
+// from the original data to the minimized one, or returns an empty string if they have not.
+func shrinkDiff(tb tb, prop func(*T), original []uint64, minimized []uint64) string {
+	before, _ := topLevelDraws(tb, prop, original)
+	after, _ := topLevelDraws(tb, prop, minimized)
+
+	var lines, unchanged []string
+	for i := 0; i < len(before) || i < len(after); i++ {
+		switch {
+		case i >= len(before):
+			lines = append(lines, fmt.Sprintf(diffLine, after[i].label, diffMissing, after[i]))
+		case i >= len(after):
+			lines = append(lines, fmt.Sprintf(diffLine, before[i].label, before[i], diffMissing))
+		case before[i].label != after[i].label || before[i].redacted() || after[i].redacted():
+			if before[i].label == after[i].label && before[i].String() == after[i].String() {
+				unchanged = append(unchanged, before[i].label)
+			} else {
+				lines = append(lines, fmt.Sprintf(diffLine, before[i].label, before[i], after[i]))
+			}
+		default:
+			d := Diff(before[i].v, after[i].v)
+			if d == "" {
+				unchanged = append(unchanged, before[i].label)
+				continue
+			}
+			for _, line := range strings.Split(d, "\n") {
+				switch {
+				case strings.HasPrefix(line, "value: "):
+					line = strings.TrimPrefix(line, "value")
+				case strings.HasPrefix(line, "... "):
+					line = ": " + line
+				}
+				lines = append(lines, before[i].label+line)
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	if n := len(lines) - diffMaxLines; n > 0 {
+		lines = append(lines[:diffMaxLines], fmt.Sprintf("... and %v more differences", n))
+	}
+	if len(unchanged) > 0 {
+		lines = append(lines, "unchanged: "+strings.Join(unchanged, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+type differ struct {
+	cfg     *diffConfig
+	lines   []string
+	visited map[[2]uintptr]bool // pairs of pointers being compared, to stop at cycles
+}
+
+func (d *differ) report(path string, want string, got string) {
+	if path == "" {
+		path = "value"
+	}
+	d.lines = append(d.lines, fmt.Sprintf(diffLine, path, want, got))
+}
+
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	return printReflect(v)
+}
+
+func (d *differ) diff(path string, a reflect.Value, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			d.report(path, formatValue(a), formatValue(b))
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		d.report(path, fmt.Sprintf("%v(%v)", a.Type(), formatValue(a)), fmt.Sprintf("%v(%v)", b.Type(), formatValue(b)))
+		return
+	}
+
+	if cmp, ok := d.cfg.comparers[a.Type()]; ok && a.CanInterface() && b.CanInterface() {
+		if !cmp.Call([]reflect.Value{a, b})[0].Bool() {
+			d.report(path, formatValue(a), formatValue(b))
+		}
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Bool:
+		d.compare(path, a, b, a.Bool() == b.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		d.compare(path, a, b, a.Int() == b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		d.compare(path, a, b, a.Uint() == b.Uint())
+	case reflect.Float32, reflect.Float64:
+		d.compare(path, a, b, a.Float() == b.Float())
+	case reflect.Complex64, reflect.Complex128:
+		d.compare(path, a, b, a.Complex() == b.Complex())
+	case reflect.String:
+		d.compare(path, a, b, a.String() == b.String())
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		d.compare(path, a, b, a.Pointer() == b.Pointer() && (a.Kind() != reflect.Func || a.IsNil()))
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			d.compare(path, a, b, a.IsNil() == b.IsNil())
+			return
+		}
+		if a.Kind() == reflect.Ptr {
+			if a.Pointer() == b.Pointer() || d.cycle(a, b) {
+				return
+			}
+		}
+		d.diff(path, a.Elem(), b.Elem())
+	case reflect.Struct:
+		ignored := d.cfg.ignored[a.Type()]
+		for i := 0; i < a.NumField(); i++ {
+			name := a.Type().Field(i).Name
+			if !ignored[name] {
+				d.diff(path+"."+name, a.Field(i), b.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && a.IsNil() != b.IsNil() && !(d.cfg.equateEmpty && a.Len() == 0 && b.Len() == 0) {
+			d.report(path, formatValue(a), formatValue(b))
+			return
+		}
+		if a.Kind() == reflect.Slice && (a.Pointer() == b.Pointer() && a.Len() == b.Len()) {
+			return
+		}
+		for i := 0; i < a.Len() || i < b.Len(); i++ {
+			p := fmt.Sprintf("%v[%v]", path, i)
+			switch {
+			case i >= a.Len():
+				d.report(p, diffMissing, formatValue(b.Index(i)))
+			case i >= b.Len():
+				d.report(p, formatValue(a.Index(i)), diffMissing)
+			default:
+				d.diff(p, a.Index(i), b.Index(i))
+			}
+		}
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() && !(d.cfg.equateEmpty && a.Len() == 0 && b.Len() == 0) {
+			d.report(path, formatValue(a), formatValue(b))
+			return
+		}
+		if a.Pointer() == b.Pointer() {
+			return
+		}
+		keys := map[string]reflect.Value{}
+		for _, k := range append(a.MapKeys(), b.MapKeys()...) {
+			keys[formatValue(k)] = k
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p := fmt.Sprintf("%v[%v]", path, name)
+			va, vb := a.MapIndex(keys[name]), b.MapIndex(keys[name])
+			switch {
+			case !va.IsValid():
+				d.report(p, diffMissing, formatValue(vb))
+			case !vb.IsValid():
+				d.report(p, formatValue(va), diffMissing)
+			default:
+				d.diff(p, va, vb)
+			}
+		}
+	}
+}
+
+func (d *differ) compare(path string, a reflect.Value, b reflect.Value, equal bool) {
+	if !equal {
+		d.report(path, formatValue(a), formatValue(b))
+	}
+}
+
+// cycle reports whether the pointers are already being compared.
+func (d *differ) cycle(a reflect.Value, b reflect.Value) bool {
+	if d.visited == nil {
+		d.visited = map[[2]uintptr]bool{}
+	}
+	k := [2]uintptr{a.Pointer(), b.Pointer()}
+	if d.visited[k] {
+		return true
+	}
+	d.visited[k] = true
+	return false
+}
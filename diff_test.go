@@ -0,0 +1,105 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+type diffItem struct {
+	Name string
+	Tags []string
+	n    int
+}
+
+type diffNode struct {
+	Next *diffNode
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	cyclic := &diffNode{}
+	cyclic.Next = cyclic
+	approx := Comparer(func(a, b float64) bool { return math.Abs(a-b) < 0.01 })
+
+	testCases := []struct {
+		want interface{}
+		got  interface{}
+		opts []DiffOption
+		diff string
+	}{
+		{1, 1, nil, ""},
+		{1, 2, nil, "value: - 1 + 2"},
+		{-1076, 3, nil, "value: - -1076 + 3"},
+		{1, int64(1), nil, "value: - int(1) + int64(1)"},
+		{
+			diffItem{"a", []string{"x", "y"}, 1},
+			diffItem{"a", []string{"x", "z", "w"}, 2},
+			nil,
+			".Tags[1]: - \"y\" + \"z\"\n.Tags[2]: - <missing> + \"w\"\n.n: - 1 + 2",
+		},
+		{diffItem{n: 1}, diffItem{n: 2}, []DiffOption{IgnoreFields(diffItem{}, "n")}, ""},
+		{map[string]int{"a": 1, "b": 2}, map[string]int{"a": 1, "c": 2}, nil, "[\"b\"]: - 2 + <missing>\n[\"c\"]: - <missing> + 2"},
+		{[]int(nil), []int{}, nil, "value: - []int(nil) + []int{}"},
+		{[]int(nil), []int{}, []DiffOption{EquateEmpty()}, ""},
+		{[]float64{1, 2}, []float64{1.001, 2.5}, []DiffOption{approx}, "[1]: - 2 + 2.5"},
+		{cyclic, cyclic, nil, ""},
+		{&diffNode{cyclic}, &diffNode{cyclic}, nil, ""},
+	}
+
+	for _, tc := range testCases {
+		if d := Diff(tc.want, tc.got, tc.opts...); d != tc.diff {
+			t.Errorf("Diff(%#v, %#v) = %q instead of %q", tc.want, tc.got, d, tc.diff)
+		}
+	}
+}
+
+func TestDiff_Truncated(t *testing.T) {
+	t.Parallel()
+
+	lines := strings.Split(Diff(make([]int, 30), []int{}), "\n")
+	if len(lines) != diffMaxLines+1 || lines[diffMaxLines] != "... and 10 more differences" {
+		t.Errorf("diff is not truncated: %q", lines)
+	}
+}
+
+func TestAssertEqual(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		tags := SliceOfN(StringMatching("[a-c]"), 1, 3).Draw(t, "tags").([]string)
+		AssertEqual(t, diffItem{Tags: tags}, diffItem{Tags: tags[1:]}, EquateEmpty())
+	})
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	if s := "values differ (-want +got):\n.Tags[0]: - \"a\" + <missing>"; !strings.Contains(out, s) {
+		t.Errorf("%q not found in output:\n%v", s, out)
+	}
+}
+
+func TestShrinkDiff(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		n := IntRange(0, 1000).Draw(t, "n").(int)
+		s := SliceOfN(IntRange(1, 1000), 5, 10).Draw(t, "s").([]int)
+		_ = Just(7).Draw(t, "c")
+		if n > 50 && len(s) > 0 {
+			t.Fatal("n too large")
+		}
+	})
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	for _, s := range []string{"minimization has changed the drawn values (-original +minimized):\n", "\nn: - ", " + 51\n", "\nunchanged: c\n"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in output:\n%v", s, out)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// machineTrace records the steps of a state machine test case.
+type machineTrace struct {
+	steps   []machineStep
+	pending *machineStep // step which has started, but has not finished
+	from    int          // index of the first top-level draw of the pending step
+}
+
+type machineStep struct {
+	action  string
+	args    []drawnValue
+	created []actorRef
+	used    []actorRef
+	result  interface{}
+	state   string
+}
+
+// begin, end and abort do nothing when the trace is nil, i.e. when
+// the test case is not traced.
+func (tr *machineTrace) begin(t *T, action string) {
+	if tr == nil {
+		return
+	}
+	if t.topDraws == nil {
+		t.topDraws = []drawnValue{}
+	}
+	tr.pending = &machineStep{action: action}
+	tr.from = len(t.topDraws)
+}
+
+func (tr *machineTrace) end(t *T, state interface{}) {
+	if tr == nil {
+		return
+	}
+	step := *tr.pending
+	step.args = append([]drawnValue(nil), t.topDraws[tr.from:]...)
+	if s, ok := state.(fmt.Stringer); ok {
+		step.state = s.String()
+	}
+
+	tr.steps = append(tr.steps, step)
+	tr.pending = nil
+}
+
+// actor records that the pending step has created or used an instance.
+func (tr *machineTrace) actor(created bool, kind string, id int) {
+	if tr == nil || tr.pending == nil {
+		return
+	}
+	if created {
+		tr.pending.created = append(tr.pending.created, actorRef{kind, id})
+	} else {
+		tr.pending.used = append(tr.pending.used, actorRef{kind, id})
+	}
+}
+
+// result records the result of the action of the pending step.
+func (tr *machineTrace) result(result interface{}) {
+	if tr == nil || tr.pending == nil {
+		return
+	}
+	tr.pending.result = result
+}
+
+func (tr *machineTrace) abort() {
+	if tr == nil {
+		return
+	}
+	tr.pending = nil
+}
+
+// all returns the steps of the trace, including the pending one, if any
+// (e.g. because its action has failed).
+func (tr *machineTrace) all(t *T) []machineStep {
+	if tr.pending == nil {
+		return tr.steps
+	}
+
+	pending := *tr.pending
+	pending.args = t.topDraws[tr.from:]
+	return append(tr.steps[:len(tr.steps):len(tr.steps)], pending)
+}
+
+// script describes the steps of the failed test case as numbered calls
+// with their results, like "1. Put(key="a", val=3) → ok".
+func (tr *machineTrace) script(t *T, err *testError) string {
+	var b strings.Builder
+	for i, step := range tr.all(t) {
+		var args []string
+		for _, r := range step.used {
+			args = append(args, r.String())
+		}
+		for _, d := range step.args {
+			args = append(args, fmt.Sprintf("%v=%v", d.label, t.printDrawn(d)))
+		}
+
+		result := "ok"
+		switch {
+		case i == len(tr.steps):
+			result = err.Error()
+		case step.result != nil:
+			result = t.printDrawn(drawnValue{v: step.result})
+		}
+		fmt.Fprintf(&b, "%v. %v(%v) → %v\n", i, step.action, strings.Join(args, ", "), result)
+	}
+	if tr.pending == nil && err != nil {
+		fmt.Fprintf(&b, "failed after step %v: %v\n", len(tr.steps)-1, err)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// key describes the step by its action, the instances it has used
+// and the values it has drawn.
+func (step *machineStep) key() string {
+	var b strings.Builder
+	b.WriteString(step.action)
+	for _, r := range step.used {
+		fmt.Fprintf(&b, " %v", r)
+	}
+	for _, d := range step.args {
+		fmt.Fprintf(&b, " %v=%v", d.label, d)
+	}
+	return b.String()
+}
+
+func (step *machineStep) uses(r actorRef) bool {
+	for _, u := range step.used {
+		if u == r {
+			return true
+		}
+	}
+	return false
+}
+
+// dot returns the DOT graph of the trace: states are nodes, and actions
+// (together with the values they have drawn) are edges. The state the test case
+// has failed in is colored red.
+func (tr *machineTrace) dot(name string, err *testError) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %v {\n", strconv.Quote(name))
+	b.WriteString("\ts0 [shape=point];\n")
+
+	steps := tr.steps
+	if tr.pending != nil {
+		steps = append(steps, *tr.pending)
+	}
+	for i, step := range steps {
+		label := step.state
+		if label == "" {
+			label = strconv.Itoa(i)
+		}
+		attrs := ""
+		if i == len(steps)-1 && err != nil {
+			if tr.pending != nil {
+				label = "failed"
+			}
+			label += "\n" + err.Error()
+			attrs = ", color=red"
+		}
+		fmt.Fprintf(&b, "\ts%v [label=%v%v];\n", i+1, strconv.Quote(label), attrs)
+
+		edge := step.action
+		for _, r := range step.used {
+			edge += fmt.Sprintf("\n%v", r)
+		}
+		for _, d := range step.args {
+			edge += fmt.Sprintf("\n%v: %v", d.label, d)
+		}
+		fmt.Fprintf(&b, "\ts%v -> s%v [label=%v];\n", i, i+1, strconv.Quote(edge))
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func saveMachineGraph(filename string, tr *machineTrace, name string, err *testError) error {
+	return writeFileAtomic(filename, []byte(tr.dot(name, err)))
+}
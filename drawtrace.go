@@ -0,0 +1,69 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// drawTrace records every draw of a test case, nested ones included, in the order they begin.
+type drawTrace struct {
+	mu    sync.Mutex
+	draws []drawRecord
+}
+
+// drawRecord is the JSON representation of a draw.
+type drawRecord struct {
+	Path      string `json:"path"`
+	Generator string `json:"generator"`
+	Value     string `json:"value"`
+	Bits      int    `json:"bits"` // drawn by the generator
+}
+
+type drawTraceFile struct {
+	Test  string       `json:"test"`
+	Error string       `json:"error"`
+	Draws []drawRecord `json:"draws"`
+}
+
+// begin records the start of a draw, returning its index.
+func (tr *drawTrace) begin(path string, g *Generator) int {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.draws = append(tr.draws, drawRecord{Path: path, Generator: g.String()})
+	return len(tr.draws) - 1
+}
+
+// end records the value of the draw and the number of bits it has used.
+func (tr *drawTrace) end(i int, d drawnValue, bits int) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.draws[i].Value = d.String()
+	tr.draws[i].Bits = bits
+}
+
+func saveDrawTrace(filename string, tr *drawTrace, testName string, err *testError) error {
+	f := drawTraceFile{
+		Test:  testName,
+		Error: errorString(err),
+		Draws: tr.draws,
+	}
+	if f.Draws == nil {
+		f.Draws = []drawRecord{}
+	}
+
+	b, err1 := json.MarshalIndent(f, "", "  ")
+	if err1 != nil {
+		return fmt.Errorf("failed to encode draw trace %q: %w", filename, err1)
+	}
+
+	return writeFileAtomic(filename, append(b, '\n'))
+}
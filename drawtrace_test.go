@@ -0,0 +1,82 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func drawTraceProp(t *T) {
+	p := Custom(func(t *T) [2]int {
+		return [2]int{Int().Draw(t, "x").(int), Int().Draw(t, "y").(int)}
+	}).Draw(t, "p").([2]int)
+	if p[1] > 0 {
+		t.Fatal("positive y")
+	}
+}
+
+func TestDrawTraceFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rapid-drawtrace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	tb := &logTB{T: t}
+	checkTB(tb, drawTraceProp, ExportDrawTraces(dir))
+	defer removeFailFiles(t.Name())
+
+	if !strings.Contains(tb.out.String(), "trace of the draws written to") {
+		t.Fatalf("draw trace file not referenced in output:\n%v", tb.out.String())
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.draws.json"))
+	if len(files) != 1 {
+		t.Fatalf("got %v draw trace files", len(files))
+	}
+	b, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var f drawTraceFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Test != t.Name() || f.Error != "positive y" || len(f.Draws) != 3 {
+		t.Fatalf("unexpected draw trace %+v", f)
+	}
+	for i, path := range []string{"p", "p.x", "p.y"} {
+		if f.Draws[i].Path != path || f.Draws[i].Generator == "" || f.Draws[i].Bits <= 0 {
+			t.Errorf("unexpected draw %v: %+v", i, f.Draws[i])
+		}
+	}
+	if f.Draws[0].Value != "[2]int{0, 1}" || f.Draws[0].Bits < f.Draws[1].Bits+f.Draws[2].Bits {
+		t.Errorf("unexpected draw of p: %+v", f.Draws[0])
+	}
+}
+
+func TestDrawTraceFile_Default(t *testing.T) {
+	if flags.drawTraceDir != "" {
+		t.Skip("-rapid.drawtracedir is set")
+	}
+
+	tb := &logTB{T: t}
+	checkTB(tb, drawTraceProp)
+	defer removeFailFiles(t.Name())
+
+	if strings.Contains(tb.out.String(), "trace of the draws written to") {
+		t.Errorf("draw trace file written without ExportDrawTraces:\n%v", tb.out.String())
+	}
+	if files, _ := filepath.Glob(filepath.Join(failFileDir(t.Name()), "*.draws.json")); len(files) != 0 {
+		t.Errorf("got draw trace files %v", files)
+	}
+}
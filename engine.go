@@ -10,8 +10,11 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"hash"
+	"hash/fnv"
 	"log"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"runtime"
@@ -29,6 +32,9 @@ const (
 	tracebackLen  = 32
 	tracebackStop = "pgregory.net/rapid.checkOnce"
 	runtimePrefix = "runtime."
+	rapidPrefix   = "pgregory.net/rapid."
+
+	drawLabelPrefix = "draw:"
 )
 
 var (
@@ -40,33 +46,74 @@ var (
 	tracebackBlacklist = map[string]bool{
 		"pgregory.net/rapid.(*customGen).maybeValue.func1": true,
 		"pgregory.net/rapid.runAction.func1":               true,
+		"pgregory.net/rapid.(*shrinker).shrink.func1":      true,
 	}
 )
 
 type cmdline struct {
-	checks     int
-	steps      int
-	failfile   string
-	nofailfile bool
-	seed       uint64
-	log        bool
-	verbose    bool
-	debug      bool
-	debugvis   bool
-	shrinkTime time.Duration
+	checks         int
+	steps          int
+	failfile       string
+	nofailfile     bool
+	seed           uint64
+	epoch          int64
+	shard          int
+	shards         int
+	log            bool
+	verbose        bool
+	debug          bool
+	debugvis       bool
+	shrinkTime     time.Duration
+	shrinkAttempts int
+	shrinkProgress bool
+	debugshrink    bool
+	exportDir      string
+	dotDir         string
+	traceDir       string
+	drawTraceDir   string
+	maxDuration    time.Duration
+	actionTimeout  time.Duration
+	maxValueLen    int
+	maxReportLen   int
+	valueDir       string
+	htmlReport     string
+	annotations    bool
+	complexity     bool
+	vacuity        bool
+	coverage       bool
 }
 
 func init() {
 	flag.IntVar(&flags.checks, "rapid.checks", 100, "rapid: number of checks to perform")
 	flag.IntVar(&flags.steps, "rapid.steps", 100, "rapid: number of state machine steps to perform")
 	flag.StringVar(&flags.failfile, "rapid.failfile", "", "rapid: fail file to use to reproduce test failure")
-	flag.BoolVar(&flags.nofailfile, "rapid.nofailfile", false, "rapid: do not write fail files on test failures")
+	flag.BoolVar(&flags.nofailfile, "rapid.nofailfile", false, "rapid: do not write fail files on test failures, or replay the saved ones")
 	flag.Uint64Var(&flags.seed, "rapid.seed", 0, "rapid: PRNG seed to start with (0 to use a random one)")
+	flag.Int64Var(&flags.epoch, "rapid.epoch", -1, "rapid: derive the PRNG seeds from the test names and this epoch (e.g. a CI build number) instead of the current time (-1 to use the time)")
+	flag.IntVar(&flags.shard, "rapid.shard", 0, "rapid: index of the shard (from 0) when running the tests in -rapid.shards parallel jobs")
+	flag.IntVar(&flags.shards, "rapid.shards", 0, "rapid: number of parallel jobs to split the PRNG seeds among (0 for no sharding)")
 	flag.BoolVar(&flags.log, "rapid.log", false, "rapid: eager verbose output to stdout (to aid with unrecoverable test failures)")
 	flag.BoolVar(&flags.verbose, "rapid.v", false, "rapid: verbose output")
 	flag.BoolVar(&flags.debug, "rapid.debug", false, "rapid: debugging output")
 	flag.BoolVar(&flags.debugvis, "rapid.debugvis", false, "rapid: debugging visualization")
-	flag.DurationVar(&flags.shrinkTime, "rapid.shrinktime", 30*time.Second, "rapid: maximum time to spend on test case minimization")
+	flag.DurationVar(&flags.shrinkTime, "rapid.shrinktime", 30*time.Second, "rapid: maximum time to spend on test case minimization (0 for no limit; makes minimization depend on machine speed)")
+	flag.IntVar(&flags.shrinkAttempts, "rapid.shrinkattempts", 100000, "rapid: maximum number of test case minimization attempts (0 for no limit)")
+	flag.BoolVar(&flags.shrinkProgress, "rapid.shrinkprogress", false, "rapid: report every accepted test case minimization step")
+	flag.BoolVar(&flags.debugshrink, "rapid.debugshrink", false, "rapid: write every test case minimization attempt to a file")
+	flag.StringVar(&flags.exportDir, "rapid.exportdir", "", "rapid: directory to export failing test cases to as JSON files")
+	flag.StringVar(&flags.dotDir, "rapid.dotdir", "", "rapid: directory to write DOT graphs of failing state machine test cases to")
+	flag.StringVar(&flags.traceDir, "rapid.tracedir", "", "rapid: directory to write editable traces of failing state machine test cases to")
+	flag.StringVar(&flags.drawTraceDir, "rapid.drawtracedir", "", "rapid: directory to write JSON traces of all the draws of failing test cases to")
+	flag.DurationVar(&flags.maxDuration, "rapid.maxduration", 0, "rapid: fail test cases which run longer (0 for no limit)")
+	flag.DurationVar(&flags.actionTimeout, "rapid.actiontimeout", 0, "rapid: fail state machine test cases with an action which blocks longer (0 for no limit)")
+	flag.IntVar(&flags.maxValueLen, "rapid.maxvaluelen", 4096, "rapid: maximum rendered length of a drawn value in the output (0 for no limit)")
+	flag.IntVar(&flags.maxReportLen, "rapid.maxreportlen", 65536, "rapid: maximum length of the output of a failed test case (0 for no limit)")
+	flag.StringVar(&flags.valueDir, "rapid.valuedir", "", "rapid: directory to write the full values of truncated draws to")
+	flag.StringVar(&flags.htmlReport, "rapid.htmlreport", "", "rapid: HTML file to write the report of all checks of the run to")
+	flag.BoolVar(&flags.annotations, "rapid.githubannotations", false, "rapid: write GitHub Actions error annotations of the failures to stdout")
+	flag.BoolVar(&flags.complexity, "rapid.complexity", false, "rapid: estimate the complexity of the property and compare it with the saved baseline")
+	flag.BoolVar(&flags.vacuity, "rapid.vacuity", false, "rapid: verify that the properties which pass can fail with corrupted draws")
+	flag.BoolVar(&flags.coverage, "rapid.coverage", false, "rapid: guide the search for a bug by code coverage (requires -cover)")
 }
 
 func assert(ok bool) {
@@ -89,9 +136,17 @@ func assertValidRange(min int, max int) {
 //
 // Property is falsified in case of a panic or a call to
 // (*T).Fatalf, (*T).Fatal, (*T).Errorf, (*T).Error, (*T).FailNow or (*T).Fail.
-func Check(t *testing.T, prop func(*T)) {
+// The minimized failing test case is then run once more with every draw logged,
+// so that the test output shows all the values which have led to the failure.
+//
+// With -rapid.coverage (or the GuideByCoverage option), when the test binary
+// is built with coverage instrumentation (go test -cover), test cases which cover
+// new code are kept and mutated to produce new ones, which helps to find bugs
+// hidden deep in the code under test. Saved failing test cases which no longer
+// fail are mutated the same way.
+func Check(t *testing.T, prop func(*T), opts ...Option) {
 	t.Helper()
-	checkTB(t, prop)
+	checkTB(t, prop, opts...)
 }
 
 // MakeCheck is a convenience function for defining subtests suitable for
@@ -109,53 +164,284 @@ func Check(t *testing.T, prop func(*T)) {
 //       })
 //   })
 //
-func MakeCheck(prop func(*T)) func(*testing.T) {
+func MakeCheck(prop func(*T), opts ...Option) func(*testing.T) {
 	return func(t *testing.T) {
 		t.Helper()
-		checkTB(t, prop)
+		checkTB(t, prop, opts...)
+	}
+}
+
+// MakeFuzz creates a fuzz target for native go test fuzzing from prop:
+//
+//   func FuzzFoo(f *testing.F) {
+//       f.Fuzz(rapid.MakeFuzz(func(t *rapid.T) {
+//           // test code
+//       }))
+//   }
+//
+// Fuzzer-provided input is used as the source of data for the generators,
+// so the same property can be checked both by rapid and by the fuzzer.
+// Inputs too short to generate all the values required are padded with zeroes;
+// inputs which generators reject (e.g. using Filter) are skipped.
+func MakeFuzz(prop func(*T)) func(*testing.T, []byte) {
+	return func(t *testing.T, input []byte) {
+		t.Helper()
+		checkFuzz(t, prop, input)
+	}
+}
+
+type fuzzTB interface {
+	tb
+	SkipNow()
+}
+
+func checkFuzz(tb fuzzTB, prop func(*T), input []byte) {
+	tb.Helper()
+
+	s := newBufBitStream(bytesToWords(input), false)
+	s.zeroPad = true
+	t := newT(tb, s, true, nil)
+	err := checkOnce(t, prop)
+
+	switch {
+	case err == nil:
+	case err.isInvalidData():
+		tb.SkipNow()
+	case err.isStopTest():
+		tb.Fatalf("[rapid] failed%v: %v", err.where(), err)
+	default:
+		tb.Fatalf("[rapid] panic%v: %v\nTraceback:\n%v", err.where(), err, traceback(err))
 	}
 }
 
-func checkTB(tb tb, prop func(*T)) {
+func checkTB(tb tb, prop func(*T), opts ...Option) {
 	tb.Helper()
 
+	cfg := newSettings(opts)
+	userProp := prop
+	if cfg.maxDuration > 0 {
+		prop = timeLimited(prop, cfg.maxDuration)
+	}
+	if cfg.actionTimeout > 0 {
+		prop = withActionTimeout(prop, cfg.actionTimeout)
+	}
+	if cfg.maxValueLen > 0 || cfg.maxReportLen > 0 {
+		prop = withOutputLimits(prop, cfg.maxValueLen, cfg.maxReportLen, cfg.valueDir)
+	}
+	if env := os.Getenv(pinEnv); env != "" {
+		pins, err := parsePins(env)
+		if err != nil {
+			tb.Fatalf("[rapid] invalid %v: %v", pinEnv, err)
+			return
+		}
+		for label, v := range cfg.pins {
+			pins[label] = v
+		}
+		cfg.pins = pins
+	}
+	if len(cfg.pins) > 0 {
+		tb.Logf("[rapid] pinned draws: %v", formatPins(cfg.pins))
+		prop = withPins(prop, cfg.pins)
+	}
+
+	var cov float64
+	if cfg.coverage != nil {
+		cov = cfg.coverage()
+	}
+
+	runSeed := testSeed(tb.Name())
+	defer func() {
+		// panics outside of the property (e.g. in the engine) are reported with the test case being generated
+		if r := recover(); r != nil {
+			err := panicToError(r, 3)
+			if cfg.caseIndex == 0 {
+				tb.Fatalf("[rapid] panic before the first test: %v\nTo reproduce, specify -run=%q -rapid.seed=%d\nTraceback:\n%v", err, regexp.QuoteMeta(tb.Name()), runSeed, traceback(err))
+			} else {
+				tb.Fatalf("[rapid] panic after generating test #%v (seed %d): %v\nTo reproduce, specify -run=%q -rapid.seed=%d\nTraceback:\n%v", cfg.caseIndex, cfg.caseSeed, err, regexp.QuoteMeta(tb.Name()), runSeed, traceback(err))
+			}
+		}
+	}()
+
 	start := time.Now()
-	valid, invalid, seed, buf, err1, err2 := doCheck(tb, flags.failfile, flags.checks, baseSeed(), prop)
+	valid, invalid, seed, buf, err1, err2 := doCheck(tb, cfg, runSeed, prop)
 	dt := time.Since(start)
+	var report checkReport
+	if cfg.htmlReport != "" {
+		report = newCheckReport(tb.Name(), dt, valid, invalid, cfg.stats)
+		report.Metadata = cfg.metadata
+	}
 
 	if err1 == nil && err2 == nil {
-		if valid == flags.checks {
+		if valid == cfg.checks {
 			tb.Logf("[rapid] OK, passed %v tests (%v)", valid, dt)
+		} else if cfg.stats.exhausted() {
+			tb.Logf("[rapid] OK, passed %v tests (%v), covering all %v distinct test cases", valid, dt, len(cfg.stats.distinct))
 		} else {
 			tb.Errorf("[rapid] only generated %v valid tests from %v total (%v)", valid, valid+invalid, dt)
 		}
+		reportCollected(tb, cfg.stats)
+		reportObserved(tb, cfg.stats)
+		reportEvents(tb, cfg.stats, cfg.requiredEvents)
+		reportActions(tb, cfg.stats, cfg.requiredActions)
+		reportDiscarded(tb, cfg.stats, cfg.maxDiscardRatio)
+		reportDegenerate(tb, cfg.stats, cfg.degenerateRatio, cfg.failDegenerate)
+		if cfg.coverage != nil {
+			reportCoverage(tb, cov, cfg.coverage())
+		}
+		if cfg.vacuity || len(cfg.mutations) > 0 {
+			checkVacuity(tb, cfg, runSeed, prop)
+		}
+	} else if err := firstActionTimeout(err1, err2); err != nil {
+		// the action is still running, so the test case is neither minimized nor replayed
+		repr := ""
+		if seed != 0 {
+			repr = fmt.Sprintf("\nTo reproduce, specify -run=%q -rapid.seed=%d", regexp.QuoteMeta(tb.Name()), seed)
+		}
+		tb.Errorf("[rapid] failed after %v tests: %v%v", valid, err, repr)
 	} else {
-		repr := fmt.Sprintf("-rapid.seed=%d", seed)
-		if flags.failfile != "" && seed == 0 {
-			repr = fmt.Sprintf("-rapid.failfile=%q", flags.failfile)
+		out, genHash := captureTestOutput(tb, prop, buf)
+		ff := failFile{version: rapidVersion, genHash: genHash, seed: seed, buf: buf}
+		if cfg.db != nil {
+			err := saveToDatabase(tb, cfg.db, prop, ff)
+			if err != nil {
+				tb.Logf("[rapid] failed to save the failing test case to the example database: %v", err)
+			}
+		}
+
+		replay := fmt.Sprintf("%v=%v", replayEnv, encodeReplayToken(tb.Name(), seed, buf))
+		repr := fmt.Sprintf("-rapid.seed=%d (or set %v)", seed, replay)
+		alt := fmt.Sprintf("-rapid.seed=%d, or set %v", seed, replay)
+		if seed == 0 {
+			repr, alt = replay, "set "+replay // not generated from a seed
+		}
+		if os.Getenv(replayEnv) != "" && seed == 0 {
+			repr = replay
+		} else if cfg.failfile != "" && seed == 0 {
+			repr = fmt.Sprintf("-rapid.failfile=%q", cfg.failfile)
 		} else if !flags.nofailfile {
 			failfile := failFileName(tb.Name())
-			out := captureTestOutput(tb, prop, buf)
-			err := saveFailFile(failfile, rapidVersion, out, seed, buf)
+			err := saveFailFile(failfile, out, ff)
 			if err == nil {
-				repr = fmt.Sprintf("-rapid.failfile=%q (or -rapid.seed=%d)", failfile, seed)
+				repr = fmt.Sprintf("-rapid.failfile=%q (or %v)", failfile, alt)
 			} else {
 				tb.Logf("[rapid] %v", err)
 			}
 		}
 
 		name := regexp.QuoteMeta(tb.Name())
+		if cfg.onFailure != nil {
+			c := newCounterexample(tb, prop, err2, fmt.Sprintf("-run=%q %v", name, repr), seed, buf)
+			c.Metadata = cfg.metadata
+			cfg.onFailure(c)
+		}
+		if cfg.htmlReport != "" {
+			draws, _ := topLevelDraws(tb, prop, buf)
+			report.failed(err2, fmt.Sprintf("-run=%q %v", name, repr), draws, cfg.maxValueLen)
+		}
+		if !cfg.metadata.empty() {
+			tb.Logf("[rapid] property: %v", cfg.metadata)
+		}
 		if traceback(err1) == traceback(err2) {
 			if err2.isStopTest() {
-				tb.Errorf("[rapid] failed after %v tests: %v\nTo reproduce, specify -run=%q %v\nFailed test output:", valid, err2, name, repr)
+				tb.Errorf("[rapid] failed after %v tests%v: %v\nTo reproduce, specify -run=%q %v\nFailed test output:", valid, err2.where(), err2, name, repr)
 			} else {
-				tb.Errorf("[rapid] panic after %v tests: %v\nTo reproduce, specify -run=%q %v\nTraceback:\n%vFailed test output:", valid, err2, name, repr, traceback(err2))
+				tb.Errorf("[rapid] panic after %v tests%v: %v\nTo reproduce, specify -run=%q %v\nTraceback:\n%vFailed test output:", valid, err2.where(), err2, name, repr, traceback(err2))
 			}
 		} else {
 			tb.Errorf("[rapid] flaky test, can not reproduce a failure\nTo try to reproduce, specify -run=%q %v\nTraceback (%v):\n%vOriginal traceback (%v):\n%vFailed test output:", name, repr, err2, traceback(err2), err1, traceback(err1))
 		}
 
-		_ = checkOnce(newT(tb, newBufBitStream(buf, false), true, nil), prop) // output using (*testing.T).Log for proper line numbers
+		if cfg.annotations {
+			draws, _ := topLevelDraws(tb, prop, buf)
+			fmt.Print(githubAnnotation(tb.Name(), cfg.metadata, valid, err1, err2, fmt.Sprintf("-run=%q %v", name, repr), draws, cfg.maxValueLen))
+		}
+
+		nt := newT(tb, newBufBitStream(buf, false), true, nil)
+		nt.trace = &machineTrace{}
+		if cfg.drawTraceDir != "" {
+			nt.drawLog = &drawTrace{}
+		}
+		err := checkOnce(nt, prop) // output using (*testing.T).Log for proper line numbers
+		if n := nt.limits.omittedBytes(); n > 0 {
+			tb.Logf("[rapid] … %d more bytes of failed test output omitted", n)
+		}
+		if cfg.drawTraceDir != "" {
+			filename := filepath.Join(cfg.drawTraceDir, persistFileName(tb.Name(), "draws.json"))
+			err := saveDrawTrace(filename, nt.drawLog, tb.Name(), err)
+			if err == nil {
+				tb.Logf("[rapid] trace of the draws written to %q", filename)
+			} else {
+				tb.Logf("[rapid] %v", err)
+			}
+		}
+
+		machine := len(nt.trace.steps) > 0 || nt.trace.pending != nil
+		if machine {
+			tb.Logf("[rapid] failing sequence of actions:\n%v", nt.trace.script(nt, err))
+			if cfg.dotDir != "" {
+				filename := filepath.Join(cfg.dotDir, persistFileName(tb.Name(), "dot"))
+				err := saveMachineGraph(filename, nt.trace, tb.Name(), err)
+				if err == nil {
+					tb.Logf("[rapid] state machine graph written to %q", filename)
+				} else {
+					tb.Logf("[rapid] %v", err)
+				}
+			}
+			if cfg.traceDir != "" {
+				filename := filepath.Join(cfg.traceDir, persistFileName(tb.Name(), "trace"))
+				err := saveMachineTrace(filename, nt, tb.Name())
+				if err == nil {
+					tb.Logf("[rapid] state machine trace written to %q, replay it using rapid.ReplayTrace", filename)
+				} else {
+					tb.Logf("[rapid] %v", err)
+				}
+			}
+		}
+
+		if cfg.exportDir != "" {
+			filename := filepath.Join(cfg.exportDir, counterexampleFileName(tb.Name()))
+			ce := makeCounterexample(tb, prop, seed, buf)
+			if !cfg.metadata.empty() {
+				ce.Metadata = &cfg.metadata
+			}
+			err := saveCounterexample(filename, ce)
+			if err == nil {
+				tb.Logf("[rapid] counterexample exported to %q", filename)
+			} else {
+				tb.Logf("[rapid] %v", err)
+			}
+		}
+
+		if cfg.fuzzName != "" {
+			filename, err := saveFuzzCorpusEntry(fuzzCorpusDir(cfg.fuzzName), wordsToBytes(buf))
+			if err == nil {
+				tb.Logf("[rapid] failing test case added to the fuzz corpus as %q", filename)
+			} else {
+				tb.Logf("[rapid] %v", err)
+			}
+		}
+
+		if traceback(err1) == traceback(err2) && cfg.original != nil {
+			if diff := shrinkDiff(tb, prop, cfg.original, buf); diff != "" {
+				tb.Logf("[rapid] minimization has changed the drawn values (-original +minimized):\n%v", diff)
+			}
+		}
+
+		if traceback(err1) == traceback(err2) && !machine {
+			if code := reproCode(tb, prop, userProp, buf); code != "" {
+				tb.Logf("[rapid] to keep the failing test case as a regression test, add:\n%v", code)
+			}
+		}
+	}
+
+	if cfg.complexity {
+		reportComplexity(tb, cfg.stats)
+	}
+
+	if cfg.htmlReport != "" {
+		if err := addToHTMLReport(cfg.htmlReport, report); err != nil {
+			tb.Logf("[rapid] %v", err)
+		}
 	}
 
 	if tb.Failed() {
@@ -163,47 +449,216 @@ func checkTB(tb tb, prop func(*T)) {
 	}
 }
 
-func doCheck(tb tb, failfile string, checks int, seed uint64, prop func(*T)) (int, int, uint64, []uint64, *testError, *testError) {
+func doCheck(tb tb, cfg *settings, seed uint64, prop func(*T)) (int, int, uint64, []uint64, *testError, *testError) {
 	tb.Helper()
 
 	assertf(!tb.Failed(), "check function called with *testing.T which has already failed")
 
-	if failfile != "" {
-		buf, err1, err2 := checkFailFile(tb, failfile, prop)
+	c := &corpus{}
+	if token := os.Getenv(replayEnv); token != "" {
+		buf, err1, err2 := checkReplayToken(tb, token, prop)
+		if err1 != nil || err2 != nil {
+			return 0, 0, 0, buf, err1, err2
+		}
+	}
+
+	if cfg.failfile != "" {
+		buf, err1, err2 := checkFailFile(tb, cfg.failfile, c, prop)
 		if err1 != nil || err2 != nil {
 			return 0, 0, 0, buf, err1, err2
 		}
+	} else if !flags.nofailfile {
+		for _, failfile := range savedFailFiles(tb.Name()) {
+			buf, err1, err2 := checkFailFile(tb, failfile, c, prop)
+			if err1 != nil || err2 != nil {
+				cfg.failfile = failfile // report the failure using the saved fail file
+				return 0, 0, 0, buf, err1, err2
+			}
+		}
+	}
+
+	if cfg.db != nil {
+		seed, buf, err1, err2 := checkDatabase(tb, cfg.db, c, prop)
+		if err1 != nil || err2 != nil {
+			return 0, 0, seed, buf, err1, err2
+		}
+	}
+
+	if cfg.resume != nil && cfg.resumeHash != replayTokenTestHash(tb.Name()) {
+		tb.Logf("[rapid] ignoring snapshot of another test")
+		cfg.resume = nil
 	}
 
-	seed, valid, invalid, err1 := findBug(tb, checks, seed, prop)
+	var (
+		buf            []uint64
+		valid, invalid int
+		err1           *testError
+	)
+	if cfg.exhaustiveSteps > 0 {
+		valid, buf, err1 = findBugExhaustive(tb, cfg, seed, prop)
+	}
+	if err1 == nil && cfg.exhaustive > 0 && cfg.resume == nil {
+		var complete bool
+		valid, invalid, buf, err1, complete = findBugEnumerated(tb, cfg, seed, prop)
+		if complete && err1 == nil {
+			return valid, invalid, 0, nil, nil, nil
+		}
+	}
 	if err1 == nil {
-		return valid, invalid, 0, nil, nil, nil
+		var n, m int
+		seed, buf, n, m, err1 = findBug(tb, cfg, c, seed, prop)
+		valid += n
+		invalid += m
+		if err1 == nil {
+			return valid, invalid, 0, nil, nil, nil
+		}
+	} else {
+		seed = 0 // not generated from a seed
+	}
+	if err1.isActionTimeout() {
+		return valid, invalid, seed, nil, err1, err1
 	}
 
-	s := newRandomBitStream(seed, true)
+	var (
+		s   bitStream
+		rec *recordedBits
+	)
+	if buf == nil {
+		r := newRandomBitStream(seed, true)
+		r.resume = cfg.resume
+		s, rec = r, &r.recordedBits
+	} else {
+		b := newBufBitStream(buf, true)
+		s, rec = b, &b.recordedBits
+	}
 	t := newT(tb, s, flags.verbose, nil)
 	t.Logf("[rapid] trying to reproduce the failure")
 	err2 := checkOnce(t, prop)
+	if err2 != nil && err2.isActionTimeout() {
+		return valid, invalid, seed, nil, err1, err2
+	}
 	if !sameError(err1, err2) {
-		return valid, invalid, seed, s.data, err1, err2
+		return valid, invalid, seed, rec.data, err1, err2
 	}
 
 	t.Logf("[rapid] trying to minimize the failing test case")
-	buf, err3 := shrink(tb, s.recordedBits, err2, prop)
+	cfg.original = append([]uint64(nil), rec.data...)
+	buf, err3 := shrink(tb, cfg, *rec, err2, prop)
 
 	return valid, invalid, seed, buf, err2, err3
 }
 
-func checkFailFile(tb tb, failfile string, prop func(*T)) ([]uint64, *testError, *testError) {
+func checkFailFile(tb tb, failfile string, c *corpus, prop func(*T)) ([]uint64, *testError, *testError) {
 	tb.Helper()
 
-	version, _, buf, err := loadFailFile(failfile)
+	ff, err := loadFailFile(failfile)
 	if err != nil {
 		tb.Logf("[rapid] ignoring fail file: %v", err)
 		return nil, nil, nil
 	}
-	if version != rapidVersion {
-		tb.Logf("[rapid] ignoring fail file: version %q differs from rapid version %q", version, rapidVersion)
+
+	return checkSavedCase(tb, fmt.Sprintf("fail file %q", failfile), ff, c, prop)
+}
+
+// checkDatabase replays the test cases saved in the example database for the test.
+func checkDatabase(tb tb, db ExampleDatabase, c *corpus, prop func(*T)) (uint64, []uint64, *testError, *testError) {
+	tb.Helper()
+
+	values, err := db.Get(tb.Name())
+	if err != nil {
+		tb.Logf("[rapid] ignoring example database: %v", err)
+		return 0, nil, nil, nil
+	}
+
+	var (
+		entries []failFile
+		names   []string
+		seen    = map[uint64]bool{}
+	)
+	for i, v := range values {
+		what := fmt.Sprintf("example database entry #%v", i)
+		ff, err := readFailFile(bytes.NewReader(v))
+		if err != nil {
+			tb.Logf("[rapid] ignoring %v: %v", what, err)
+			continue
+		}
+		h := valuesHash(tb, prop, ff.buf)
+		if seen[h] {
+			tb.Logf("[rapid] removing %v: it duplicates the values of another entry", what)
+			if err := db.Delete(tb.Name(), v); err != nil {
+				tb.Logf("[rapid] failed to remove %v: %v", what, err)
+			}
+			continue
+		}
+		seen[h] = true
+		entries = append(entries, ff)
+		names = append(names, what)
+	}
+
+	for i, ff := range entries {
+		buf, err1, err2 := checkSavedCase(tb, names[i], ff, c, prop)
+		if err1 != nil || err2 != nil {
+			return ff.seed, buf, err1, err2
+		}
+	}
+
+	return 0, nil, nil, nil
+}
+
+// checkSavedCase replays the test case loaded from the fail file or the example
+// database (what describes which one). Test cases which no longer fail are added
+// to the corpus (if any) to be mutated while searching for new failures.
+func checkSavedCase(tb tb, what string, ff failFile, c *corpus, prop func(*T)) ([]uint64, *testError, *testError) {
+	tb.Helper()
+
+	if ff.version != rapidVersion {
+		tb.Logf("[rapid] ignoring %v: version %q differs from rapid version %q", what, ff.version, rapidVersion)
+		return nil, nil, nil
+	}
+	buf := ff.buf
+
+	s1 := newBufBitStream(buf, c != nil)
+	t1 := newT(tb, s1, flags.verbose, nil)
+	t1.genHash = fnv.New64a()
+	err1 := checkOnce(t1, prop)
+	if ff.genHash == 0 {
+		tb.Logf("[rapid] ignoring %v: it was recorded without the hash of the generators", what)
+		return nil, nil, nil
+	}
+	if t1.genHash.Sum64() != ff.genHash {
+		tb.Logf("[rapid] ignoring %v: it was recorded with different generators", what)
+		return nil, nil, nil
+	}
+	if err1 == nil {
+		if c != nil {
+			c.add(&s1.recordedBits)
+		}
+		return nil, nil, nil
+	}
+	if err1.isInvalidData() {
+		tb.Logf("[rapid] %v is no longer valid", what)
+		return nil, nil, nil
+	}
+
+	s2 := newBufBitStream(buf, false)
+	t2 := newT(tb, s2, flags.verbose, nil)
+	t2.Logf("[rapid] trying to reproduce the failure")
+	err2 := checkOnce(t2, prop)
+
+	return buf, err1, err2
+}
+
+// checkReplayToken replays the test case encoded in the token, if the token
+// was printed for this test.
+func checkReplayToken(tb tb, token string, prop func(*T)) ([]uint64, *testError, *testError) {
+	tb.Helper()
+
+	testHash, _, buf, err := decodeReplayToken(token)
+	if err != nil {
+		tb.Logf("[rapid] ignoring %v: %v", replayEnv, err)
+		return nil, nil, nil
+	}
+	if testHash != replayTokenTestHash(tb.Name()) {
 		return nil, nil, nil
 	}
 
@@ -214,7 +669,7 @@ func checkFailFile(tb tb, failfile string, prop func(*T)) ([]uint64, *testError,
 		return nil, nil, nil
 	}
 	if err1.isInvalidData() {
-		tb.Logf("[rapid] fail file %q is no longer valid", failfile)
+		tb.Logf("[rapid] %v is no longer valid", replayEnv)
 		return nil, nil, nil
 	}
 
@@ -226,22 +681,73 @@ func checkFailFile(tb tb, failfile string, prop func(*T)) ([]uint64, *testError,
 	return buf, err1, err2
 }
 
-func findBug(tb tb, checks int, seed uint64, prop func(*T)) (uint64, int, int, *testError) {
+// findBug runs the checks until one of them fails. Normally, every test case
+// is generated from its own seed; when the corpus is not empty, half of the test
+// cases are mutations of the corpus entries instead. Test cases which cover new
+// code (when cfg.coverage is set) or have the best score so far (when the property
+// uses (*T).Target) are added to the corpus. Random test cases are only recorded
+// when the corpus needs their data (once coverage is collected or a score is
+// reported), so that passing test cases do not pay for recording the data and
+// groups. Failing random test cases are returned as seeds, and failing mutated
+// ones as data with zero seed; doCheck re-runs them with recording enabled.
+func findBug(tb tb, cfg *settings, c *corpus, seed uint64, prop func(*T)) (uint64, []uint64, int, int, *testError) {
 	tb.Helper()
 
 	var (
-		r       = newRandomBitStream(0, false)
+		guided  = cfg.coverage != nil
+		timed   = cfg.complexity && cfg.stats != nil
+		r       = newRandomBitStream(0, guided)
 		t       = newT(tb, r, flags.verbose, nil)
+		m       = newBufBitStream(nil, true)
+		mt      = newT(tb, m, flags.verbose, nil)
+		ctx     jsf64ctx
+		cov     float64
+		best    float64
+		scored  = false
 		valid   = 0
 		invalid = 0
 	)
+	m.zeroPad = true
+	r.resume = cfg.resume
+	if cfg.stats != nil {
+		t.stats = &caseStats{}
+		mt.stats = t.stats
+	}
+	ctx.init(seed)
+	if guided {
+		cov = cfg.coverage()
+	}
 
-	for valid < checks && invalid < checks*invalidChecksMult {
+	for valid < cfg.checks && invalid < cfg.checks*invalidChecksMult {
 		seed += uint64(valid) + uint64(invalid)
-		r.init(seed)
-		var start time.Time
+		t, rec, mutated := t, &r.recordedBits, len(c.entries) > 0 && ctx.rand()&1 == 0
+		cfg.caseIndex, cfg.caseSeed = valid+invalid+1, seed
+		if mutated {
+			cfg.caseSeed = 0
+			m.buf = c.mutate(&ctx)
+			// mutations keep the prefix being resumed from
+			if len(m.buf) < len(cfg.resume) {
+				m.buf = append(m.buf, cfg.resume[len(m.buf):]...)
+			}
+			copy(m.buf, cfg.resume)
+			m.reset()
+			t, rec = mt, &m.recordedBits
+		} else {
+			r.init(seed)
+		}
+		t.score, t.scored = 0, false
+		if t.stats != nil {
+			t.stats.reset()
+		}
 		if t.shouldLog() {
-			t.Logf("[rapid] test #%v start (seed %v)", valid+invalid+1, seed)
+			if mutated {
+				t.Logf("[rapid] test #%v start (mutated)", valid+invalid+1)
+			} else {
+				t.Logf("[rapid] test #%v start (seed %v)", valid+invalid+1, seed)
+			}
+		}
+		var start time.Time
+		if t.shouldLog() || timed {
 			start = time.Now()
 		}
 
@@ -251,27 +757,99 @@ func findBug(tb tb, checks int, seed uint64, prop func(*T)) (uint64, int, int, *
 				t.Logf("[rapid] test #%v OK (%v)", valid+invalid+1, time.Since(start))
 			}
 			valid++
+			if t.stats != nil {
+				cfg.stats.addCase(t.stats)
+				if cfg.stats.exhausted() {
+					break
+				}
+			}
+			if timed {
+				cfg.stats.addTiming(rec.size(), time.Since(start))
+			}
+			if guided {
+				if cv := cfg.coverage(); cv > cov {
+					cov = cv
+					c.add(rec)
+				}
+			}
+			if t.scored && rec.persist && (!scored || t.score > best) {
+				best, scored = t.score, true
+				c.add(rec)
+			}
+			if t.scored {
+				r.persist = true // record the test cases to be able to keep the best ones
+			}
 		} else if err.isInvalidData() {
 			if t.shouldLog() {
 				t.Logf("[rapid] test #%v invalid (%v)", valid+invalid+1, time.Since(start))
 			}
 			invalid++
+			if t.stats != nil {
+				cfg.stats.addDiscarded(t.stats)
+			}
+		} else if err.isActionTimeout() {
+			return cfg.caseSeed, nil, valid, invalid, err // the data is still in use
 		} else {
 			if t.shouldLog() {
 				t.Logf("[rapid] test #%v failed: %v", valid+invalid+1, err)
 			}
-			return seed, valid, invalid, err
+			if mutated {
+				return 0, append([]uint64(nil), rec.data...), valid, invalid, err
+			}
+			return seed, nil, valid, invalid, err
+		}
+	}
+
+	return 0, nil, valid, invalid, nil
+}
+
+// timeLimited returns the property which also fails when prop runs longer than d.
+// It reports the running time as the score, to search for the slowest test cases.
+func timeLimited(prop func(*T), d time.Duration) func(*T) {
+	return func(t *T) {
+		start := time.Now()
+		prop(t)
+		dt := time.Since(start)
+		t.Target(dt.Seconds())
+		if dt > d {
+			t.Fatalf("test case took longer than %v", d)
 		}
 	}
+}
+
+func withActionTimeout(prop func(*T), d time.Duration) func(*T) {
+	return func(t *T) {
+		t.actionTimeout = d
+		prop(t)
+	}
+}
 
-	return 0, valid, invalid, nil
+// firstActionTimeout returns the first of the errors which is an action timeout, if any.
+func firstActionTimeout(errs ...*testError) *testError {
+	for _, err := range errs {
+		if err != nil && err.isActionTimeout() {
+			return err
+		}
+	}
+	return nil
 }
 
 func checkOnce(t *T, prop func(*T)) (err *testError) {
 	if t.tbLog && t.tb != nil {
 		t.tb.Helper()
 	}
-	defer func() { err = panicToError(recover(), 3) }()
+	path, depth := t.path, t.depth
+	defer func() {
+		err = panicToError(recover(), 3)
+		if err != nil && err.isActionTimeout() {
+			return // the action is still running, and using t
+		}
+		if err != nil && t.path != path {
+			err.path = t.path
+		}
+		t.path, t.depth = path, depth // draws left unfinished by the panic
+	}()
+	defer t.runCleanups()
 
 	prop(t)
 	t.failOnError()
@@ -279,11 +857,15 @@ func checkOnce(t *T, prop func(*T)) (err *testError) {
 	return nil
 }
 
-func captureTestOutput(tb tb, prop func(*T), buf []uint64) []byte {
+// captureTestOutput returns the output of the test case, together with the hash
+// of the draws it makes.
+func captureTestOutput(tb tb, prop func(*T), buf []uint64) ([]byte, uint64) {
 	var b bytes.Buffer
 	l := log.New(&b, fmt.Sprintf("%s ", tb.Name()), log.Ldate|log.Ltime) // TODO: enable log.Lmsgprefix once all supported versions of Go have it
-	_ = checkOnce(newT(tb, newBufBitStream(buf, false), false, l), prop)
-	return b.Bytes()
+	t := newT(tb, newBufBitStream(buf, false), false, l)
+	t.genHash = fnv.New64a()
+	_ = checkOnce(t, prop)
+	return b.Bytes(), t.genHash.Sum64()
 }
 
 type invalidData string
@@ -292,6 +874,9 @@ type stopTest string
 type testError struct {
 	data      interface{}
 	traceback string
+	file      string // location of the failure in the property, if known
+	line      int
+	path      string // label path of the draw in progress, if any
 }
 
 func panicToError(p interface{}, skip int) *testError {
@@ -304,6 +889,7 @@ func panicToError(p interface{}, skip int) *testError {
 	frames := runtime.CallersFrames(callers)
 
 	b := &strings.Builder{}
+	te := &testError{data: p}
 	f, more, skipSpecial := runtime.Frame{}, true, true
 	for more && !strings.HasSuffix(f.Function, tracebackStop) {
 		f, more = frames.Next()
@@ -313,14 +899,16 @@ func panicToError(p interface{}, skip int) *testError {
 		}
 		skipSpecial = false
 
+		if te.file == "" && f.File != "" && (!strings.HasPrefix(f.Function, rapidPrefix) || strings.HasSuffix(f.File, "_test.go")) {
+			te.file, te.line = f.File, f.Line
+		}
+
 		_, err := fmt.Fprintf(b, "    %s:%d in %s\n", f.File, f.Line, f.Function)
 		assert(err == nil)
 	}
 
-	return &testError{
-		data:      p,
-		traceback: b.String(),
-	}
+	te.traceback = b.String()
+	return te
 }
 
 func (err *testError) Error() string {
@@ -345,6 +933,19 @@ func (err *testError) isStopTest() bool {
 	return ok
 }
 
+func (err *testError) isActionTimeout() bool {
+	_, ok := err.data.(actionTimeout)
+	return ok
+}
+
+// where describes the draw which has been in progress when the test case failed, if any.
+func (err *testError) where() string {
+	if err.path == "" {
+		return ""
+	}
+	return fmt.Sprintf(" in draw %v", err.path)
+}
+
 func sameError(err1 *testError, err2 *testError) bool {
 	return errorString(err1) == errorString(err2) && traceback(err1) == traceback(err2)
 }
@@ -401,14 +1002,34 @@ type tb interface {
 // If concurrency is unavoidable, methods on *T, such as Helper and Errorf, are safe for concurrent calls,
 // but Draw from a given *T is not.
 type T struct {
-	tb       // unnamed to force re-export of (*T).Helper()
-	tbLog    bool
-	rawLog   *log.Logger
-	s        bitStream
-	draws    int
-	refDraws []value
-	mu       sync.RWMutex
-	failed   stopTest
+	tb            // unnamed to force re-export of (*T).Helper()
+	tbLog         bool
+	rawLog        *log.Logger
+	s             bitStream
+	draws         int
+	refDraws      []value
+	genHash       hash.Hash64  // if not nil, hashes the generators and labels of all draws
+	replay        []value      // if not nil, values to return from the top-level draws
+	topDraws      []drawnValue // if not nil, collects the top-level draws
+	depth         int          // nesting level of the current draw
+	path          string       // path of the value being drawn, like "users[2].address", if labels are used
+	drawLog       *drawTrace   // if not nil, records all draws, nested ones included
+	mu            sync.RWMutex
+	failed        stopTest
+	score         float64 // highest score reported by Target
+	scored        bool
+	stats         *caseStats // if not nil, collects the statistics reported by the test case
+	cleanups      []func()
+	trace         *machineTrace          // if not nil, records the steps of the state machine
+	temporal      []temporalProp         // registered by Always and Eventually
+	plan          *actionPlan            // if not nil, the sequence of actions for the state machine to execute
+	enum          *valuePlan             // if not nil, the combination of the drawn values to check
+	corrupt       *corruption            // if not nil, the top-level draw to corrupt
+	actionTimeout time.Duration          // if not zero, limits the time a state machine action can block
+	limits        *outputLimits          // if not nil, bounds the output of the test case
+	pins          map[string]interface{} // if not nil, values to draw instead of the generated ones, by label
+	sensitive     bool                   // whether the value being drawn is built from a sensitive one
+	floats        *[]floatDraw           // if not nil, collects the float values drawn, nested ones included
 }
 
 func newT(tb tb, s bitStream, tbLog bool, rawLog *log.Logger, refDraws ...value) *T {
@@ -432,25 +1053,77 @@ func newT(tb tb, s bitStream, tbLog bool, rawLog *log.Logger, refDraws ...value)
 	return t
 }
 
+// nested returns the T for the draws a Custom generator makes while drawing a value with t.
+func (t *T) nested() *T {
+	nt := newT(t.tb, t.s, t.tbLog || flags.debug, t.rawLog)
+	nt.path, nt.limits, nt.drawLog, nt.pins, nt.enum, nt.floats = t.path, t.limits, t.drawLog, t.pins, t.enum, t.floats
+	return nt
+}
+
 func (t *T) draw(g *Generator, label string) value {
-	v := g.value(t)
+	if t.genHash != nil {
+		_, _ = fmt.Fprintf(t.genHash, "%v\x00%v\x00", g, label)
+	}
+	if label == "" && (t.s.recording() || t.tbLog || t.rawLog != nil || t.topDraws != nil || t.pins != nil) {
+		label = callerLabel(2) // called from (*Generator).Draw
+	}
+
+	groupLabel := drawLabelPrefix
+	if t.s.recording() || label != "" {
+		groupLabel = drawLabelPrefix + label
+	}
+	path := t.path
+	if label != "" {
+		label = subPath(path, label)
+	}
+	n, bits := 0, t.s.bitsDrawn()
+	if t.drawLog != nil {
+		n = t.drawLog.begin(label, g)
+	}
+	var v value
+	sensitive := t.sensitive
+	t.sensitive = false
+	if t.replay != nil {
+		v = t.replayValue(g, label)
+	} else {
+		i := t.s.beginGroup(groupLabel, false)
+		t.depth++
+		t.path = label
+		v = g.value(t)
+		t.path = path
+		t.depth--
+		t.s.endGroup(i, false)
+		if t.pins != nil {
+			v = t.pinnedValue(g, label, v)
+		}
+		if t.corrupt != nil {
+			v = t.corrupt.apply(t.draws, v)
+		}
+	}
+	d := drawnValue{label, v, t.sensitive || isSensitive(g)}
+	t.sensitive = sensitive || d.sensitive
+	if t.drawLog != nil {
+		t.drawLog.end(n, d, t.s.bitsDrawn()-bits)
+	}
+	if t.topDraws != nil && t.depth == 0 {
+		t.topDraws = append(t.topDraws, d)
+	}
+	if t.stats != nil {
+		t.recordDraw(g, d)
+	}
 
 	if len(t.refDraws) > 0 {
 		ref := t.refDraws[t.draws]
 		if !reflect.DeepEqual(v, ref) {
-			t.tb.Fatalf("draw %v differs: %#v vs expected %#v", t.draws, v, ref)
+			t.tb.Fatalf("draw %v differs: %v vs expected %v", t.draws, d.String(), drawnValue{label, ref, d.sensitive}.String())
 		}
 	}
 
 	if t.tbLog || t.rawLog != nil {
-		if label == "" {
-			label = fmt.Sprintf("#%v", t.draws)
-		}
-
 		if t.tbLog && t.tb != nil {
 			t.tb.Helper()
 		}
-		t.Logf("[rapid] draw %v: %#v", label, v)
+		t.Logf("[rapid] draw %v: %v", label, t.logDrawn(d))
 	}
 
 	t.draws++
@@ -463,6 +1136,9 @@ func (t *T) shouldLog() bool {
 }
 
 func (t *T) Logf(format string, args ...interface{}) {
+	if t.limits != nil && t.shouldLog() && !t.limits.allow(fmt.Sprintf(format, args...)) {
+		return
+	}
 	if t.rawLog != nil {
 		t.rawLog.Printf(format, args...)
 	} else if t.tbLog && t.tb != nil {
@@ -472,6 +1148,9 @@ func (t *T) Logf(format string, args ...interface{}) {
 }
 
 func (t *T) Log(args ...interface{}) {
+	if t.limits != nil && t.shouldLog() && !t.limits.allow(fmt.Sprint(args...)) {
+		return
+	}
 	if t.rawLog != nil {
 		t.rawLog.Print(args...)
 	} else if t.tbLog && t.tb != nil {
@@ -560,6 +1239,69 @@ func (t *T) Failed() bool {
 	return t.failed != ""
 }
 
+// Target reports the score of the current test case (e.g. the length of a queue,
+// or the number of errors). When the property reports scores, rapid searches for
+// test cases which maximize the score, by mutating the best test cases found
+// so far instead of only generating new random ones. When called several times,
+// Target uses the highest score.
+func (t *T) Target(score float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.scored || score > t.score {
+		t.score, t.scored = score, true
+	}
+}
+
+// Cleanup registers a function to be called when the test case finishes,
+// even if it has failed or panicked (e.g. to release the resources acquired
+// by the test case). Functions are called in the reverse order of registration.
+func (t *T) Cleanup(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cleanups = append(t.cleanups, f)
+}
+
+// Snapshot returns a token describing the test case so far: the data drawn
+// before the call. Passed to ResumeFrom, the token makes every test case start
+// by drawing the same data, so the property (e.g. a state machine) reaches
+// the same state again, and then continues with new random data. This way, a long
+// sequence of actions leading to an interesting state can be grown once,
+// and then explored with lots of different continuations.
+func (t *T) Snapshot() string {
+	var buf []uint64
+	switch s := t.s.(type) {
+	case *randomBitStream:
+		buf = s.drawn()
+	case *bufBitStream:
+		buf = s.drawn()
+	default:
+		assertf(false, "can not snapshot %T", t.s)
+	}
+
+	name := ""
+	if t.tb != nil {
+		name = t.tb.Name()
+	}
+	return encodeReplayToken(name, 0, buf)
+}
+
+func (t *T) runCleanups() {
+	t.mu.Lock()
+	n := len(t.cleanups)
+	if n == 0 {
+		t.mu.Unlock()
+		return
+	}
+	f := t.cleanups[n-1]
+	t.cleanups = t.cleanups[:n-1]
+	t.mu.Unlock()
+
+	defer t.runCleanups() // even if f panics
+	f()
+}
+
 func (t *T) skip(msg string) {
 	panic(invalidData(msg))
 }
@@ -7,8 +7,13 @@
 package rapid
 
 import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func brokenGen(*T) int { panic("this generator is not working") }
@@ -71,6 +76,61 @@ func TestPanicTraceback(t *testing.T) {
 	}
 }
 
+func TestFindBugDoesNotRecord(t *testing.T) {
+	t.Parallel()
+
+	recorded := 0
+	_, _, valid, _, err := findBug(t, &settings{checks: 10}, &corpus{}, baseSeed(), func(t *T) {
+		SliceOf(Int()).Draw(t, "s")
+		if t.s.recording() {
+			recorded++
+		}
+	})
+	if err != nil || valid != 10 {
+		t.Fatalf("got %v valid checks (error %v) instead of 10", valid, err)
+	}
+	if recorded != 0 {
+		t.Fatalf("%v passing test cases were recorded", recorded)
+	}
+}
+
+func TestCheckFailFileGenerators(t *testing.T) {
+	t.Parallel()
+
+	intProp := func(t *T) {
+		Int().Draw(t, "x")
+		t.Fatal("always fails")
+	}
+	uintProp := func(t *T) {
+		Uint().Draw(t, "x")
+		t.Fatal("always fails")
+	}
+
+	buf := []uint64{0, 0, 0}
+	_, genHash := captureTestOutput(t, intProp, buf)
+	fileName := tempFailFileName(t, t.Name())
+	err := saveFailFile(fileName, nil, failFile{version: rapidVersion, genHash: genHash, buf: buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err1, _ := checkFailFile(t, fileName, nil, intProp); err1 == nil {
+		t.Fatalf("fail file did not reproduce the failure")
+	}
+	if _, err1, _ := checkFailFile(t, fileName, nil, uintProp); err1 != nil {
+		t.Fatalf("fail file recorded with different generators was not ignored: %v", err1)
+	}
+
+	legacyName := tempFailFileName(t, t.Name())
+	err = ioutil.WriteFile(legacyName, []byte(fmt.Sprintf("%v#0\n0x0\n0x0\n0x0", rapidVersion)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err1, _ := checkFailFile(t, legacyName, nil, intProp); err1 != nil {
+		t.Fatalf("fail file recorded without generator hash was not ignored: %v", err1)
+	}
+}
+
 func BenchmarkCheckOverhead(b *testing.B) {
 	g := Uint()
 	f := func(t *T) {
@@ -82,3 +142,271 @@ func BenchmarkCheckOverhead(b *testing.B) {
 		checkTB(b, f)
 	}
 }
+
+func TestCheckReplayToken(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		if Int().Draw(t, "x").(int) != 0 {
+			t.Fatal("non-zero")
+		}
+	}
+
+	_, _, seed, buf, err1, _ := doCheck(t, newSettings(nil), baseSeed(), prop)
+	if err1 == nil {
+		t.Fatal("property did not fail")
+	}
+
+	if _, err1, _ := checkReplayToken(t, encodeReplayToken(t.Name(), seed, buf), prop); err1 == nil {
+		t.Fatalf("replay token did not reproduce the failure")
+	}
+	if _, err1, _ := checkReplayToken(t, encodeReplayToken(t.Name()+"/other", seed, buf), prop); err1 != nil {
+		t.Fatalf("replay token for a different test was not ignored: %v", err1)
+	}
+}
+
+func TestSnapshotResume(t *testing.T) {
+	t.Parallel()
+
+	var (
+		token  string
+		prefix []int
+	)
+	checkTB(t, func(t *T) {
+		s := SliceOfN(Int(), 10, 10).Draw(t, "s").([]int)
+		if token == "" {
+			token, prefix = t.Snapshot(), s
+		}
+		Int().Draw(t, "x")
+	})
+
+	xs := map[int]bool{}
+	checkTB(t, func(t *T) {
+		s := SliceOfN(Int(), 10, 10).Draw(t, "s").([]int)
+		if !reflect.DeepEqual(s, prefix) {
+			t.Fatalf("resumed with %v instead of %v", s, prefix)
+		}
+		xs[Int().Draw(t, "x").(int)] = true
+	}, ResumeFrom(token))
+	if len(xs) < 2 {
+		t.Fatalf("resumed test cases did not continue with random data")
+	}
+}
+
+// logTB records the output of a check instead of failing the test.
+type logTB struct {
+	*testing.T
+	mu      sync.Mutex
+	out     strings.Builder
+	failed  bool
+	skipped bool
+}
+
+func (tb *logTB) Logf(format string, args ...interface{}) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	fmt.Fprintf(&tb.out, format+"\n", args...)
+}
+
+func (tb *logTB) Log(args ...interface{}) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	fmt.Fprintln(&tb.out, args...)
+}
+
+func (tb *logTB) Errorf(format string, args ...interface{}) {
+	tb.Logf(format, args...)
+	tb.Fail()
+}
+
+func (tb *logTB) Error(args ...interface{}) {
+	tb.Log(args...)
+	tb.Fail()
+}
+
+func (tb *logTB) Fatalf(format string, args ...interface{}) { tb.Errorf(format, args...) }
+func (tb *logTB) Fatal(args ...interface{})                 { tb.Error(args...) }
+func (tb *logTB) FailNow()                                  { tb.Fail() }
+func (tb *logTB) Fail()                                     { tb.failed = true }
+func (tb *logTB) Failed() bool                              { return tb.failed }
+func (tb *logTB) SkipNow()                                  { tb.skipped = true }
+
+func TestCheckLogsMinimizedDraws(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		x := IntMin(0).Draw(t, "x").(int)
+		s := SliceOf(Byte()).Draw(t, "s").([]byte)
+		if x > 10 && len(s) > 1 {
+			t.Fatal("boom")
+		}
+	})
+	removeFailFiles(t.Name())
+
+	if !tb.failed {
+		t.Fatal("check did not fail")
+	}
+	out := tb.out.String()
+	for _, line := range []string{"[rapid] draw x: 11\n", "[rapid] draw s: []byte{0x0, 0x0}\n", "boom\n"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("%q not found in output:\n%v", line, out)
+		}
+	}
+}
+
+func TestCheckReportsDrawInProgress(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		_ = SliceOfN(Custom(func(t *T) int {
+			n := IntRange(0, 10).Draw(t, "n").(int)
+			if n == 10 {
+				panic("boom")
+			}
+			return n
+		}), 3, 3).Draw(t, "s")
+	})
+	removeFailFiles(t.Name())
+
+	if out := tb.out.String(); !strings.Contains(out, " tests in draw s: boom\n") {
+		t.Errorf("draw in progress not found in output:\n%v", out)
+	}
+}
+
+func TestCheckReportsEnginePanic(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		if IntRange(0, 10).Draw(t, "n").(int) > 5 {
+			t.Fatal("fail")
+		}
+	}, OnShrink(func(ShrinkStep) { panic("boom") }))
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	if !strings.HasPrefix(out, "[rapid] panic after generating test #") || !strings.Contains(out, "): boom\nTo reproduce, specify -run=\"TestCheckReportsEnginePanic\" -rapid.seed=") {
+		t.Errorf("test case not found in output:\n%v", out)
+	}
+}
+
+func TestCheckMaxDuration(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		if IntRange(0, 10).Draw(t, "n").(int) == 3 {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}, MaxDuration(5*time.Millisecond))
+	removeFailFiles(t.Name())
+
+	if !tb.failed {
+		t.Fatal("check did not fail")
+	}
+	out := tb.out.String()
+	for _, line := range []string{"[rapid] draw n: 3\n", "test case took longer than 5ms\n"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("%q not found in output:\n%v", line, out)
+		}
+	}
+}
+
+func TestCheckFuzz(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		if Uint8().Draw(t, "x").(uint8) == 0xff {
+			t.Fatal("0xff")
+		}
+	}
+
+	for _, td := range []struct {
+		input   []byte
+		failed  bool
+		skipped bool
+	}{
+		{[]byte{0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, false, false},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, true, false},
+		{nil, false, false},
+	} {
+		tb := &logTB{T: t}
+		checkFuzz(tb, prop, td.input)
+		if tb.failed != td.failed || tb.skipped != td.skipped {
+			t.Errorf("input %v: got failed %v and skipped %v instead of %v and %v", td.input, tb.failed, tb.skipped, td.failed, td.skipped)
+		}
+	}
+
+	tb := &logTB{T: t}
+	checkFuzz(tb, func(t *T) {
+		Uint8().Filter(func(uint8) bool { return false }).Draw(t, "x")
+	}, nil)
+	if tb.failed || !tb.skipped {
+		t.Errorf("got failed %v and skipped %v for rejected input", tb.failed, tb.skipped)
+	}
+
+	MakeFuzz(prop)(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+}
+
+func TestFindBugCoverageGuided(t *testing.T) {
+	t.Parallel()
+
+	magic := []int{1, 3, 2, 0, 3, 1, 2, 2, 0, 3, 1, 3}
+	var matched int
+	prop := func(t *T) {
+		b := SliceOfN(IntRange(0, 3), len(magic), len(magic)).Draw(t, "b").([]int)
+		n := 0
+		for n < len(magic) && b[n] == magic[n] {
+			n++
+		}
+		if n > matched {
+			matched = n
+		}
+		if n == len(magic) {
+			t.Fatalf("magic found")
+		}
+	}
+
+	cfg := &settings{checks: 100000, coverage: func() float64 { return float64(matched) }}
+	seed, buf, _, _, err := findBug(t, cfg, &corpus{}, baseSeed(), prop)
+	if err == nil {
+		t.Fatalf("magic not found with coverage guidance (matched %v)", matched)
+	}
+	if seed != 0 || buf == nil {
+		t.Fatalf("got seed %v and data %v for mutated test case", seed, buf)
+	}
+	if err := checkOnce(newT(t, newBufBitStream(buf, false), false, nil), prop); err == nil || err.isInvalidData() {
+		t.Fatalf("returned data does not reproduce the failure: %v", err)
+	}
+}
+
+func TestCoverageGuidanceOptIn(t *testing.T) {
+	if flags.coverage {
+		t.Skip("-rapid.coverage is set")
+	}
+
+	if s := newSettings(nil); s.coverage != nil {
+		t.Errorf("coverage guidance is on without GuideByCoverage")
+	}
+	if s := newSettings([]Option{GuideByCoverage()}); (s.coverage != nil) != (testing.CoverMode() != "") {
+		t.Errorf("coverage guidance is %v with GuideByCoverage in cover mode %q", s.coverage != nil, testing.CoverMode())
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	t.Parallel()
+
+	var calls []int
+	err := checkOnce(newT(nil, newRandomBitStream(baseSeed(), false), false, nil), func(t *T) {
+		for i := 1; i <= 3; i++ {
+			i := i
+			t.Cleanup(func() {
+				calls = append(calls, i)
+				if i == 2 {
+					panic("cleanup failed")
+				}
+			})
+		}
+	})
+
+	if err == nil || err.Error() != "cleanup failed" {
+		t.Fatalf("got error %v from the panicking cleanup function", err)
+	}
+	if fmt.Sprint(calls) != "[3 2 1]" {
+		t.Fatalf("cleanup functions called in order %v", calls)
+	}
+}
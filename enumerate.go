@@ -0,0 +1,144 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+// enumerator is implemented by the generators which can force the data of
+// any of their values.
+type enumerator interface {
+	cardinalityEstimator
+	// enumWords returns the data which makes the generator produce its i-th
+	// value, or nil if its values can not be enumerated.
+	enumWords(i uint64) []uint64
+}
+
+// valuePlan enumerates the combinations of the values of the draws from the
+// generators with small finite domains, depth first. Like actionPlan, it forces
+// the data of the draws, so that the test case data is the same as if the
+// values were random.
+type valuePlan struct {
+	maxCases uint64
+	seq      []uint64 // indices of the values of the draws of the current combination
+	sizes    []uint64 // cardinalities of the generators of the draws
+	draws    int      // number of the draws of the current test case so far
+	done     bool
+}
+
+// force makes g produce the value of the current draw, if g can be enumerated.
+// Otherwise, the value is random, which ends the enumeration.
+func (p *valuePlan) force(t *T, g *Generator) {
+	e, ok := g.impl.(enumerator)
+	if !ok {
+		return
+	}
+	n := e.cardinality()
+	if n > float64(p.maxCases) {
+		return
+	}
+
+	var i uint64
+	if p.draws < len(p.seq) {
+		i = p.seq[p.draws]
+	}
+	words := e.enumWords(i)
+	if words == nil {
+		return
+	}
+	if p.draws == len(p.seq) {
+		p.seq = append(p.seq, 0)
+		p.sizes = append(p.sizes, uint64(n))
+	}
+	p.draws++
+
+	t.s.(*randomBitStream).force(words...)
+}
+
+// next moves to the next combination, after the test case has made p.draws draws.
+func (p *valuePlan) next() {
+	p.seq, p.sizes = p.seq[:p.draws], p.sizes[:p.draws]
+	p.draws = 0
+	for len(p.seq) > 0 {
+		last := len(p.seq) - 1
+		p.seq[last]++
+		if p.seq[last] < p.sizes[last] {
+			return
+		}
+		p.seq, p.sizes = p.seq[:last], p.sizes[:last]
+	}
+	p.done = true
+}
+
+func (g *boolGen) enumWords(i uint64) []uint64 {
+	return []uint64{i}
+}
+
+func (g *integerGen) enumWords(i uint64) []uint64 {
+	switch {
+	case g.hasTarget:
+		return nil
+	case !g.signed:
+		return uintNBiasedWords(g.umax-g.umin, i)
+	}
+
+	return intRangeWords(g.smin, g.smax, int64(uint64(g.smin)+i))
+}
+
+func (g *sampledGen) enumWords(i uint64) []uint64 {
+	return uintNBiasedWords(uint64(g.n-1), i)
+}
+
+// findBugEnumerated checks the property with every combination of the values
+// it draws, until one of the test cases fails. It gives up (returning false)
+// when a value is drawn from a generator which can not be enumerated, or there
+// are more than cfg.exhaustive combinations. It returns the number of valid
+// and invalid test cases, and the data of the failing one.
+func findBugEnumerated(tb tb, cfg *settings, seed uint64, prop func(*T)) (int, int, []uint64, *testError, bool) {
+	tb.Helper()
+
+	var (
+		plan    = &valuePlan{maxCases: uint64(cfg.exhaustive)}
+		r       = newRandomBitStream(0, true)
+		t       = newT(tb, r, flags.verbose, nil)
+		valid   = 0
+		invalid = 0
+	)
+	t.enum = plan
+
+	for i := 0; !plan.done; i++ {
+		if i == cfg.exhaustive {
+			tb.Logf("[rapid] not enumerating the test cases: the drawn values have more than %v combinations", cfg.exhaustive)
+			return valid, invalid, nil, nil, false
+		}
+		r.init(seed + uint64(i))
+		cfg.caseIndex, cfg.caseSeed = i+1, 0
+		if t.shouldLog() {
+			t.Logf("[rapid] enumerated test #%v start (%v draws)", i+1, len(plan.seq))
+		}
+
+		err := checkOnce(t, prop)
+		switch {
+		case err == nil:
+			valid++
+		case err.isInvalidData():
+			invalid++
+		case err.isActionTimeout():
+			return valid, invalid, nil, err, true // the data is still in use
+		default:
+			if t.shouldLog() {
+				t.Logf("[rapid] enumerated test #%v failed: %v", i+1, err)
+			}
+			return valid, invalid, append([]uint64(nil), r.data...), err, true
+		}
+		if r.unforced > 0 {
+			tb.Logf("[rapid] not enumerating the test cases: values are drawn from generators without small finite domains")
+			return valid, invalid, nil, nil, false
+		}
+		plan.next()
+	}
+
+	tb.Logf("[rapid] checked all %v combinations of the drawn values", valid+invalid)
+	return valid, invalid, nil, nil, true
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExhaustive(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name string
+		prop func(*T, map[string]bool)
+		want int
+	}{
+		{"independent", func(t *T, seen map[string]bool) {
+			b := Bool().Draw(t, "b").(bool)
+			i := IntRange(-2, 2).Draw(t, "i").(int)
+			u := Uint8Range(7, 8).Draw(t, "u").(uint8)
+			s := SampledFrom([]string{"a", "b", "c"}).Draw(t, "s").(string)
+			j := Just(1).Draw(t, "j").(int)
+			seen[fmt.Sprint(b, i, u, s, j)] = true
+		}, 2 * 5 * 2 * 3},
+		{"dependent", func(t *T, seen map[string]bool) {
+			n := IntRange(0, 2).Draw(t, "n").(int)
+			bs := make([]bool, n)
+			for i := range bs {
+				bs[i] = Bool().Draw(t, "b").(bool)
+			}
+			seen[fmt.Sprint(bs)] = true
+		}, 1 + 2 + 4},
+		{"custom", func(t *T, seen map[string]bool) {
+			p := Custom(func(t *T) [2]int {
+				return [2]int{IntRange(-5, -4).Draw(t, "x").(int), IntRange(4, 5).Draw(t, "y").(int)}
+			}).Map(func(p [2]int) string { return fmt.Sprint(p) }).Draw(t, "p").(string)
+			seen[p] = true
+		}, 4},
+	} {
+		seen := map[string]bool{}
+		prop := func(t *T) { tt.prop(t, seen) }
+		valid, invalid, _, err, complete := findBugEnumerated(t, &settings{exhaustive: 1000}, baseSeed(), prop)
+		if err != nil || !complete || valid != tt.want || invalid != 0 {
+			t.Errorf("%v: %v valid and %v invalid test cases, complete %v (%v)", tt.name, valid, invalid, complete, err)
+		}
+		if len(seen) != tt.want {
+			t.Errorf("%v: %v distinct test cases out of %v", tt.name, len(seen), tt.want)
+		}
+	}
+}
+
+func TestExhaustive_Failure(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		i := IntRange(-100, 100).Draw(t, "i").(int)
+		b := Bool().Draw(t, "b").(bool)
+		if i == -37 && b {
+			t.Fatalf("found it")
+		}
+	}, Exhaustive(1000))
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	for _, s := range []string{"found it", "i: -37", "b: true"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in output:\n%v", s, out)
+		}
+	}
+}
+
+func TestExhaustive_Fallback(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name string
+		prop func(*T)
+	}{
+		{"unbounded", func(t *T) { Int().Draw(t, "i") }},
+		{"slice", func(t *T) { SliceOfN(Bool(), 0, 1).Draw(t, "s") }},
+		{"large", func(t *T) { IntRange(0, 99).Draw(t, "i"); IntRange(0, 99).Draw(t, "j") }},
+	} {
+		_, _, _, err, complete := findBugEnumerated(t, &settings{exhaustive: 1000}, baseSeed(), tt.prop)
+		if err != nil || complete {
+			t.Errorf("%v: complete %v (%v)", tt.name, complete, err)
+		}
+	}
+}
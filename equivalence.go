@@ -0,0 +1,75 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import "reflect"
+
+const commandLabel = "command"
+
+var commandType = reflect.TypeOf(Command{})
+
+// Command is an operation of an equivalence test (see RunEquivalent).
+type Command struct {
+	// Name describes the command (including its arguments) in the test output.
+	Name string
+	// Run applies the command to an implementation and returns the result.
+	Run func(impl interface{}) interface{}
+}
+
+// GoString returns the name of the command, to make the draws of commands readable.
+func (c Command) GoString() string {
+	return c.Name
+}
+
+// RunEquivalent is a convenience function for defining "test against an oracle"
+// tests, to be run by Check or MakeCheck.
+//
+// Each test case creates a trusted reference implementation with newRef and
+// the system under test with newImpl, and applies the same sequence of commands
+// (drawn from the commands generator, which must generate values of type Command)
+// to both of them. Test case fails as soon as the results of a command differ.
+// When equal is nil, results are compared using reflect.DeepEqual, and differing
+// composite results (e.g. structs or slices) are reported using Diff.
+func RunEquivalent(newRef func(*T) interface{}, newImpl func(*T) interface{}, commands *Generator, equal func(a, b interface{}) bool) func(*T) {
+	assertf(commands.type_() == commandType, "commands generator should generate values of type %v, not %v", commandType, commands.type_())
+	diff := equal == nil
+	if equal == nil {
+		equal = reflect.DeepEqual
+	}
+
+	return func(t *T) {
+		t.Helper()
+
+		ref := newRef(t)
+		impl := newImpl(t)
+		t.failOnError()
+
+		repeat := newRepeat(0, flags.steps, maxInt)
+		for step := 1; repeat.more(t.s, commandLabel); step++ {
+			t.trace.begin(t, commandLabel)
+			c := commands.Draw(t, commandLabel).(Command)
+			want := c.Run(ref)
+			got := c.Run(impl)
+			if !equal(want, got) {
+				if diff && composite(want) {
+					t.Fatalf("step %v (%v): result differs from the one of the reference implementation (-want +got):\n%v", step, c.Name, Diff(want, got))
+				}
+				t.Fatalf("step %v (%v): got %v, reference implementation returned %v", step, c.Name, printValue(got), printValue(want))
+			}
+			t.trace.end(t, nil)
+		}
+	}
+}
+
+func composite(v interface{}) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Array, reflect.Map, reflect.Ptr, reflect.Slice, reflect.Struct:
+		return true
+	default:
+		return false
+	}
+}
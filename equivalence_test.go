@@ -0,0 +1,63 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type intSet interface {
+	Add(n int)
+	Len() int
+}
+
+type mapSet map[int]bool
+
+func (s mapSet) Add(n int) { s[n] = true }
+func (s mapSet) Len() int  { return len(s) }
+
+// sliceSet does not check for duplicates.
+type sliceSet struct {
+	s []int
+}
+
+func (s *sliceSet) Add(n int) { s.s = append(s.s, n) }
+func (s *sliceSet) Len() int  { return len(s.s) }
+
+func setCommands() *Generator {
+	return Custom(func(t *T) Command {
+		if Bool().Draw(t, "len").(bool) {
+			return Command{Name: "Len", Run: func(s interface{}) interface{} { return s.(intSet).Len() }}
+		}
+
+		n := IntRange(0, 10).Draw(t, "n").(int)
+		return Command{Name: fmt.Sprintf("Add(%v)", n), Run: func(s interface{}) interface{} { s.(intSet).Add(n); return nil }}
+	})
+}
+
+func TestRunEquivalent(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, RunEquivalent(
+		func(*T) interface{} { return mapSet{} },
+		func(*T) interface{} { return &sliceSet{} },
+		setCommands(),
+		nil,
+	))
+	removeFailFiles(t.Name())
+
+	if !tb.failed {
+		t.Fatal("check did not fail")
+	}
+	out := tb.out.String()
+	for _, line := range []string{"[rapid] draw command: Add(0)\n", "step 3 (Len): got 2, reference implementation returned 1\n"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("%q not found in output:\n%v", line, out)
+		}
+	}
+}
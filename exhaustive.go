@@ -0,0 +1,138 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+const planDisabledMsg = "planned action can not be executed"
+
+// actionPlan enumerates the sequences of state machine actions, shortest first.
+// Run executes the current sequence by forcing the data of its choices,
+// so that the test case data is the same as if the choices were random.
+type actionPlan struct {
+	maxSteps int
+	seq      []int // indices of the actions of the current sequence
+	actions  int   // number of actions of the state machine, 0 until it has run
+	pruned   int   // position of the first action which could not be executed, or -1
+	done     bool
+}
+
+// forceMore makes the state machine continue to the step (counting from 1),
+// if the sequence has it, or stop otherwise.
+func (p *actionPlan) forceMore(t *T, sm *stateMachine, repeat *repeat, step int) {
+	if p == nil {
+		return
+	}
+
+	p.actions = len(sm.actionKeys)
+	more := step <= len(p.seq)
+	if !more {
+		repeat.forceStop = true // state machines never stop on their own
+	}
+	t.s.(*randomBitStream).force(coinFlipWord(more))
+}
+
+// forceAction makes the state machine choose the action of the step among
+// the enabled keys (with the cumulative weights, if not nil). The sequence is
+// pruned when the action is disabled, or has been skipped or rejected (attempt > 0).
+func (p *actionPlan) forceAction(t *T, sm *stateMachine, keys []string, weights []int, step int, attempt int) {
+	if p == nil {
+		return
+	}
+
+	name := sm.actionKeys[p.seq[step-1]]
+	for i, key := range keys {
+		if key == name && attempt == 0 {
+			switch {
+			case weights == nil:
+				t.s.(*randomBitStream).force(uintNBiasedWords(uint64(len(keys)-1), uint64(i))...)
+			case i == 0:
+				t.s.(*randomBitStream).force(0)
+			default:
+				t.s.(*randomBitStream).force(uint64(weights[i-1]))
+			}
+			return
+		}
+	}
+
+	p.abort(step)
+}
+
+// abort prunes the sequences starting with the sequence up to the step.
+func (p *actionPlan) abort(step int) {
+	if p == nil {
+		return
+	}
+
+	p.pruned = step - 1
+	panic(invalidData(planDisabledMsg))
+}
+
+// next moves to the next sequence.
+func (p *actionPlan) next() {
+	if p.actions == 0 {
+		p.done = true // not a state machine
+		return
+	}
+
+	at := len(p.seq) - 1
+	if p.pruned >= 0 {
+		at = p.pruned
+	}
+	for ; at >= 0; at-- {
+		p.seq[at]++
+		if p.seq[at] < p.actions {
+			for i := at + 1; i < len(p.seq); i++ {
+				p.seq[i] = 0
+			}
+			return
+		}
+	}
+
+	if len(p.seq) == p.maxSteps {
+		p.done = true
+	} else {
+		p.seq = make([]int, len(p.seq)+1)
+	}
+}
+
+// findBugExhaustive runs the state machine with every sequence of at most
+// cfg.exhaustiveSteps actions, until one of the test cases fails.
+// It returns the number of valid test cases, and the data of the failing one.
+func findBugExhaustive(tb tb, cfg *settings, seed uint64, prop func(*T)) (int, []uint64, *testError) {
+	tb.Helper()
+
+	var (
+		plan  = &actionPlan{maxSteps: cfg.exhaustiveSteps}
+		r     = newRandomBitStream(0, true)
+		t     = newT(tb, r, flags.verbose, nil)
+		valid = 0
+	)
+	t.plan = plan
+
+	for i := 0; !plan.done; i++ {
+		r.init(seed + uint64(i))
+		cfg.caseIndex, cfg.caseSeed = i+1, 0
+		plan.pruned = -1
+		if t.shouldLog() {
+			t.Logf("[rapid] exhaustive test #%v start (%v steps)", i+1, len(plan.seq))
+		}
+
+		err := checkOnce(t, prop)
+		if err == nil {
+			valid++
+		} else if err.isActionTimeout() {
+			return valid, nil, err // the data is still in use
+		} else if !err.isInvalidData() {
+			if t.shouldLog() {
+				t.Logf("[rapid] exhaustive test #%v failed: %v", i+1, err)
+			}
+			return valid, append([]uint64(nil), r.data...), err
+		}
+		plan.next()
+	}
+
+	return valid, nil, nil
+}
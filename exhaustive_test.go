@@ -0,0 +1,82 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"strings"
+	"testing"
+)
+
+var abcSeqs = map[string]bool{}
+
+type abcMachine struct {
+	seq []string
+}
+
+func (m *abcMachine) A(t *T) { m.seq = append(m.seq, "A") }
+func (m *abcMachine) B(t *T) { m.seq = append(m.seq, "B") }
+func (m *abcMachine) C(t *T) { m.seq = append(m.seq, "C") }
+
+func (m *abcMachine) Check(t *T) {
+	abcSeqs[strings.Join(m.seq, "")] = true
+}
+
+type switchMachine struct {
+	on bool
+}
+
+func (m *switchMachine) OnPrecondition() bool  { return !m.on }
+func (m *switchMachine) OffPrecondition() bool { return m.on }
+
+func (m *switchMachine) On(t *T)  { m.on = true }
+func (m *switchMachine) Off(t *T) { m.on = false }
+
+func (m *switchMachine) Check(t *T) {}
+
+type orderMachine struct {
+	opened bool
+}
+
+func (m *orderMachine) Open(t *T) { m.opened = true }
+
+func (m *orderMachine) Read(t *T) {
+	if m.opened {
+		t.Fatal("read after open")
+	}
+}
+
+func (m *orderMachine) Check(t *T) {}
+
+func TestExhaustiveSteps(t *testing.T) {
+	t.Parallel()
+
+	valid, _, err := findBugExhaustive(t, &settings{exhaustiveSteps: 3}, baseSeed(), Run(&abcMachine{}))
+	if err != nil || valid != 1+3+9+27 {
+		t.Fatalf("%v sequences of at most 3 of 3 actions checked (%v)", valid, err)
+	}
+	if len(abcSeqs) != valid {
+		t.Fatalf("%v distinct sequences out of %v", len(abcSeqs), valid)
+	}
+
+	valid, _, err = findBugExhaustive(t, &settings{exhaustiveSteps: 3}, baseSeed(), Run(&switchMachine{}))
+	if err != nil || valid != 4 {
+		t.Fatalf("%v sequences of at most 3 switches checked (%v)", valid, err)
+	}
+}
+
+func TestExhaustiveSteps_Failure(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, Run(&orderMachine{}), ExhaustiveSteps(2))
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	for _, s := range []string{"failed after 4 tests: read after open", "1. Open() → ok\n2. Read() → read after open"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in output:\n%v", s, out)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"reflect"
+)
+
+const faultProb = 0.1
+
+var faultType = reflect.TypeOf(FaultNone)
+
+// Fault is the outcome of an invocation of a FaultPoint.
+type Fault int
+
+const (
+	FaultNone  Fault = iota // operation should proceed normally
+	FaultError              // operation should fail with an error
+	FaultDelay              // operation should succeed, but only after a delay
+)
+
+func (f Fault) String() string {
+	switch f {
+	case FaultNone:
+		return "none"
+	case FaultError:
+		return "error"
+	case FaultDelay:
+		return "delay"
+	default:
+		return fmt.Sprintf("Fault(%d)", int(f))
+	}
+}
+
+// GoString returns the name of the fault, to make the draws of faults readable.
+func (f Fault) GoString() string {
+	return f.String()
+}
+
+// FaultPoint is a named place in the system under test (like a disk write
+// or an RPC send) where a fault can be injected.
+type FaultPoint struct {
+	name string
+	gen  *Generator
+}
+
+// NewFaultPoint creates a fault point which injects one of faults (FaultError
+// by default) on a small share of invocations.
+func NewFaultPoint(name string, faults ...Fault) *FaultPoint {
+	assertf(name != "", "fault point name should not be empty")
+	if len(faults) == 0 {
+		faults = []Fault{FaultError}
+	}
+	for _, f := range faults {
+		assertf(f == FaultError || f == FaultDelay, "fault point %q can not inject %v", name, f)
+	}
+
+	return &FaultPoint{
+		name: name,
+		gen:  newGenerator(&faultGen{faults: faults}),
+	}
+}
+
+// Inject draws the outcome of an invocation of the fault point. Since outcomes
+// are drawn like any other value, failing test cases are minimized to inject
+// as few faults as possible, and their output lists the outcome of every invocation.
+func (p *FaultPoint) Inject(t *T) Fault {
+	if t.tbLog && t.tb != nil {
+		t.tb.Helper()
+	}
+
+	return p.gen.Draw(t, p.name).(Fault)
+}
+
+type faultGen struct {
+	faults []Fault
+}
+
+func (g *faultGen) String() string {
+	return fmt.Sprintf("Fault(%v)", g.faults)
+}
+
+func (g *faultGen) type_() reflect.Type {
+	return faultType
+}
+
+func (g *faultGen) value(t *T) value {
+	if !flipBiasedCoin(t.s, faultProb) {
+		return FaultNone
+	}
+
+	return g.faults[genIndex(t.s, len(g.faults), false)]
+}
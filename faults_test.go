@@ -0,0 +1,37 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFaultPoint(t *testing.T) {
+	disk := NewFaultPoint("disk write")
+
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		for i := 0; i < 10; i++ {
+			// every write is retried once
+			if disk.Inject(t) != FaultNone && disk.Inject(t) != FaultNone {
+				t.Fatalf("write %v lost", i)
+			}
+		}
+	})
+	removeFailFiles(t.Name())
+
+	if !tb.failed {
+		t.Fatal("lost write not found")
+	}
+	out := tb.out.String()
+	for _, line := range []string{"[rapid] draw disk write: error\n[rapid] draw disk write: error\n", "write 0 lost\n"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("%q not found in output:\n%v", line, out)
+		}
+	}
+}
@@ -111,11 +111,100 @@ func (g *floatGen) type_() reflect.Type {
 }
 
 func (g *floatGen) value(t *T) value {
+	begin := t.s.size()
+
+	var f float64
+	if g.typ == float32Type {
+		f = float64(float32FromParts(genFloatRange(t.s, g.min, g.max, float32SignifBits)))
+	} else {
+		f = float64FromParts(genFloatRange(t.s, g.min, g.max, float64SignifBits))
+	}
+
+	if t.floats != nil {
+		*t.floats = append(*t.floats, floatDraw{g: g, f: f, begin: begin, end: t.s.size()})
+	}
+
+	if g.typ == float32Type {
+		return float32(f)
+	}
+	return f
+}
+
+func (g *floatGen) signifBits() uint {
+	if g.typ == float32Type {
+		return float32SignifBits
+	}
+	return float64SignifBits
+}
+
+// floatDraw is a float value drawn from the data in [begin, end).
+type floatDraw struct {
+	g     *floatGen
+	f     float64
+	begin int
+	end   int
+}
+
+// simpleFloats returns the human-friendly simplifications of f (zero,
+// integers, halves and short decimals), simplest first, which the shrinker
+// tries for the float values (see simplifyFloats). Candidates which are out
+// of range or farther from zero than f are skipped.
+func (g *floatGen) simpleFloats(f float64) []float64 {
+	var cs []float64
+	for _, c := range []float64{0, math.Trunc(f), math.Trunc(f*2) / 2, math.Trunc(f*10) / 10} {
+		if g.typ == float32Type {
+			c = float64(float32(c))
+		}
+		if c != f && c >= g.min && c <= g.max && math.Abs(c) <= math.Abs(f) {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// words returns the data which makes g generate f, or false if there is none.
+func (g *floatGen) words(f float64) ([]uint64, bool) {
+	var posMin, negMin, pNeg float64 // same choices as genFloatRange
+	if g.min >= 0 {
+		posMin = g.min
+	} else if g.max <= 0 {
+		negMin = -g.max
+		pNeg = 1
+	} else {
+		pNeg = 0.5
+	}
+
+	var words []uint64
+	if pNeg == 1 || (pNeg > 0 && math.Signbit(f)) {
+		words = append([]uint64{coinFlipWord(true)}, ufloatRangeWords(-f, negMin, -g.min, g.signifBits())...)
+	} else {
+		words = append([]uint64{coinFlipWord(false)}, ufloatRangeWords(f, posMin, g.max, g.signifBits())...)
+	}
+
+	v, ok := g.fromWords(words)
+	return words, ok && v == f && math.Signbit(v) == math.Signbit(f)
+}
+
+// fromWords returns the value g generates from the data, or false if it does not
+// use exactly all of the data.
+func (g *floatGen) fromWords(words []uint64) (f float64, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, invalid := r.(invalidData); !invalid {
+				panic(r)
+			}
+			ok = false
+		}
+	}()
+
+	s := newBufBitStream(words, false)
 	if g.typ == float32Type {
-		return float32FromParts(genFloatRange(t.s, g.min, g.max, float32SignifBits))
+		f = float64(float32FromParts(genFloatRange(s, g.min, g.max, float32SignifBits)))
 	} else {
-		return float64FromParts(genFloatRange(t.s, g.min, g.max, float64SignifBits))
+		f = float64FromParts(genFloatRange(s, g.min, g.max, float64SignifBits))
 	}
+
+	return f, s.size() == len(words)
 }
 
 func ufloatFracBits(e int32, signifBits uint) uint {
@@ -180,59 +269,99 @@ func float64FromParts(sign bool, e int32, si uint64, sf uint64) float64 {
 	}
 }
 
-func genUfloatRange(s bitStream, min float64, max float64, signifBits uint) (int32, uint64, uint64) {
+// ufloatRange is the range of the parts of the non-negative floats in [min, max].
+type ufloatRange struct {
+	signifBits                                     uint
+	minExp, maxExp                                 int32
+	minSignifI, maxSignifI, minSignifF, maxSignifF uint64
+}
+
+func newUfloatRange(min float64, max float64, signifBits uint) ufloatRange {
 	assert(min >= 0 && min <= max)
 
-	var (
-		minExp, maxExp                                 int32
-		minSignifI, maxSignifI, minSignifF, maxSignifF uint64
-	)
+	r := ufloatRange{signifBits: signifBits}
 	if signifBits == float32SignifBits {
-		minExp, minSignifI, minSignifF = ufloat32Parts(float32(min))
-		maxExp, maxSignifI, maxSignifF = ufloat32Parts(float32(max))
+		r.minExp, r.minSignifI, r.minSignifF = ufloat32Parts(float32(min))
+		r.maxExp, r.maxSignifI, r.maxSignifF = ufloat32Parts(float32(max))
 	} else {
-		minExp, minSignifI, minSignifF = ufloat64Parts(min)
-		maxExp, maxSignifI, maxSignifF = ufloat64Parts(max)
+		r.minExp, r.minSignifI, r.minSignifF = ufloat64Parts(min)
+		r.maxExp, r.maxSignifI, r.maxSignifF = ufloat64Parts(max)
 	}
+	return r
+}
 
-	i := s.beginGroup(floatExpLabel, false)
-	e, lOverflow, rOverflow := genIntRange(s, int64(minExp), int64(maxExp), true)
-	s.endGroup(i, false)
-
-	fracBits := ufloatFracBits(int32(e), signifBits)
+// signifI returns the range of the integer part of the significand for the exponent e.
+func (r ufloatRange) signifI(e int32, lOverflow bool, rOverflow bool) (uint64, uint64) {
+	fracBits := ufloatFracBits(e, r.signifBits)
 
-	j := s.beginGroup(floatSignifLabel, false)
-	var siMin, siMax uint64
 	switch {
 	case lOverflow:
-		siMin, siMax = minSignifI, minSignifI
+		return r.minSignifI, r.minSignifI
 	case rOverflow:
-		siMin, siMax = maxSignifI, maxSignifI
-	case minExp == maxExp:
-		siMin, siMax = minSignifI, maxSignifI
-	case int32(e) == minExp:
-		siMin, siMax = minSignifI, bitmask64(signifBits-fracBits)
-	case int32(e) == maxExp:
-		siMin, siMax = 0, maxSignifI
+		return r.maxSignifI, r.maxSignifI
+	case r.minExp == r.maxExp:
+		return r.minSignifI, r.maxSignifI
+	case e == r.minExp:
+		return r.minSignifI, bitmask64(r.signifBits - fracBits)
+	case e == r.maxExp:
+		return 0, r.maxSignifI
 	default:
-		siMin, siMax = 0, bitmask64(signifBits-fracBits)
+		return 0, bitmask64(r.signifBits - fracBits)
 	}
-	si, _, _ := genUintRange(s, siMin, siMax, false)
-	var sfMin, sfMax uint64
+}
+
+// signifF returns the range of the fractional part of the significand for the exponent e
+// and the integer part si.
+func (r ufloatRange) signifF(e int32, si uint64, lOverflow bool, rOverflow bool) (uint64, uint64) {
+	fracBits := ufloatFracBits(e, r.signifBits)
+
 	switch {
 	case lOverflow:
-		sfMin, sfMax = minSignifF, minSignifF
+		return r.minSignifF, r.minSignifF
 	case rOverflow:
-		sfMin, sfMax = maxSignifF, maxSignifF
-	case minExp == maxExp && minSignifI == maxSignifI:
-		sfMin, sfMax = minSignifF, maxSignifF
-	case int32(e) == minExp && si == minSignifI:
-		sfMin, sfMax = minSignifF, bitmask64(fracBits)
-	case int32(e) == maxExp && si == maxSignifI:
-		sfMin, sfMax = 0, maxSignifF
+		return r.maxSignifF, r.maxSignifF
+	case r.minExp == r.maxExp && r.minSignifI == r.maxSignifI:
+		return r.minSignifF, r.maxSignifF
+	case e == r.minExp && si == r.minSignifI:
+		return r.minSignifF, bitmask64(fracBits)
+	case e == r.maxExp && si == r.maxSignifI:
+		return 0, r.maxSignifF
 	default:
-		sfMin, sfMax = 0, bitmask64(fracBits)
+		return 0, bitmask64(fracBits)
+	}
+}
+
+// ufloatRangeWords returns the data which makes genUfloatRange(s, min, max, signifBits)
+// return the parts of f, if f is in range.
+func ufloatRangeWords(f float64, min float64, max float64, signifBits uint) []uint64 {
+	rg := newUfloatRange(min, max, signifBits)
+
+	var e int32
+	var si, sf uint64
+	if signifBits == float32SignifBits {
+		e, si, sf = ufloat32Parts(float32(f))
+	} else {
+		e, si, sf = ufloat64Parts(f)
 	}
+
+	siMin, _ := rg.signifI(e, false, false)
+	sfMin, sfMax := rg.signifF(e, si, false, false)
+	maxR := bits.Len64(sfMax - sfMin) // no bits of sf are cleared
+
+	return append(intRangeWords(int64(rg.minExp), int64(rg.maxExp), int64(e)), si-siMin, uint64(maxR), sf-sfMin)
+}
+
+func genUfloatRange(s bitStream, min float64, max float64, signifBits uint) (int32, uint64, uint64) {
+	rg := newUfloatRange(min, max, signifBits)
+
+	i := s.beginGroup(floatExpLabel, false)
+	e, lOverflow, rOverflow := genIntRange(s, int64(rg.minExp), int64(rg.maxExp), true)
+	s.endGroup(i, false)
+
+	j := s.beginGroup(floatSignifLabel, false)
+	siMin, siMax := rg.signifI(int32(e), lOverflow, rOverflow)
+	si, _, _ := genUintRange(s, siMin, siMax, false)
+	sfMin, sfMax := rg.signifF(int32(e), si, lOverflow, rOverflow)
 	maxR := bits.Len64(sfMax - sfMin)
 	r := genUintNNoReject(s, uint64(maxR))
 	sf, _, _ := genUintRange(s, sfMin, sfMax, false)
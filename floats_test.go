@@ -118,3 +118,25 @@ func TestGenFloat64Range(t *testing.T) {
 		}
 	})
 }
+
+func TestFloatGenWords(t *testing.T) {
+	t.Parallel()
+
+	Check(t, func(t *T) {
+		min := Float64().Draw(t, "min").(float64)
+		max := Float64().Draw(t, "max").(float64)
+		if min > max {
+			min, max = max, min
+		}
+
+		g := &floatGen{typ: float64Type, min: min, max: max}
+		f := float64FromParts(genFloatRange(t.s, min, max, float64SignifBits))
+		words, ok := g.words(f)
+		if !ok {
+			t.Fatalf("no data for %v (0x%x) in [%v, %v]", f, math.Float64bits(f), min, max)
+		}
+		if v, _ := g.fromWords(words); v != f {
+			t.Fatalf("got %v back from data %v for %v in [%v, %v]", v, words, f, min, max)
+		}
+	})
+}
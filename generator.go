@@ -7,6 +7,7 @@
 package rapid
 
 import (
+	"fmt"
 	"reflect"
 	"sync"
 )
@@ -45,6 +46,8 @@ func (g *Generator) type_() reflect.Type {
 	return g.typ
 }
 
+// Draw produces a value from the generator, labeled in the output of rapid.
+// An empty label is derived from the call site, like "x@foo_test.go:12".
 func (g *Generator) Draw(t *T, label string) interface{} {
 	if t.tbLog && t.tb != nil {
 		t.tb.Helper()
@@ -55,6 +58,9 @@ func (g *Generator) Draw(t *T, label string) interface{} {
 func (g *Generator) value(t *T) value {
 	i := t.s.beginGroup(g.str, true)
 
+	if t.enum != nil {
+		t.enum.force(t, g)
+	}
 	v := g.impl.value(t)
 	u := reflect.TypeOf(v)
 	assertf(v != nil, "%v has generated a nil value", g)
@@ -65,6 +71,10 @@ func (g *Generator) value(t *T) value {
 	return v
 }
 
+// Example returns a value produced by g, e.g. for documentation examples or
+// to inspect composed generators. The value is deterministic when the seed
+// is specified, and random otherwise. Generators have no size parameter:
+// the size of the value depends on the seed only.
 func (g *Generator) Example(seed ...int) interface{} {
 	s := baseSeed()
 	if len(seed) > 0 {
@@ -77,6 +87,29 @@ func (g *Generator) Example(seed ...int) interface{} {
 	return v
 }
 
+// SampleN returns n values produced by g from the seed, e.g. to see what a new
+// generator produces without writing a test. The same seed gives the same values.
+func SampleN(g *Generator, seed int, n int) []interface{} {
+	assertf(n >= 0, "number of samples should not be negative, not %v", n)
+
+	t := newT(nil, newRandomBitStream(uint64(seed), false), false, nil)
+	values := make([]interface{}, n)
+	for i := range values {
+		v, tries, err := example(g, t)
+		assertf(err == nil, "%v failed to generate a sample in %v tries: %v", g, tries, err)
+		values[i] = v
+	}
+
+	return values
+}
+
+// PrintSampleN prints n values produced by g from the seed (see SampleN), one per line.
+func PrintSampleN(g *Generator, seed int, n int) {
+	for _, v := range SampleN(g, seed, n) {
+		fmt.Println(printValue(v))
+	}
+}
+
 func (g *Generator) Filter(fn interface{}) *Generator {
 	return filter(g, fn)
 }
@@ -85,6 +118,25 @@ func (g *Generator) Map(fn interface{}) *Generator {
 	return map_(g, fn)
 }
 
+// ShrinkWith returns a generator which lets the shrinker try domain-specific
+// simplifications of the values g produces, in addition to the usual bit-level
+// minimization. fn should have the form func(V) []V, returning candidate
+// values (most preferred first); every candidate must be a valid value of g.
+// fn is only called while shrinking: from random or zero-filled data (as used
+// when fuzzing), the generator produces exactly the same values as g.
+func (g *Generator) ShrinkWith(fn interface{}) *Generator {
+	return shrinkWith(g, fn)
+}
+
+// Sensitive returns a generator which marks the values of g as sensitive: rapid
+// replaces them with hashes when describing the draws in failures, logs and exported
+// reports (see RegisterSensitive). The persisted failing test cases still reproduce
+// the values exactly. The values built from the sensitive ones (by Custom, SliceOf,
+// Map and so on) are redacted as well.
+func (g *Generator) Sensitive() *Generator {
+	return sensitive(g)
+}
+
 func example(g *Generator, t *T) (value, int, error) {
 	for i := 1; ; i++ {
 		r, err := recoverValue(g, t)
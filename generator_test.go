@@ -8,6 +8,7 @@ package rapid
 
 import (
 	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -36,3 +37,56 @@ func BenchmarkGenerator_Value(b *testing.B) {
 		g.value(t)
 	}
 }
+
+func TestSampleN(t *testing.T) {
+	t.Parallel()
+
+	g := SliceOf(Int())
+	s1, s2 := SampleN(g, 1, 10), SampleN(g, 1, 10)
+	if len(s1) != 10 || !reflect.DeepEqual(s1, s2) {
+		t.Errorf("samples from the same seed differ: %v and %v", s1, s2)
+	}
+	if reflect.DeepEqual(s1, SampleN(g, 2, 10)) {
+		t.Errorf("samples from different seeds are equal: %v", s1)
+	}
+}
+
+func isEven(i int) bool { return i%2 == 0 }
+
+func TestGenerator_String(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		g    *Generator
+		want string
+	}{
+		{IntRange(-5, 100), "IntRange(-5, 100)"},
+		{SliceOfN(String(), 0, 8), "SliceOfN(String(), minLen=0, maxLen=8)"},
+		{Int().Filter(isEven), "Int().Filter(rapid.isEven)"},
+		{Int().Filter(func(i int) bool { return i > 0 }), "Int().Filter(...)"},
+		{Int().Map(strconv.Itoa), "Int().Map(strconv.Itoa)"},
+		{Int().Map(func(i int) uint { return uint(i) }), "Int().Map(func(...) uint)"},
+		{OneOf(Just("a"), SampledFrom([]int{1, 2, 3}).Map(strconv.Itoa)), `OneOf(Just("a"), SampledFrom([]int{1, 2, 3}).Map(strconv.Itoa))`},
+		{Custom(func(t *T) int { return 0 }), "Custom(int)"},
+		{SliceOfDistinct(Int(), nil), "SliceOfDistinct(Int())"},
+		{Just(new(int)), "Just(*int)"},
+		{SampledFrom([]struct{ p *int }{{}, {}}), "SampledFrom(2 struct { p *int })"},
+	} {
+		if got := tt.g.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestGenerator_StringHash(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		_ = SampledFrom([]*int{new(int), new(int)}).Draw(t, "p")
+	}
+	_, h1 := captureTestOutput(t, prop, nil)
+	_, h2 := captureTestOutput(t, prop, nil)
+	if h1 != h2 {
+		t.Errorf("hashes of the same generators differ: %v, %v", h1, h2)
+	}
+}
@@ -241,13 +241,68 @@ func newUintMaxGen(kind string, max uint64) *Generator {
 
 type integerGen struct {
 	integerKindInfo
-	kind   string
-	umin   uint64
-	hasMin bool
-	hasMax bool
+	kind      string
+	umin      uint64
+	hasMin    bool
+	hasMax    bool
+	hasTarget bool
+	starget   int64
+	utarget   uint64
+}
+
+// ShrinkTowards returns a generator of the integers in the range of g, which
+// considers target (instead of the value closest to zero) to be the simplest
+// one, so that failing test cases are minimized towards it. The values are
+// generated around target, so their distribution differs from the one of g.
+// g should be created by one of the integer generator functions (e.g. IntRange,
+// not IntRange(...).Filter), and target should be within its range.
+func (g *Generator) ShrinkTowards(target interface{}) *Generator {
+	ig, ok := g.impl.(*integerGen)
+	assertf(ok, "%v does not support ShrinkTowards, only integer generators do", g)
+
+	v := reflect.ValueOf(target)
+	ig_ := *ig
+	ig_.hasTarget = true
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if ig.signed {
+			ig_.starget = v.Int()
+		} else {
+			assertf(v.Int() >= 0, "target %v is out of range [%d, %d]", target, ig.umin, ig.umax)
+			ig_.utarget = uint64(v.Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if ig.signed {
+			assertf(v.Uint() <= math.MaxInt64, "target %v is out of range [%d, %d]", target, ig.smin, ig.smax)
+			ig_.starget = int64(v.Uint())
+		} else {
+			ig_.utarget = v.Uint()
+		}
+	default:
+		assertf(false, "target should be an integer, not %v", v.Kind())
+	}
+
+	if ig.signed {
+		assertf(ig_.starget >= ig.smin && ig_.starget <= ig.smax, "target %v is out of range [%d, %d]", target, ig.smin, ig.smax)
+	} else {
+		assertf(ig_.utarget >= ig.umin && ig_.utarget <= ig.umax, "target %v is out of range [%d, %d]", target, ig.umin, ig.umax)
+	}
+
+	return newGenerator(&ig_)
 }
 
 func (g *integerGen) String() string {
+	if g.hasTarget {
+		target := fmt.Sprint(g.starget)
+		if !g.signed {
+			target = fmt.Sprint(g.utarget)
+		}
+
+		g_ := *g
+		g_.hasTarget = false
+		return fmt.Sprintf("%v.ShrinkTowards(%v)", g_.String(), target)
+	}
+
 	if g.hasMin && g.hasMax {
 		if g.signed {
 			return fmt.Sprintf("%sRange(%d, %d)", g.kind, g.smin, g.smax)
@@ -279,9 +334,24 @@ func (g *integerGen) value(t *T) value {
 	var i int64
 	var u uint64
 
-	if g.signed {
+	switch {
+	case g.hasTarget && g.signed:
+		neg, d := genOffset(t.s, uint64(g.starget)-uint64(g.smin), uint64(g.smax)-uint64(g.starget), true)
+		if neg {
+			i = int64(uint64(g.starget) - d)
+		} else {
+			i = int64(uint64(g.starget) + d)
+		}
+	case g.hasTarget:
+		neg, d := genOffset(t.s, g.utarget-g.umin, g.umax-g.utarget, true)
+		if neg {
+			u = g.utarget - d
+		} else {
+			u = g.utarget + d
+		}
+	case g.signed:
 		i, _, _ = genIntRange(t.s, g.smin, g.smax, true)
-	} else {
+	default:
 		u, _, _ = genUintRange(t.s, g.umin, g.umax, true)
 	}
 
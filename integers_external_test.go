@@ -8,10 +8,12 @@ package rapid_test
 
 import (
 	"flag"
+	"fmt"
 	"math/bits"
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
 
 	. "pgregory.net/rapid"
@@ -147,6 +149,42 @@ func TestUintMinMaxRange(t *testing.T) {
 	}
 }
 
+func TestShrinkTowardsRange(t *testing.T) {
+	t.Parallel()
+
+	Check(t, func(t *T) {
+		min := Int64().Draw(t, "min").(int64)
+		max := Int64().Draw(t, "max").(int64)
+		if min > max {
+			min, max = max, min
+		}
+		target := Int64Range(min, max).Draw(t, "target").(int64)
+
+		i := Int64Range(min, max).ShrinkTowards(target).Draw(t, "i").(int64)
+		if i < min || i > max {
+			t.Fatalf("got %v which is out of bounds [%v, %v]", i, min, max)
+		}
+	})
+}
+
+func TestShrinkTowardsNonInteger(t *testing.T) {
+	t.Parallel()
+
+	for _, g := range []*Generator{
+		Float64(),
+		IntRange(0, 10).Filter(func(i int) bool { return i > 0 }),
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "does not support ShrinkTowards") {
+					t.Errorf("%v: got %v instead of a panic", g, r)
+				}
+			}()
+			g.ShrinkTowards(1)
+		}()
+	}
+}
+
 func TestIntBoundCoverage(t *testing.T) {
 	t.Parallel()
 
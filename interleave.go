@@ -0,0 +1,156 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import "runtime"
+
+const (
+	scheduleLabel         = "schedule"
+	interleavePreemptProb = 0.25
+	interleaveMaxYields   = 64 // yields after which the running goroutine is always preempted
+)
+
+// Yielder is given to every goroutine run by Interleave.
+type Yielder struct {
+	sc     *scheduler
+	resume chan struct{}
+}
+
+// Yield is a point where the goroutine can be preempted by the scheduler
+// in favor of another one.
+func (y *Yielder) Yield() {
+	select {
+	case y.sc.events <- interleaveEvent{}:
+	case <-y.sc.abort:
+		runtime.Goexit()
+	}
+	y.wait()
+}
+
+func (y *Yielder) wait() {
+	select {
+	case <-y.resume:
+	case <-y.sc.abort:
+		runtime.Goexit()
+	}
+}
+
+func (y *Yielder) run(f func(*Yielder)) {
+	defer func() {
+		select {
+		case y.sc.events <- interleaveEvent{done: true, panic: recover()}:
+		case <-y.sc.abort:
+		}
+	}()
+
+	y.wait()
+	f(y)
+}
+
+type interleaveEvent struct {
+	done  bool
+	panic interface{}
+}
+
+type scheduler struct {
+	events chan interleaveEvent
+	abort  chan struct{}
+}
+
+// Interleave runs every function in its own goroutine, but only one of them
+// at a time: goroutines are switched only at the yield points, where the
+// running goroutine calls Yield. The scheduler decides which goroutine to run
+// next using the drawn data, so a failing interleaving is found, reproduced
+// and minimized (to fewer context switches) like any other drawn value.
+// Since the scheduler rarely preempts the running goroutine, like a PCT
+// (probabilistic concurrency testing) scheduler it is likely to find
+// bugs which need only a few context switches.
+//
+// Functions may call methods of t and draw values, but they should not block
+// waiting for each other (except in loops calling Yield), as the goroutine
+// they wait for does not run. Interleave returns after every function has
+// returned; panics (including ones caused by test failures) are re-raised
+// in the calling goroutine.
+func Interleave(t *T, fs ...func(y *Yielder)) {
+	if t.tbLog && t.tb != nil {
+		t.tb.Helper()
+	}
+
+	sc := &scheduler{
+		events: make(chan interleaveEvent),
+		abort:  make(chan struct{}),
+	}
+	defer close(sc.abort)
+
+	ys := make([]*Yielder, len(fs))
+	for i, f := range fs {
+		ys[i] = &Yielder{sc: sc, resume: make(chan struct{})}
+		go ys[i].run(f)
+	}
+
+	var (
+		done     = make([]bool, len(fs))
+		running  = len(fs)
+		cur      = 0
+		yields   = 0
+		schedule []int
+	)
+	defer func() {
+		if t.shouldLog() {
+			if t.tbLog && t.tb != nil {
+				t.tb.Helper()
+			}
+			t.Logf("[rapid] interleaving: %v", schedule)
+		}
+	}()
+
+	for running > 0 {
+		if len(schedule) == 0 || schedule[len(schedule)-1] != cur+1 {
+			schedule = append(schedule, cur+1)
+		}
+
+		ys[cur].resume <- struct{}{}
+		ev := <-sc.events
+		if ev.done {
+			if ev.panic != nil {
+				panic(ev.panic)
+			}
+			done[cur] = true
+			running--
+		} else {
+			yields++
+		}
+
+		next := chooseGoroutine(t.s, cur, done, yields >= interleaveMaxYields)
+		if next != cur {
+			cur = next
+			yields = 0
+		}
+	}
+}
+
+// chooseGoroutine returns the goroutine to run after cur, which is switched
+// from when it is done or forced to be preempted, and with a small probability otherwise.
+func chooseGoroutine(s bitStream, cur int, done []bool, force bool) int {
+	var others []int
+	for i := range done {
+		if i != cur && !done[i] {
+			others = append(others, i)
+		}
+	}
+	if len(others) == 0 {
+		return cur
+	}
+
+	i := s.beginGroup(scheduleLabel, false)
+	if done[cur] || force || flipBiasedCoin(s, interleavePreemptProb) {
+		cur = others[genIndex(s, len(others), false)]
+	}
+	s.endGroup(i, false)
+
+	return cur
+}
@@ -0,0 +1,65 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func counterIncs(yieldInside bool) func(*T) {
+	return func(t *T) {
+		n := 0
+		inc := func(y *Yielder) {
+			y.Yield()
+			v := n
+			if yieldInside {
+				y.Yield()
+			}
+			n = v + 1
+			y.Yield()
+		}
+		Interleave(t, inc, inc, inc)
+		if n != 3 {
+			t.Fatalf("got %v after 3 increments", n)
+		}
+	}
+}
+
+func TestInterleave(t *testing.T) {
+	t.Parallel()
+
+	_, _, _, _, err := findBug(t, &settings{checks: 100}, &corpus{}, baseSeed(), counterIncs(false))
+	if err != nil {
+		t.Fatalf("increments without yield points inside have failed: %v", err)
+	}
+
+	tb := &logTB{T: t}
+	checkTB(tb, counterIncs(true))
+	removeFailFiles(t.Name())
+
+	if !tb.failed {
+		t.Fatal("lost update not found")
+	}
+	// the incrementers are identical, so switching to either of them first is minimal
+	out := tb.out.String()
+	if re := regexp.MustCompile(`\[rapid\] interleaving: \[1 [23]`); !re.MatchString(out) {
+		t.Errorf("%q not found in output:\n%v", re, out)
+	}
+}
+
+func TestInterleave_Panic(t *testing.T) {
+	t.Parallel()
+
+	_, _, _, _, err := findBug(t, &settings{checks: 10}, &corpus{}, baseSeed(), func(t *T) {
+		Interleave(t, func(y *Yielder) { y.Yield() }, func(y *Yielder) { panic("boom") })
+	})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("panic not re-raised: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package failfile reads and writes the fail files of rapid, so that the rapid
+// command and the library share one implementation of the format.
+//
+// Fail files start with the Magic header followed by the format version, so that
+// files written by incompatible future versions of rapid are rejected explicitly.
+// Fail files without the header (written by rapid before the format was versioned)
+// are still read, just without the generator hash.
+package failfile
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	Magic         = "rapid-failfile"
+	FormatVersion = 2
+	DataPrefix    = "varint:"
+)
+
+// File is a persisted failing test case.
+type File struct {
+	Version string   // rapid version which has written the file
+	GenHash uint64   // hash of the draws made by the failing test case (0 if unknown)
+	Seed    uint64   // seed which has generated the test case (0 if unknown)
+	Buf     []uint64 // bitstream of the test case
+	Output  []string // lines of the recorded test output, stored as comments
+}
+
+// Encode returns the contents of the fail file.
+func Encode(f File) []byte {
+	var b strings.Builder
+	for _, s := range f.Output {
+		b.WriteString("# " + s + "\n")
+	}
+
+	bs := []string{
+		fmt.Sprintf("%v %v", Magic, FormatVersion),
+		fmt.Sprintf("version %v", f.Version),
+		fmt.Sprintf("generators %016x", f.GenHash),
+		fmt.Sprintf("seed %v", f.Seed),
+		fmt.Sprintf("data %v", EncodeData(f.Buf)),
+	}
+	b.WriteString(strings.Join(bs, "\n"))
+
+	return []byte(b.String())
+}
+
+// Read parses the fail file. Trailing empty lines of the output are dropped.
+func Read(r io.Reader) (File, error) {
+	var data, output []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		s := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(s, "#") {
+			output = append(output, strings.TrimSpace(strings.TrimPrefix(s, "#")))
+		} else if s != "" {
+			data = append(data, s)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return File{}, err
+	}
+	for len(output) > 0 && output[len(output)-1] == "" {
+		output = output[:len(output)-1]
+	}
+
+	if len(data) == 0 {
+		return File{}, fmt.Errorf("no data")
+	}
+	var (
+		f   File
+		err error
+	)
+	if strings.HasPrefix(data[0], Magic+" ") {
+		f, err = parse(data)
+	} else {
+		f, err = parseLegacy(data)
+	}
+	if err != nil {
+		return File{}, err
+	}
+	f.Output = output
+
+	return f, nil
+}
+
+func parse(data []string) (File, error) {
+	format, err := strconv.Atoi(strings.TrimPrefix(data[0], Magic+" "))
+	if err != nil {
+		return File{}, fmt.Errorf("invalid format field %q", data[0])
+	}
+	if format != FormatVersion {
+		return File{}, fmt.Errorf("unsupported format %v (only format %v is supported)", format, FormatVersion)
+	}
+
+	fields := map[string]string{}
+	for _, s := range data[1:] {
+		split := strings.SplitN(s, " ", 2)
+		if len(split) != 2 {
+			return File{}, fmt.Errorf("invalid field %q", s)
+		}
+		fields[split[0]] = split[1]
+	}
+	for _, k := range []string{"version", "generators", "seed", "data"} {
+		if _, ok := fields[k]; !ok {
+			return File{}, fmt.Errorf("no %v field", k)
+		}
+	}
+
+	f := File{Version: fields["version"]}
+	if f.GenHash, err = strconv.ParseUint(fields["generators"], 16, 64); err != nil {
+		return File{}, fmt.Errorf("invalid generator hash %q", fields["generators"])
+	}
+	if f.Seed, err = strconv.ParseUint(fields["seed"], 10, 64); err != nil {
+		return File{}, fmt.Errorf("invalid seed %q", fields["seed"])
+	}
+	if f.Buf, err = DecodeData(fields["data"]); err != nil {
+		return File{}, err
+	}
+
+	return f, nil
+}
+
+// parseLegacy parses the unversioned fail files: a "version#seed" line
+// followed by either varint-encoded data or one hexadecimal word per line.
+func parseLegacy(data []string) (File, error) {
+	split := strings.Split(data[0], "#")
+	if len(split) != 2 {
+		return File{}, fmt.Errorf("invalid version/seed field %q", data[0])
+	}
+	seed, err := strconv.ParseUint(split[1], 10, 64)
+	if err != nil {
+		return File{}, fmt.Errorf("invalid seed %q", split[1])
+	}
+
+	f := File{Version: split[0], Seed: seed}
+	if len(data) == 2 && strings.HasPrefix(data[1], DataPrefix) {
+		f.Buf, err = DecodeData(data[1])
+		if err != nil {
+			return File{}, err
+		}
+		return f, nil
+	}
+
+	for _, b := range data[1:] {
+		u, err := strconv.ParseUint(b, 0, 64)
+		if err != nil {
+			return File{}, err
+		}
+		f.Buf = append(f.Buf, u)
+	}
+
+	return f, nil
+}
+
+// EncodeData encodes the bitstream as the number of words followed by
+// the words themselves, all as varints. Most of the words drawn are small
+// (runes, lengths, shrunk integers), so this is much more compact than one
+// hexadecimal word per line.
+func EncodeData(buf []uint64) string {
+	b := make([]byte, 0, binary.MaxVarintLen64*(len(buf)+1))
+	b = AppendUvarint(b, uint64(len(buf)))
+	for _, u := range buf {
+		b = AppendUvarint(b, u)
+	}
+
+	return DataPrefix + base64.RawStdEncoding.EncodeToString(b)
+}
+
+// DecodeData decodes the bitstream encoded by EncodeData.
+func DecodeData(s string) ([]uint64, error) {
+	b, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(s, DataPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid data encoding: %w", err)
+	}
+
+	n, err := ReadUvarint(&b)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(b)) {
+		return nil, fmt.Errorf("invalid data length %v", n)
+	}
+	buf := make([]uint64, n)
+	for i := range buf {
+		buf[i], err = ReadUvarint(&b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(b) != 0 {
+		return nil, fmt.Errorf("%v trailing data bytes", len(b))
+	}
+
+	return buf, nil
+}
+
+// AppendUvarint appends the varint encoding of u to b.
+func AppendUvarint(b []byte, u uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], u)
+	return append(b, tmp[:n]...)
+}
+
+// ReadUvarint decodes a varint from the start of *b and removes it.
+func ReadUvarint(b *[]byte) (uint64, error) {
+	u, n := binary.Uvarint(*b)
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid varint in data")
+	}
+	*b = (*b)[n:]
+	return u, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package failfile_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+	"pgregory.net/rapid/internal/failfile"
+)
+
+func TestRoundtrip(t *testing.T) {
+	t.Parallel()
+
+	f := failfile.File{Version: "v1", GenHash: 0xabc, Seed: 42, Buf: []uint64{3, 0, 1 << 63}, Output: []string{"TestFoo [rapid] draw x: 1", ""}}
+	f2, err := failfile.Read(strings.NewReader(string(failfile.Encode(f))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f2.Version != f.Version || f2.GenHash != f.GenHash || f2.Seed != f.Seed || fmt.Sprint(f2.Buf) != fmt.Sprint(f.Buf) || fmt.Sprint(f2.Output) != fmt.Sprint(f.Output[:1]) {
+		t.Fatalf("got %+v instead of %+v", f2, f)
+	}
+}
+
+func TestFailDataEncoding(t *testing.T) {
+	t.Parallel()
+
+	rapid.Check(t, func(t *rapid.T) {
+		buf := rapid.SliceOf(rapid.Uint64()).Draw(t, "buf").([]uint64)
+		n := rapid.IntRange(0, 100).Draw(t, "n").(int)
+
+		s := failfile.EncodeData(buf)
+		buf2, err := failfile.DecodeData(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(buf2) != fmt.Sprint(buf) {
+			t.Fatalf("got %v instead of %v", buf2, buf)
+		}
+
+		if n < len(s) {
+			_, _ = failfile.DecodeData(s[:n]) // must not panic
+		}
+	})
+}
+
+func TestFailDataEncodingCompact(t *testing.T) {
+	t.Parallel()
+
+	var buf []uint64
+	for _, r := range "a fairly typical string-heavy failing test case" {
+		buf = append(buf, 1, uint64(r))
+	}
+
+	var hex int
+	for _, u := range buf {
+		hex += len(fmt.Sprintf("0x%x\n", u))
+	}
+	if s := failfile.EncodeData(buf); len(s)*2 > hex {
+		t.Fatalf("encoded data is %v bytes, hexadecimal words are %v bytes", len(s), hex)
+	}
+}
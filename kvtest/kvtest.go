@@ -0,0 +1,206 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package kvtest provides a ready-made state machine for testing map-like
+// stores (caches, storage engines and the like) against a map model.
+//
+// To use it, embed Machine into a state machine type, and set up the store
+// under test in Init:
+//
+//	type storeMachine struct {
+//		kvtest.Machine
+//	}
+//
+//	func (m *storeMachine) Init(t *rapid.T) {
+//		m.Setup(mystore.New())
+//	}
+//
+//	func TestStore(t *testing.T) {
+//		rapid.Check(t, rapid.Run(&storeMachine{}))
+//	}
+//
+// The state machine type can add actions of its own (e.g. to flush, compact
+// or reopen the store), and override Check, calling Machine.Check to keep
+// the invariants of the store.
+package kvtest
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"pgregory.net/rapid"
+)
+
+// Store is a map from string keys to byte slice values.
+type Store interface {
+	// Get returns the value of the key, and whether the key is present.
+	Get(key string) ([]byte, bool, error)
+	// Put sets the value of the key. The store should not keep value,
+	// which can be changed by the caller after Put returns.
+	Put(key string, value []byte) error
+	// Delete removes the key, if it is present.
+	Delete(key string) error
+	// Scan returns the entries with keys in [from, to), in ascending order
+	// of the keys; empty to means no upper bound.
+	Scan(from string, to string) ([]Entry, error)
+}
+
+// Entry is a key together with its value.
+type Entry struct {
+	Key   string
+	Value []byte
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("%q: %q", e.Key, e.Value)
+}
+
+var (
+	// DefaultKeys are short strings of a few letters, so that the actions
+	// often use the same keys.
+	DefaultKeys = rapid.StringOfN(rapid.RuneFrom([]rune("abc")), 1, 3, -1)
+	// DefaultValues are short byte slices.
+	DefaultValues = rapid.SliceOfN(rapid.Byte(), 0, 8)
+)
+
+// Machine checks a store against a map model. Its actions are Get, Put, Delete
+// and Scan, and Check compares all the entries of the store with the model.
+// Any error returned by the store fails the test.
+type Machine struct {
+	Keys   *rapid.Generator // generator of the keys (of type string), DefaultKeys if nil
+	Values *rapid.Generator // generator of the values (of type []byte), DefaultValues if nil
+
+	store Store
+	model map[string][]byte
+}
+
+// Setup sets the store under test, which should be empty when Setup is first
+// called. Setup can be called again (e.g. after the store has been reopened)
+// to replace the store, keeping the model.
+func (m *Machine) Setup(store Store) {
+	m.store = store
+	if m.model == nil {
+		m.model = map[string][]byte{}
+	}
+}
+
+// Store returns the store under test.
+func (m *Machine) Store() Store {
+	return m.store
+}
+
+// Model returns the entries the store should have, in ascending order of the keys.
+// The values should not be modified.
+func (m *Machine) Model() []Entry {
+	return m.expected("", "")
+}
+
+func (m *Machine) key(t *rapid.T, label string) string {
+	g := m.Keys
+	if g == nil {
+		g = DefaultKeys
+	}
+	return g.Draw(t, label).(string)
+}
+
+func (m *Machine) value(t *rapid.T) []byte {
+	g := m.Values
+	if g == nil {
+		g = DefaultValues
+	}
+	return g.Draw(t, "value").([]byte)
+}
+
+func (m *Machine) expected(from string, to string) []Entry {
+	var entries []Entry
+	for k, v := range m.model {
+		if k >= from && (to == "" || k < to) {
+			entries = append(entries, Entry{Key: k, Value: v})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// Get checks the value of a key.
+func (m *Machine) Get(t *rapid.T) {
+	key := m.key(t, "key")
+
+	v, ok, err := m.store.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q) failed: %v", key, err)
+	}
+	want, wantOK := m.model[key]
+	if ok != wantOK || !bytes.Equal(v, want) {
+		t.Fatalf("Get(%q) = %q, %v; want %q, %v", key, v, ok, want, wantOK)
+	}
+}
+
+// Put sets a value, and then changes the slice passed to the store,
+// to check that the store does not keep it.
+func (m *Machine) Put(t *rapid.T) {
+	key := m.key(t, "key")
+	value := m.value(t)
+
+	v := append([]byte(nil), value...)
+	if err := m.store.Put(key, v); err != nil {
+		t.Fatalf("Put(%q, %q) failed: %v", key, value, err)
+	}
+	m.model[key] = value
+	for i := range v {
+		v[i] ^= 0xff
+	}
+}
+
+// Delete removes a key, which may be absent.
+func (m *Machine) Delete(t *rapid.T) {
+	key := m.key(t, "key")
+
+	if err := m.store.Delete(key); err != nil {
+		t.Fatalf("Delete(%q) failed: %v", key, err)
+	}
+	delete(m.model, key)
+}
+
+// Scan checks the entries in a range of keys, which may have no upper bound.
+func (m *Machine) Scan(t *rapid.T) {
+	from := m.key(t, "from")
+	to := ""
+	if rapid.Bool().Draw(t, "bounded").(bool) {
+		to = m.key(t, "to")
+	}
+
+	m.checkScan(t, from, to)
+}
+
+// Check checks that the store has exactly the entries of the model.
+func (m *Machine) Check(t *rapid.T) {
+	m.checkScan(t, "", "")
+}
+
+func (m *Machine) checkScan(t *rapid.T, from string, to string) {
+	entries, err := m.store.Scan(from, to)
+	if err != nil {
+		t.Fatalf("Scan(%q, %q) failed: %v", from, to, err)
+	}
+	want := m.expected(from, to)
+	if !equal(entries, want) {
+		t.Fatalf("Scan(%q, %q) = %v; want %v", from, to, entries, want)
+	}
+}
+
+func equal(a []Entry, b []Entry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Key != b[i].Key || !bytes.Equal(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package kvtest
+
+import (
+	"sort"
+	"testing"
+
+	"pgregory.net/rapid"
+)
+
+// mapStore is a correct store; with aliasing, it keeps the values passed to Put.
+type mapStore struct {
+	m        map[string][]byte
+	aliasing bool
+}
+
+func newMapStore(aliasing bool) *mapStore {
+	return &mapStore{m: map[string][]byte{}, aliasing: aliasing}
+}
+
+func (s *mapStore) Get(key string) ([]byte, bool, error) {
+	v, ok := s.m[key]
+	return v, ok, nil
+}
+
+func (s *mapStore) Put(key string, value []byte) error {
+	if !s.aliasing {
+		value = append([]byte(nil), value...)
+	}
+	s.m[key] = value
+	return nil
+}
+
+func (s *mapStore) Delete(key string) error {
+	delete(s.m, key)
+	return nil
+}
+
+func (s *mapStore) Scan(from string, to string) ([]Entry, error) {
+	var entries []Entry
+	for k, v := range s.m {
+		if k >= from && (to == "" || k < to) {
+			entries = append(entries, Entry{Key: k, Value: v})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
+type mapMachine struct {
+	Machine
+}
+
+func (m *mapMachine) Init(t *rapid.T) {
+	m.Setup(newMapStore(false))
+}
+
+// Reopen replaces the store with a copy of it.
+func (m *mapMachine) Reopen(t *rapid.T) {
+	s := newMapStore(false)
+	for _, e := range m.Model() {
+		_ = s.Put(e.Key, e.Value)
+	}
+	m.Setup(s)
+}
+
+type aliasingMachine struct {
+	Machine
+}
+
+func (m *aliasingMachine) Init(t *rapid.T) {
+	m.Setup(newMapStore(true))
+}
+
+func TestMachine(t *testing.T) {
+	rapid.Check(t, rapid.Run(&mapMachine{}))
+}
+
+func TestMachine_Aliasing(t *testing.T) {
+	t.Skip("expected failure")
+
+	rapid.Check(t, rapid.Run(&aliasingMachine{}))
+}
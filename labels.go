@@ -0,0 +1,99 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var (
+	callerLabels = sync.Map{} // pc -> label
+	sourceLines  = sync.Map{} // file name -> []string, nil if unreadable
+
+	// assignedVarRe matches the start of an assignment to a variable or a field,
+	// like "x := ", "var x int = ", "if x := " or "m.x = "
+	assignedVarRe = regexp.MustCompile(`^\s*(?:(?:var|if|for|switch)\s+)?([A-Za-z_][\w.]*)(?:\s+[^=]+?)?\s*:?=\s*[^=]`)
+
+	goKeywords = map[string]bool{
+		"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true,
+		"defer": true, "else": true, "fallthrough": true, "for": true, "func": true, "go": true,
+		"goto": true, "if": true, "import": true, "interface": true, "map": true, "package": true,
+		"range": true, "return": true, "select": true, "struct": true, "switch": true, "type": true,
+		"var": true, "_": true,
+	}
+)
+
+// subPath returns the path of the value labeled name, drawn while drawing
+// the value at the path.
+func subPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// elemPath returns the path of the element i of the value at the path,
+// or an empty string if paths are not used.
+func elemPath(path string, i int) string {
+	if path == "" {
+		return ""
+	}
+	return fmt.Sprintf("%v[%v]", path, i)
+}
+
+// callerLabel returns the label of a draw made without one, like "x@foo_test.go:12":
+// the name of the variable the drawn value is assigned to (if it can be deduced
+// from the source), and the location of the call skip frames above the caller.
+func callerLabel(skip int) string {
+	var pc [1]uintptr
+	if runtime.Callers(skip+2, pc[:]) == 0 {
+		return ""
+	}
+	if label, ok := callerLabels.Load(pc[0]); ok {
+		return label.(string)
+	}
+
+	frame, _ := runtime.CallersFrames(pc[:]).Next()
+	label := fmt.Sprintf("%v:%v", filepath.Base(frame.File), frame.Line)
+	if name := assignedVar(frame.File, frame.Line); name != "" {
+		label = name + "@" + label
+	}
+
+	callerLabels.Store(pc[0], label)
+	return label
+}
+
+// assignedVar returns the name of the variable assigned at the start
+// of the line of the file, or an empty string.
+func assignedVar(file string, line int) string {
+	lines, ok := sourceLines.Load(file)
+	if !ok {
+		b, err := ioutil.ReadFile(file)
+		if err == nil {
+			lines = strings.Split(string(b), "\n")
+		} else {
+			lines = []string(nil)
+		}
+		sourceLines.Store(file, lines)
+	}
+
+	src := lines.([]string)
+	if line < 1 || line > len(src) {
+		return ""
+	}
+	m := assignedVarRe.FindStringSubmatch(src[line-1])
+	if m == nil || goKeywords[m[1]] {
+		return ""
+	}
+	return m[1]
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAssignedVarRe(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`	n := Int().Draw(t, "").(int)`, "n"},
+		{`	var n int = Int().Draw(t, "").(int)`, "n"},
+		{`	m.state = Int().Draw(t, "").(int)`, "m.state"},
+		{`	if ok := Bool().Draw(t, "").(bool); ok {`, "ok"},
+		{`	return Int().Draw(t, "").(int) != 0`, ""},
+		{`	if Int().Draw(t, "").(int) == 0 {`, ""},
+		{`	a, b := Int().Draw(t, ""), 1`, ""},
+		{`	_ = Int().Draw(t, "")`, ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if m := assignedVarRe.FindStringSubmatch(tt.line); m != nil && !goKeywords[m[1]] {
+			got = m[1]
+		}
+		if got != tt.want {
+			t.Errorf("got variable %q instead of %q in %q", got, tt.want, tt.line)
+		}
+	}
+}
+
+func TestCallerLabel(t *testing.T) {
+	t.Parallel()
+
+	var labels []string
+	Check(t, func(t *T) {
+		t.topDraws = []drawnValue{}
+		n := Int().Draw(t, "").(int)
+		_ = IntRange(0, n*0).Draw(t, "")
+		labels = []string{t.topDraws[0].label, t.topDraws[1].label}
+	})
+
+	re := regexp.MustCompile(`^n@labels_test\.go:\d+$`)
+	if !re.MatchString(labels[0]) {
+		t.Errorf("got label %q for an assigned draw", labels[0])
+	}
+	re = regexp.MustCompile(`^labels_test\.go:\d+$`)
+	if !re.MatchString(labels[1]) {
+		t.Errorf("got label %q for an unassigned draw", labels[1])
+	}
+}
+
+func TestLabelPaths(t *testing.T) {
+	type user struct {
+		Name string
+		Zip  int
+	}
+	userGen := Custom(func(t *T) user {
+		return user{
+			Name: StringMatching("[a-z]+").Draw(t, "name").(string),
+			Zip:  IntRange(0, 99999).Draw(t, "zip").(int),
+		}
+	})
+
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		users := SliceOfN(userGen, 3, 3).Draw(t, "users").([]user)
+		_ = MapOfN(Int(), userGen, 1, 1).Draw(t, "byID")
+		if users[2].Zip > 0 {
+			t.Fatal("non-zero zip")
+		}
+	})
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	for _, s := range []string{"draw users[0].name: ", "draw users[2].zip: 1\n", "draw users: ", "draw byID[0].value.zip: 0\n"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in output:\n%v", s, out)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+//go:build go1.16
+// +build go1.16
+
+// Package memfs implements an in-memory filesystem with faults drawn by rapid,
+// as a building block for testing storage layers and crash recovery code.
+//
+// Writes can be short or fail with ENOSPC, and Crash simulates a power loss:
+// everything written since the last Sync may be lost, partially or entirely.
+// Synced returns the model of what is guaranteed to survive a crash.
+// All decisions are drawn from the test case data, so failing test cases are
+// reproducible and are minimized towards as few faults as possible.
+//
+// The namespace is flat: file names can not contain slashes.
+package memfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"pgregory.net/rapid"
+)
+
+// writeFault is the outcome of a write.
+type writeFault int
+
+const (
+	writeOK writeFault = iota
+	writeShort
+	writeNoSpace
+)
+
+func (f writeFault) String() string {
+	switch f {
+	case writeOK:
+		return "ok"
+	case writeShort:
+		return "short"
+	case writeNoSpace:
+		return "ENOSPC"
+	default:
+		return fmt.Sprintf("writeFault(%d)", int(f))
+	}
+}
+
+func (f writeFault) GoString() string {
+	return f.String()
+}
+
+// writeFaults lets most of the writes succeed, and shrinks towards successful writes.
+var writeFaults = rapid.Custom(func(t *rapid.T) writeFault {
+	switch rapid.IntRange(0, 19).Draw(t, "n").(int) {
+	case 18:
+		return writeShort
+	case 19:
+		return writeNoSpace
+	default:
+		return writeOK
+	}
+})
+
+type inode struct {
+	data    []byte
+	durable []byte // contents as of the last Sync
+}
+
+// metaOp is a namespace change which has not been synced yet.
+type metaOp struct {
+	name string
+	to   string // for renames
+	ino  *inode // nil for removals
+}
+
+// FS is an in-memory filesystem. It implements fs.FS and fs.ReadFileFS,
+// and is not safe for concurrent use.
+type FS struct {
+	t        *rapid.T
+	capacity int
+	files    map[string]*inode // current namespace
+	durable  map[string]*inode // namespace as of the last Sync
+	log      []metaOp          // namespace changes since the last Sync
+}
+
+// New creates an empty filesystem, which can hold at most capacity bytes
+// (0 means no limit), and draws its faults from t.
+func New(t *rapid.T, capacity int) *FS {
+	return &FS{
+		t:        t,
+		capacity: capacity,
+		files:    map[string]*inode{},
+		durable:  map[string]*inode{},
+	}
+}
+
+func validName(name string) bool {
+	return fs.ValidPath(name) && name != "." && !strings.ContainsRune(name, '/')
+}
+
+func (fsys *FS) used() int {
+	n := 0
+	for _, ino := range fsys.files {
+		n += len(ino.data)
+	}
+	return n
+}
+
+// write appends data to ino, with a drawn fault.
+func (fsys *FS) write(op string, name string, ino *inode, data []byte) (int, error) {
+	switch writeFaults.Draw(fsys.t, op+" "+name).(writeFault) {
+	case writeShort:
+		n := rapid.IntRange(0, len(data)).Draw(fsys.t, "written").(int)
+		ino.data = append(ino.data, data[:n]...)
+		if n < len(data) {
+			return n, &fs.PathError{Op: op, Path: name, Err: io.ErrShortWrite}
+		}
+		return n, nil
+	case writeNoSpace:
+		return 0, &fs.PathError{Op: op, Path: name, Err: syscall.ENOSPC}
+	}
+
+	if fsys.capacity > 0 && fsys.used()+len(data) > fsys.capacity {
+		return 0, &fs.PathError{Op: op, Path: name, Err: syscall.ENOSPC}
+	}
+	ino.data = append(ino.data, data...)
+	return len(data), nil
+}
+
+// WriteFile creates the file (or truncates the existing one) and writes data to it.
+func (fsys *FS) WriteFile(name string, data []byte) (int, error) {
+	if !validName(name) {
+		return 0, &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+
+	ino, ok := fsys.files[name]
+	if ok {
+		ino.data = nil
+	} else {
+		ino = &inode{}
+		fsys.files[name] = ino
+		fsys.log = append(fsys.log, metaOp{name: name, ino: ino})
+	}
+
+	return fsys.write("write", name, ino, data)
+}
+
+// Append writes data to the end of the file, which should exist.
+func (fsys *FS) Append(name string, data []byte) (int, error) {
+	ino, ok := fsys.files[name]
+	if !ok {
+		return 0, &fs.PathError{Op: "append", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return fsys.write("append", name, ino, data)
+}
+
+// Rename atomically renames the file, replacing newname if it exists.
+func (fsys *FS) Rename(oldname string, newname string) error {
+	ino, ok := fsys.files[oldname]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrNotExist}
+	}
+	if !validName(newname) {
+		return &fs.PathError{Op: "rename", Path: newname, Err: fs.ErrInvalid}
+	}
+
+	delete(fsys.files, oldname)
+	fsys.files[newname] = ino
+	fsys.log = append(fsys.log, metaOp{name: oldname, to: newname, ino: ino})
+	return nil
+}
+
+// Remove removes the file.
+func (fsys *FS) Remove(name string) error {
+	if _, ok := fsys.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+
+	delete(fsys.files, name)
+	fsys.log = append(fsys.log, metaOp{name: name})
+	return nil
+}
+
+// Sync makes the contents of the file, together with all the namespace
+// changes (creations, renames and removals) made so far, durable.
+func (fsys *FS) Sync(name string) error {
+	ino, ok := fsys.files[name]
+	if !ok {
+		return &fs.PathError{Op: "sync", Path: name, Err: fs.ErrNotExist}
+	}
+
+	ino.durable = append([]byte(nil), ino.data...)
+
+	fsys.durable = map[string]*inode{}
+	for n, ino := range fsys.files {
+		fsys.durable[n] = ino
+	}
+	fsys.log = nil
+	return nil
+}
+
+// Synced returns the durable contents of the file, which are guaranteed to
+// survive a crash unless the file has been changed since it was synced.
+func (fsys *FS) Synced(name string) ([]byte, bool) {
+	ino, ok := fsys.durable[name]
+	if !ok {
+		return nil, false
+	}
+
+	return append([]byte(nil), ino.durable...), true
+}
+
+// Crash simulates a power loss. Namespace changes made since the last Sync
+// survive in order up to a drawn point, and each file keeps its durable
+// contents, followed by a drawn prefix of the data appended since (or, when
+// the file has been rewritten, either the old or the new contents).
+func (fsys *FS) Crash() {
+	files := map[string]*inode{}
+	for n, ino := range fsys.durable {
+		files[n] = ino
+	}
+
+	applied := rapid.IntRange(0, len(fsys.log)).Draw(fsys.t, "changes survived").(int)
+	for _, op := range fsys.log[:applied] {
+		switch {
+		case op.ino == nil:
+			delete(files, op.name)
+		case op.to != "":
+			delete(files, op.name)
+			files[op.to] = op.ino
+		default:
+			files[op.name] = op.ino
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	seen := map[*inode]bool{}
+	for _, n := range names {
+		ino := files[n]
+		if seen[ino] {
+			continue
+		}
+		seen[ino] = true
+
+		if bytes.HasPrefix(ino.data, ino.durable) {
+			k := rapid.IntRange(len(ino.durable), len(ino.data)).Draw(fsys.t, "survived "+n).(int)
+			ino.data = ino.data[:k]
+		} else if !rapid.Bool().Draw(fsys.t, "rewrite survived "+n).(bool) {
+			ino.data = append([]byte(nil), ino.durable...)
+		}
+		ino.durable = append([]byte(nil), ino.data...)
+	}
+
+	fsys.files = files
+	fsys.durable = map[string]*inode{}
+	for n, ino := range files {
+		fsys.durable[n] = ino
+	}
+	fsys.log = nil
+}
+
+// ReadFile returns the current contents of the file.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	ino, ok := fsys.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return append([]byte(nil), ino.data...), nil
+}
+
+// Open opens the file (or the root directory ".") for reading.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &dir{fsys: fsys}, nil
+	}
+	ino, ok := fsys.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return &file{Reader: bytes.NewReader(ino.data), info: fileInfo{name: name, size: len(ino.data)}}, nil
+}
+
+type fileInfo struct {
+	name string
+	size int
+	dir  bool
+}
+
+func (fi fileInfo) Name() string               { return fi.name }
+func (fi fileInfo) Size() int64                { return int64(fi.size) }
+func (fi fileInfo) ModTime() time.Time         { return time.Time{} }
+func (fi fileInfo) IsDir() bool                { return fi.dir }
+func (fi fileInfo) Sys() interface{}           { return nil }
+func (fi fileInfo) Type() fs.FileMode          { return fi.Mode().Type() }
+func (fi fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type file struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
+
+type dir struct {
+	fsys    *FS
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return fileInfo{name: ".", dir: true}, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: fs.ErrInvalid}
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		for name, ino := range d.fsys.files {
+			d.entries = append(d.entries, fileInfo{name: name, size: len(ino.data)})
+		}
+		sort.Slice(d.entries, func(i, j int) bool { return d.entries[i].Name() < d.entries[j].Name() })
+		d.read = true
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
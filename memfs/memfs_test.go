@@ -0,0 +1,86 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+//go:build go1.16
+// +build go1.16
+
+package memfs
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"pgregory.net/rapid"
+)
+
+func TestFS_Conformance(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		fsys := New(t, 0)
+		names := rapid.SliceOfDistinct(rapid.SampledFrom([]string{"a", "b", "c.txt", "d"}), nil).Draw(t, "names").([]string)
+		for _, name := range names {
+			_, _ = fsys.WriteFile(name, []byte(name))
+		}
+		if err := fstest.TestFS(fsys, names...); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestFS_CrashKeepsSyncedAppends(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		fsys := New(t, 64)
+		if _, err := fsys.WriteFile("wal", nil); err != nil {
+			t.Skip(err)
+		}
+		_ = fsys.Sync("wal")
+
+		var acked []byte
+		records := rapid.SliceOf(rapid.SliceOfN(rapid.Byte(), 1, 8)).Draw(t, "records").([][]byte)
+		for _, r := range records {
+			if _, err := fsys.Append("wal", r); err != nil {
+				break
+			}
+			_ = fsys.Sync("wal")
+			acked = append(acked, r...)
+		}
+		if synced, _ := fsys.Synced("wal"); !bytes.Equal(synced, acked) {
+			t.Fatalf("synced %q instead of %q", synced, acked)
+		}
+
+		fsys.Crash()
+		data, err := fsys.ReadFile("wal")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, acked) {
+			t.Fatalf("got %q after crash, lost acknowledged %q", data, acked)
+		}
+	})
+}
+
+func TestFS_UnsyncedRenameCanBeLost(t *testing.T) {
+	// replacing a file by renaming a temporary one, which has not been synced
+	gen := rapid.Custom(func(t *rapid.T) string {
+		fsys := New(t, 0)
+		_, _ = fsys.WriteFile("config", []byte("old"))
+		_ = fsys.Sync("config")
+		_, _ = fsys.WriteFile("config.tmp", []byte("new"))
+		_ = fsys.Rename("config.tmp", "config")
+		fsys.Crash()
+
+		data, err := fsys.ReadFile("config")
+		return fmt.Sprintf("%q %v", data, err)
+	})
+
+	for seed := 0; seed < 1000; seed++ {
+		if s := gen.Example(seed).(string); s == `"" <nil>` {
+			return
+		}
+	}
+	t.Fatal("crash has never left the replaced file empty")
+}
@@ -0,0 +1,269 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package memnet implements an in-memory network connection with faults
+// drawn by rapid, to test protocol implementations against an adversarial,
+// but reproducible, network.
+//
+// The fate of every Write is drawn from the test case data: the data can be
+// delayed (and then overtaken by later writes), corrupted, or lost in a network
+// partition. To keep the test cases reproducible, writes to both ends of
+// a pipe should happen in a deterministic order (e.g. from a single goroutine);
+// reads draw nothing, and can happen from any goroutine.
+package memnet
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"pgregory.net/rapid"
+)
+
+// Fault is a kind of network misbehavior.
+type Fault int
+
+const (
+	Delay     Fault = iota // data is delivered after the data of up to 3 later writes
+	Corrupt                // a byte of the data is changed
+	Partition              // write (and up to 4 writes after it) fails with a timeout, and the data is lost
+)
+
+func (f Fault) String() string {
+	switch f {
+	case Delay:
+		return "delay"
+	case Corrupt:
+		return "corrupt"
+	case Partition:
+		return "partition"
+	default:
+		return fmt.Sprintf("Fault(%d)", int(f))
+	}
+}
+
+// fate is what happens to the data of a single write.
+type fate struct {
+	fault  Fault
+	ok     bool
+	delay  int
+	offset int
+	mask   byte
+	outage int
+}
+
+func (f fate) GoString() string {
+	switch {
+	case f.ok:
+		return "deliver"
+	case f.fault == Delay:
+		return fmt.Sprintf("delay by %v writes", f.delay)
+	case f.fault == Corrupt:
+		return fmt.Sprintf("corrupt byte %v (xor %#x)", f.offset, f.mask)
+	default:
+		return fmt.Sprintf("partition for %v writes", f.outage)
+	}
+}
+
+// fates delivers the data of most of the writes, and shrinks towards delivering all of them.
+func fates(faults []Fault, n int) *rapid.Generator {
+	return rapid.Custom(func(t *rapid.T) fate {
+		if rapid.IntRange(0, 9).Draw(t, "n").(int) < 8 {
+			return fate{ok: true}
+		}
+
+		f := fate{fault: faults[rapid.IntRange(0, len(faults)-1).Draw(t, "fault").(int)]}
+		switch f.fault {
+		case Delay:
+			f.delay = rapid.IntRange(1, 3).Draw(t, "delay").(int)
+		case Corrupt:
+			if n == 0 {
+				return fate{ok: true}
+			}
+			f.offset = rapid.IntRange(0, n-1).Draw(t, "offset").(int)
+			f.mask = rapid.Uint8Min(1).Draw(t, "mask").(byte)
+		case Partition:
+			f.outage = rapid.IntRange(1, 5).Draw(t, "outage").(int)
+		}
+		return f
+	})
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+type addr string
+
+func (a addr) Network() string { return "memnet" }
+func (a addr) String() string  { return string(a) }
+
+type chunk struct {
+	data []byte
+	due  int // number of writes after which the chunk is delivered
+	seq  int
+}
+
+// link is one direction of a pipe.
+type link struct {
+	name   string
+	queue  []chunk
+	writes int
+	outage int // number of writes which still fail because of a partition
+	closed bool
+}
+
+// pop removes the data due first, and returns at most n bytes of it.
+func (l *link) pop(n int) []byte {
+	j := 0
+	for i, c := range l.queue {
+		if c.due < l.queue[j].due || (c.due == l.queue[j].due && c.seq < l.queue[j].seq) {
+			j = i
+		}
+	}
+
+	c := &l.queue[j]
+	if n >= len(c.data) {
+		data := c.data
+		l.queue = append(l.queue[:j], l.queue[j+1:]...)
+		return data
+	}
+	data := c.data[:n]
+	c.data = c.data[n:]
+	return data
+}
+
+type pipe struct {
+	t      *rapid.T
+	faults []Fault
+	mu     sync.Mutex
+	cond   *sync.Cond
+}
+
+// Pipe creates a pair of connected in-memory connections, which inject
+// the given faults (none by default) into the data written to them.
+func Pipe(t *rapid.T, faults ...Fault) (net.Conn, net.Conn) {
+	p := &pipe{t: t, faults: faults}
+	p.cond = sync.NewCond(&p.mu)
+
+	ab := &link{name: "a->b"}
+	ba := &link{name: "b->a"}
+	return &conn{p: p, local: "a", remote: "b", in: ba, out: ab},
+		&conn{p: p, local: "b", remote: "a", in: ab, out: ba}
+}
+
+type conn struct {
+	p             *pipe
+	local         addr
+	remote        addr
+	in            *link
+	out           *link
+	readDeadline  time.Time
+	deadlineTimer *time.Timer
+}
+
+func (c *conn) LocalAddr() net.Addr  { return c.local }
+func (c *conn) RemoteAddr() net.Addr { return c.remote }
+
+func (c *conn) Read(b []byte) (int, error) {
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+
+	for {
+		switch {
+		case c.out.closed:
+			return 0, io.ErrClosedPipe
+		case !c.readDeadline.IsZero() && !time.Now().Before(c.readDeadline):
+			return 0, timeoutError{}
+		case len(c.in.queue) > 0:
+			return copy(b, c.in.pop(len(b))), nil
+		case c.in.closed:
+			return 0, io.EOF
+		}
+		c.p.cond.Wait()
+	}
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+
+	l := c.out
+	if l.closed {
+		return 0, io.ErrClosedPipe
+	}
+	l.writes++
+	if l.outage > 0 {
+		l.outage--
+		return 0, timeoutError{}
+	}
+
+	f := fate{ok: true}
+	if len(c.p.faults) > 0 {
+		f = fates(c.p.faults, len(b)).Draw(c.p.t, l.name).(fate)
+	}
+	data := append([]byte(nil), b...)
+	switch {
+	case f.ok:
+	case f.fault == Corrupt:
+		data[f.offset] ^= f.mask
+	case f.fault == Partition:
+		l.outage = f.outage - 1
+		return 0, timeoutError{}
+	}
+
+	l.queue = append(l.queue, chunk{data: data, due: l.writes + f.delay, seq: l.writes})
+	c.p.cond.Broadcast()
+	return len(b), nil
+}
+
+// Close closes the connection; the data already written can still be read by the peer.
+func (c *conn) Close() error {
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+
+	if c.out.closed {
+		return io.ErrClosedPipe
+	}
+	c.out.closed = true
+	if c.deadlineTimer != nil {
+		c.deadlineTimer.Stop()
+	}
+	c.p.cond.Broadcast()
+	return nil
+}
+
+func (c *conn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+
+	c.readDeadline = t
+	if c.deadlineTimer != nil {
+		c.deadlineTimer.Stop()
+		c.deadlineTimer = nil
+	}
+	if !t.IsZero() {
+		c.deadlineTimer = time.AfterFunc(time.Until(t), func() {
+			c.p.mu.Lock()
+			defer c.p.mu.Unlock()
+			c.p.cond.Broadcast()
+		})
+	}
+	return nil
+}
+
+// SetWriteDeadline does nothing, since writes never block.
+func (c *conn) SetWriteDeadline(time.Time) error {
+	return nil
+}
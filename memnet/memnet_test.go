@@ -0,0 +1,114 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package memnet
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+)
+
+// exchange writes the messages to a, closes it, and returns what b has read, message by message.
+func exchange(t *rapid.T, a net.Conn, b net.Conn, msgs [][]byte) ([]string, []error) {
+	var errs []error
+	for _, m := range msgs {
+		if _, err := a.Write(m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	_ = a.Close()
+
+	var got []string
+	buf := make([]byte, 64)
+	for {
+		n, err := b.Read(buf)
+		if err == io.EOF {
+			return got, errs
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(buf[:n]))
+	}
+}
+
+func messages(t *rapid.T) [][]byte {
+	n := rapid.IntRange(0, 10).Draw(t, "n").(int)
+	msgs := make([][]byte, n)
+	for i := range msgs {
+		msgs[i] = []byte{byte('a' + i)}
+	}
+	return msgs
+}
+
+func TestPipe_Reliable(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		a, b := Pipe(t)
+		msgs := messages(t)
+		got, errs := exchange(t, a, b, msgs)
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		if strings.Join(got, "") != string(bytes.Join(msgs, nil)) {
+			t.Fatalf("got %q", got)
+		}
+	})
+}
+
+func TestPipe_DelayReorders(t *testing.T) {
+	reordered := false
+	rapid.Check(t, func(t *rapid.T) {
+		a, b := Pipe(t, Delay)
+		msgs := messages(t)
+		got, _ := exchange(t, a, b, msgs)
+		if strings.Join(got, "") != string(bytes.Join(msgs, nil)) {
+			reordered = true
+		}
+		sort.Strings(got)
+		if strings.Join(got, "") != string(bytes.Join(msgs, nil)) {
+			t.Fatalf("got %q", got)
+		}
+	})
+	if !reordered {
+		t.Error("messages were never reordered")
+	}
+}
+
+func TestPipe_PartitionLosesWrites(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		a, b := Pipe(t, Partition, Corrupt)
+		msgs := messages(t)
+		got, errs := exchange(t, a, b, msgs)
+		for _, err := range errs {
+			if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+				t.Fatalf("unexpected error %v", err)
+			}
+		}
+		if len(got)+len(errs) != len(msgs) {
+			t.Fatalf("%v messages received and %v failed out of %v", len(got), len(errs), len(msgs))
+		}
+	})
+}
+
+func TestPipe_ReadDeadline(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		a, b := Pipe(t)
+		_ = b.SetReadDeadline(time.Now().Add(time.Millisecond))
+		_, err := ioutil.ReadAll(b)
+		if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+			t.Fatalf("got %v instead of a timeout", err)
+		}
+		_ = a.Close()
+	})
+}
@@ -0,0 +1,40 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import "strings"
+
+// PropertyMetadata describes the property being checked, to trace it back
+// to the specification (see Metadata).
+type PropertyMetadata struct {
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	Link        string `json:"link,omitempty"` // e.g. of the issue, or the requirement
+}
+
+func (m PropertyMetadata) empty() bool {
+	return m == PropertyMetadata{}
+}
+
+func (m PropertyMetadata) String() string {
+	var details []string
+	if m.Owner != "" {
+		details = append(details, "owner: "+m.Owner)
+	}
+	if m.Link != "" {
+		details = append(details, "see "+m.Link)
+	}
+
+	s := m.Description
+	if len(details) > 0 {
+		if s != "" {
+			s += " "
+		}
+		s += "(" + strings.Join(details, ", ") + ")"
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPropertyMetadata_String(t *testing.T) {
+	for _, tt := range []struct {
+		m    PropertyMetadata
+		want string
+	}{
+		{PropertyMetadata{}, ""},
+		{PropertyMetadata{Description: "sorted"}, "sorted"},
+		{PropertyMetadata{Owner: "storage"}, "(owner: storage)"},
+		{PropertyMetadata{Description: "sorted", Owner: "storage", Link: "https://example.com/1"}, "sorted (owner: storage, see https://example.com/1)"},
+	} {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("%#v: got %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rapid-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	m := PropertyMetadata{Description: "n is small", Owner: "storage", Link: "https://example.com/1"}
+	var c Counterexample
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		if IntRange(0, 100).Draw(t, "n").(int) > 10 {
+			t.Fatalf("n is big")
+		}
+	}, Metadata(m), ExportCounterexamples(dir), HTMLReport(filepath.Join(dir, "report.html")), OnCounterexample(func(ce Counterexample) { c = ce }))
+	removeFailFiles(t.Name())
+
+	if out := tb.out.String(); !strings.Contains(out, "[rapid] property: n is small (owner: storage, see https://example.com/1)") {
+		t.Errorf("metadata not found in output:\n%v", out)
+	}
+	if c.Metadata != m {
+		t.Errorf("got counterexample metadata %#v", c.Metadata)
+	}
+	for filename, s := range map[string]string{
+		counterexampleFileName(t.Name()): `"owner": "storage"`,
+		"report.html":                    `<p>See <a href="https://example.com/1">https://example.com/1</a></p>`,
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), s) {
+			t.Errorf("%q not found in %v:\n%s", s, filename, b)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+// Minimize returns the simplest value produced by g which satisfies cond:
+// the value rapid would report for a property which fails for the values
+// satisfying cond. It returns false if none of the values generated from the
+// seed satisfies cond. The same seed gives the same value. Minimize is useful
+// to test how the values of Custom generators shrink (see the rapidtest package).
+func Minimize(g *Generator, seed int, cond func(interface{}) bool, opts ...Option) (interface{}, bool) {
+	var (
+		tb   = &nopTB{name: "Minimize"}
+		cfg  = newSettings(opts)
+		prop = func(t *T) {
+			if cond(g.Draw(t, "value")) {
+				t.Fatalf("condition satisfied")
+			}
+		}
+	)
+
+	caseSeed, buf, _, _, err := findBug(tb, cfg, &corpus{}, uint64(seed), prop)
+	if err == nil {
+		return nil, false
+	}
+
+	var (
+		s   bitStream
+		rec *recordedBits
+	)
+	if buf == nil {
+		r := newRandomBitStream(caseSeed, true)
+		s, rec = r, &r.recordedBits
+	} else {
+		b := newBufBitStream(buf, true)
+		s, rec = b, &b.recordedBits
+	}
+	if err = checkOnce(newT(tb, s, false, nil), prop); err == nil {
+		return nil, false // cond is not deterministic
+	}
+
+	buf, _ = shrink(tb, cfg, *rec, err, prop)
+	draws, _ := topLevelDraws(tb, prop, buf)
+	if len(draws) == 0 {
+		return nil, false
+	}
+	return draws[0].v, true
+}
+
+// nopTB discards the output of the checks which are not run by a test.
+type nopTB struct {
+	name string
+}
+
+func (tb *nopTB) Helper()                                   {}
+func (tb *nopTB) Name() string                              { return tb.name }
+func (tb *nopTB) Logf(format string, args ...interface{})   {}
+func (tb *nopTB) Log(args ...interface{})                   {}
+func (tb *nopTB) Errorf(format string, args ...interface{}) {}
+func (tb *nopTB) Error(args ...interface{})                 {}
+func (tb *nopTB) Fatalf(format string, args ...interface{}) {}
+func (tb *nopTB) Fatal(args ...interface{})                 {}
+func (tb *nopTB) FailNow()                                  {}
+func (tb *nopTB) Fail()                                     {}
+func (tb *nopTB) Failed() bool                              { return false }
@@ -0,0 +1,31 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMinimize(t *testing.T) {
+	t.Parallel()
+
+	v, ok := Minimize(IntRange(0, 1000), 1, func(v interface{}) bool { return v.(int) >= 100 })
+	if !ok || v != 100 {
+		t.Errorf("got %v (%v), want 100", v, ok)
+	}
+
+	v, ok = Minimize(SliceOf(Int()), 1, func(v interface{}) bool { return len(v.([]int)) >= 3 })
+	if !ok || !reflect.DeepEqual(v, []int{0, 0, 0}) {
+		t.Errorf("got %v (%v), want [0 0 0]", v, ok)
+	}
+
+	v, ok = Minimize(Bool(), 1, func(v interface{}) bool { return false })
+	if ok {
+		t.Errorf("got %v for an unsatisfiable condition", v)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	outcomeLabel     = "outcome"
+	scheduleMaxLen   = 20
+	scheduleFailProb = 0.25
+)
+
+var (
+	errorScheduleType = reflect.TypeOf(&ErrorSchedule{})
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// ErrorSchedule is a sequence of outcomes of the calls to a dependency:
+// nil for success, or an error. Calls after the end of the schedule succeed.
+// It is safe for concurrent use.
+type ErrorSchedule struct {
+	mu       sync.Mutex
+	outcomes []error
+	calls    int
+}
+
+// ErrorScheduleOf creates a generator of error schedules (of type *ErrorSchedule),
+// where a call can succeed, fail with one of errs, or time out
+// (fail with context.DeadlineExceeded). Schedules are minimized towards
+// fewer failing calls.
+func ErrorScheduleOf(errs ...error) *Generator {
+	for _, err := range errs {
+		assertf(err != nil, "scheduled errors should not be nil")
+	}
+
+	return newGenerator(&errorScheduleGen{
+		errs: append(append([]error(nil), errs...), context.DeadlineExceeded),
+	})
+}
+
+type errorScheduleGen struct {
+	errs []error
+}
+
+func (g *errorScheduleGen) String() string {
+	var errs []string
+	for _, err := range g.errs[:len(g.errs)-1] {
+		errs = append(errs, fmt.Sprintf("%q", err.Error()))
+	}
+	return fmt.Sprintf("ErrorScheduleOf(%v)", strings.Join(errs, ", "))
+}
+
+func (g *errorScheduleGen) type_() reflect.Type {
+	return errorScheduleType
+}
+
+func (g *errorScheduleGen) value(t *T) value {
+	s := &ErrorSchedule{}
+
+	repeat := newRepeat(0, scheduleMaxLen, -1)
+	for repeat.more(t.s, outcomeLabel) {
+		var err error
+		if flipBiasedCoin(t.s, scheduleFailProb) {
+			err = g.errs[genIndex(t.s, len(g.errs), false)]
+		}
+		s.outcomes = append(s.outcomes, err)
+	}
+
+	return s
+}
+
+// Next returns the outcome of the next call.
+func (s *ErrorSchedule) Next() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.calls
+	s.calls++
+	if i < len(s.outcomes) {
+		return s.outcomes[i]
+	}
+	return nil
+}
+
+// Calls returns the number of calls made so far.
+func (s *ErrorSchedule) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls
+}
+
+// String describes the outcomes of the schedule.
+func (s *ErrorSchedule) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var outcomes []string
+	for _, err := range s.outcomes {
+		switch err {
+		case nil:
+			outcomes = append(outcomes, "ok")
+		case context.DeadlineExceeded:
+			outcomes = append(outcomes, "timeout")
+		default:
+			outcomes = append(outcomes, fmt.Sprintf("%q", err.Error()))
+		}
+	}
+	return fmt.Sprintf("ErrorSchedule[%v]", strings.Join(outcomes, " "))
+}
+
+// GoString describes the outcomes, to make the draws of schedules readable.
+func (s *ErrorSchedule) GoString() string {
+	return s.String()
+}
+
+// Wrap mocks a dependency: fn should be a function whose last result is an error,
+// and Wrap returns a function of the same type, which either fails according to
+// the schedule (returning zero values and the scheduled error), or calls fn.
+// For example, a method of an interface can be mocked like this:
+//
+//	type flakyStore struct {
+//	    Store
+//	    get func(key string) ([]byte, error)
+//	}
+//
+//	func (s *flakyStore) Get(key string) ([]byte, error) { return s.get(key) }
+//
+//	store := &flakyStore{Store: real}
+//	store.get = schedule.Wrap(real.Get).(func(string) ([]byte, error))
+func (s *ErrorSchedule) Wrap(fn interface{}) interface{} {
+	f := reflect.ValueOf(fn)
+	typ := f.Type()
+	assertf(typ.Kind() == reflect.Func, "should wrap a function, not %v", typ.Kind())
+	assertf(typ.NumOut() > 0 && typ.Out(typ.NumOut()-1) == errorType, "last result of %v should be an error", typ)
+
+	return reflect.MakeFunc(typ, func(args []reflect.Value) []reflect.Value {
+		err := s.Next()
+		if err == nil {
+			if typ.IsVariadic() {
+				return f.CallSlice(args)
+			}
+			return f.Call(args)
+		}
+
+		results := make([]reflect.Value, typ.NumOut())
+		for i := range results {
+			results[i] = reflect.Zero(typ.Out(i))
+		}
+		results[len(results)-1] = reflect.ValueOf(&err).Elem()
+		return results
+	}).Interface()
+}
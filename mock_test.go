@@ -0,0 +1,67 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// retry retries fn up to 3 times, but does not retry timeouts.
+func retry(fn func(string) (int, error), key string) (int, error) {
+	var err error
+	for i := 0; i < 3; i++ {
+		var n int
+		n, err = fn(key)
+		if err == nil || err == context.DeadlineExceeded {
+			return n, err
+		}
+	}
+	return 0, err
+}
+
+func TestErrorSchedule_Wrap(t *testing.T) {
+	t.Parallel()
+
+	s := &ErrorSchedule{outcomes: []error{nil, errUnavailable, context.DeadlineExceeded}}
+	get := s.Wrap(func(key string) (int, error) { return len(key), nil }).(func(string) (int, error))
+
+	for i, want := range []error{nil, errUnavailable, context.DeadlineExceeded, nil} {
+		n, err := get("abc")
+		if err != want || (err == nil && n != 3) || (err != nil && n != 0) {
+			t.Errorf("call %v: got %v, %v", i, n, err)
+		}
+	}
+	if s.Calls() != 4 {
+		t.Errorf("got %v calls instead of 4", s.Calls())
+	}
+}
+
+func TestErrorSchedule_MinimalCounterexample(t *testing.T) {
+	t.Parallel()
+
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		s := ErrorScheduleOf(errUnavailable).Draw(t, "schedule").(*ErrorSchedule)
+		get := s.Wrap(func(key string) (int, error) { return len(key), nil }).(func(string) (int, error))
+		if _, err := retry(get, "key"); err == context.DeadlineExceeded {
+			t.Fatal("timeout not retried")
+		}
+	})
+	removeFailFiles(t.Name())
+
+	if !tb.failed {
+		t.Fatal("check did not fail")
+	}
+	if line := "[rapid] draw schedule: ErrorSchedule[timeout]\n"; !strings.Contains(tb.out.String(), line) {
+		t.Errorf("%q not found in output:\n%v", line, tb.out.String())
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	parallelLabel     = "parallel"
+	parallelThreads   = 2
+	parallelMaxPrefix = 10
+	parallelMaxSuffix = 5
+)
+
+// Operation is a single operation of a parallel state machine test.
+type Operation struct {
+	// Name describes the operation (including its arguments) in the test output.
+	Name string
+	// Run applies the operation to the system under test and returns the result.
+	// Operations of different goroutines run concurrently.
+	Run func() interface{}
+	// Next applies the operation to the model state (without modifying it),
+	// returning the new state and whether result is allowed in state.
+	Next func(state interface{}, result interface{}) (interface{}, bool)
+}
+
+// ParallelStateMachine describes the system under test of RunParallel together
+// with its sequential model: the operations act on the system, while the
+// model state (returned by Init and Operation.Next) is only used to check
+// whether their results are allowed.
+type ParallelStateMachine interface {
+	// Init is ran at the beginning of each test case to create the system
+	// under test, and returns the initial state of the model.
+	//
+	// Cleanup(), if present, is called at the end of each test case.
+	Init(*T) interface{}
+	// Operation generates a random operation. Since operations are generated
+	// before any of them is run, they can not depend on the model state.
+	Operation(*T) Operation
+}
+
+// RunParallel is a convenience function for defining "parallel state machine" tests,
+// to be run by Check or MakeCheck.
+//
+// Each test case generates a sequence of operations, run one after another,
+// followed by several sequences run concurrently, one per goroutine.
+// Test case fails unless there is an order of the concurrent operations
+// (keeping the order of each goroutine, and the order of the operations
+// which have not overlapped in time) in which every result is allowed by the model,
+// i.e. unless the system under test behaves as if it were linearizable.
+//
+// Like with Run, for each test case new state machine instance is created
+// via reflection from the type of m, which must be a pointer.
+func RunParallel(m ParallelStateMachine) func(*T) {
+	typ := reflect.TypeOf(m)
+	assertf(typ.Kind() == reflect.Ptr, "state machine type should be a pointer, not %v", typ.Kind())
+
+	return func(t *T) {
+		t.Helper()
+
+		sm := reflect.New(typ.Elem()).Interface().(ParallelStateMachine)
+		state := sm.Init(t)
+		t.failOnError()
+		if c, ok := sm.(interface{ Cleanup() }); ok {
+			defer c.Cleanup()
+		}
+
+		prefix := drawOperations(t, sm, parallelMaxPrefix)
+		suffixes := make([][]Operation, parallelThreads)
+		for i := range suffixes {
+			suffixes[i] = drawOperations(t, sm, parallelMaxSuffix)
+		}
+
+		h := runOperations(prefix, suffixes)
+		for _, c := range h.prefix {
+			next, ok := c.op.Next(state, c.result)
+			if !ok {
+				t.Fatalf("%v returned %v, which is not allowed by the model\n%v", c.op.Name, c.result, h)
+			}
+			state = next
+		}
+		if !linearizable(state, h.suffixes) {
+			t.Fatalf("no order of the concurrent operations is allowed by the model\n%v", h)
+		}
+	}
+}
+
+func drawOperations(t *T, sm ParallelStateMachine, maxCount int) []Operation {
+	var ops []Operation
+	repeat := newRepeat(0, maxCount, float64(maxCount)/2)
+	for repeat.more(t.s, parallelLabel) {
+		i := t.s.beginGroup(actionLabel, false)
+		ops = append(ops, sm.Operation(t))
+		t.s.endGroup(i, false)
+	}
+
+	return ops
+}
+
+// opCall is an operation which has been run, together with its result and
+// the (logical) times of its start and end.
+type opCall struct {
+	op     Operation
+	result interface{}
+	start  uint64
+	end    uint64
+}
+
+type history struct {
+	prefix   []opCall
+	suffixes [][]opCall
+}
+
+func (h history) String() string {
+	var b strings.Builder
+	b.WriteString("sequential:\n")
+	for _, c := range h.prefix {
+		fmt.Fprintf(&b, "  %v -> %v\n", c.op.Name, c.result)
+	}
+	for i, calls := range h.suffixes {
+		fmt.Fprintf(&b, "goroutine %v:\n", i+1)
+		for _, c := range calls {
+			fmt.Fprintf(&b, "  %v -> %v\n", c.op.Name, c.result)
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// runOperations runs the prefix, and then every suffix in its own goroutine.
+// Panics of the concurrent operations are re-raised in the calling goroutine.
+func runOperations(prefix []Operation, suffixes [][]Operation) history {
+	var clock uint64
+	run := func(ops []Operation) []opCall {
+		calls := make([]opCall, len(ops))
+		for i, op := range ops {
+			calls[i].op = op
+			calls[i].start = atomic.AddUint64(&clock, 1)
+			calls[i].result = op.Run()
+			calls[i].end = atomic.AddUint64(&clock, 1)
+		}
+		return calls
+	}
+
+	h := history{prefix: run(prefix), suffixes: make([][]opCall, len(suffixes))}
+
+	var (
+		wg     sync.WaitGroup
+		start  = make(chan struct{})
+		panics = make([]interface{}, len(suffixes))
+	)
+	for i := range suffixes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { panics[i] = recover() }()
+			<-start
+			h.suffixes[i] = run(suffixes[i])
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for _, p := range panics {
+		if p != nil {
+			panic(p)
+		}
+	}
+
+	return h
+}
+
+// linearizable reports whether the calls can be ordered (keeping the order of every
+// goroutine, and the order of non-overlapping calls) so that every result is allowed
+// by the model starting from state.
+func linearizable(state interface{}, calls [][]opCall) bool {
+	done := true
+	for i, cs := range calls {
+		if len(cs) == 0 {
+			continue
+		}
+		done = false
+
+		c := cs[0]
+		if !canGoFirst(c, calls) {
+			continue
+		}
+		next, ok := c.op.Next(state, c.result)
+		if !ok {
+			continue
+		}
+
+		rest := append([][]opCall(nil), calls...)
+		rest[i] = cs[1:]
+		if linearizable(next, rest) {
+			return true
+		}
+	}
+
+	return done
+}
+
+// canGoFirst reports whether no pending call has ended before c has started.
+func canGoFirst(c opCall, calls [][]opCall) bool {
+	for _, cs := range calls {
+		if len(cs) > 0 && cs[0].end < c.start {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+var atomicIncs = true
+
+type parallelCounterMachine struct {
+	n int64
+}
+
+func (m *parallelCounterMachine) Init(*T) interface{} {
+	return 0
+}
+
+func (m *parallelCounterMachine) Operation(t *T) Operation {
+	if Bool().Draw(t, "get").(bool) {
+		return Operation{
+			Name: "Get",
+			Run:  func() interface{} { return int(atomic.LoadInt64(&m.n)) },
+			Next: func(state interface{}, result interface{}) (interface{}, bool) {
+				return state, result == state
+			},
+		}
+	}
+
+	return Operation{
+		Name: "Inc",
+		Run: func() interface{} {
+			if atomicIncs {
+				atomic.AddInt64(&m.n, 1)
+			} else {
+				n := atomic.LoadInt64(&m.n)
+				runtime.Gosched()
+				atomic.StoreInt64(&m.n, n+1)
+			}
+			return nil
+		},
+		Next: func(state interface{}, _ interface{}) (interface{}, bool) {
+			return state.(int) + 1, true
+		},
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	_, _, _, _, err := findBug(t, &settings{checks: 100}, &corpus{}, baseSeed(), RunParallel(&parallelCounterMachine{}))
+	if err != nil {
+		t.Fatalf("linearizable counter has failed: %v", err)
+	}
+
+	atomicIncs = false
+	defer func() { atomicIncs = true }()
+	_, _, _, _, err = findBug(t, &settings{checks: 1000}, &corpus{}, baseSeed(), RunParallel(&parallelCounterMachine{}))
+	if err == nil {
+		t.Fatal("lost counter update not found")
+	}
+}
+
+func TestRunOperationsPanic(t *testing.T) {
+	t.Parallel()
+
+	var ran int64
+	ok := Operation{Name: "OK", Run: func() interface{} { return atomic.AddInt64(&ran, 1) }}
+	bad := Operation{Name: "Bad", Run: func() interface{} { panic("concurrent operation failed") }}
+
+	defer func() {
+		if r := recover(); r != "concurrent operation failed" {
+			t.Fatalf("got panic %v", r)
+		}
+		if n := atomic.LoadInt64(&ran); n != 3 {
+			t.Fatalf("%v operations have run instead of 3", n)
+		}
+	}()
+	runOperations([]Operation{ok}, [][]Operation{{ok, ok}, {bad}})
+	t.Fatal("panic of a concurrent operation was not re-raised")
+}
+
+func TestLinearizable(t *testing.T) {
+	t.Parallel()
+
+	set := Operation{Name: "Set", Next: func(_ interface{}, result interface{}) (interface{}, bool) { return result, true }}
+	get := Operation{Name: "Get", Next: func(state interface{}, result interface{}) (interface{}, bool) { return state, result == state }}
+
+	testCases := []struct {
+		calls [][]opCall
+		ok    bool
+	}{
+		{[][]opCall{{{op: set, result: 1, start: 1, end: 4}}, {{op: get, result: 1, start: 2, end: 3}}}, true},
+		{[][]opCall{{{op: set, result: 1, start: 1, end: 4}}, {{op: get, result: 0, start: 2, end: 3}}}, true},
+		{[][]opCall{{{op: set, result: 1, start: 1, end: 2}}, {{op: get, result: 0, start: 3, end: 4}}}, false},
+		{[][]opCall{{{op: get, result: 1, start: 1, end: 2}}, {{op: set, result: 1, start: 3, end: 4}}}, false},
+		{[][]opCall{{{op: get, result: 2, start: 1, end: 4}}, {{op: set, result: 1, start: 2, end: 3}}}, false},
+	}
+
+	for i, tc := range testCases {
+		if ok := linearizable(0, tc.calls); ok != tc.ok {
+			t.Errorf("test case %v: got %v instead of %v", i, ok, tc.ok)
+		}
+	}
+}
@@ -7,22 +7,34 @@
 package rapid
 
 import (
-	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 	"unicode"
+
+	"pgregory.net/rapid/internal/failfile"
 )
 
 const (
-	rapidVersion = "v0.4.6"
+	rapidVersion = "v0.4.7"
+
+	persistDirMode    = 0775
+	persistTmpPattern = ".rapid-tmp-*"
+
+	replayEnv         = "RAPID_REPLAY"
+	replayTokenFormat = 1
 
-	persistDirMode     = 0775
-	failfileTmpPattern = ".rapid-failfile-tmp-*"
+	fuzzCorpusHeader = "go test fuzz v1"
 )
 
 func kindaSafeFilename(f string) string {
@@ -37,93 +49,232 @@ func kindaSafeFilename(f string) string {
 	return s.String()
 }
 
+// failFileDir is the directory where fail files of the test are saved, and
+// where they are loaded from to be replayed before trying new test cases.
+func failFileDir(testName string) string {
+	return filepath.Join("testdata", "rapid", kindaSafeFilename(testName))
+}
+
 func failFileName(testName string) string {
+	return filepath.Join(failFileDir(testName), persistFileName(testName, "fail"))
+}
+
+// savedFailFiles returns the fail files saved for the test, newest first.
+func savedFailFiles(testName string) []string {
+	files, _ := filepath.Glob(filepath.Join(failFileDir(testName), "*.fail"))
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files
+}
+
+func counterexampleFileName(testName string) string {
+	return persistFileName(testName, "json")
+}
+
+func fuzzCorpusDir(fuzzName string) string {
+	return filepath.Join("testdata", "fuzz", fuzzName)
+}
+
+// saveFuzzCorpusEntry writes the input to the go fuzz corpus in dir, naming
+// the file (like go test does) after the hash of its contents.
+func saveFuzzCorpusEntry(dir string, input []byte) (string, error) {
+	data := []byte(fmt.Sprintf("%v\n[]byte(%q)\n", fuzzCorpusHeader, input))
+	filename := filepath.Join(dir, fmt.Sprintf("%x", sha256.Sum256(data))[:16])
+
+	return filename, writeFileAtomic(filename, data)
+}
+
+func persistFileName(testName string, ext string) string {
 	ts := time.Now().Format("20060102150405")
-	return fmt.Sprintf("%s-%s-%d.fail", kindaSafeFilename(testName), ts, os.Getpid())
+	return fmt.Sprintf("%s-%s-%d.%s", kindaSafeFilename(testName), ts, os.Getpid(), ext)
 }
 
-func saveFailFile(filename string, version string, output []byte, seed uint64, buf []uint64) error {
-	dir := filepath.Dir(filename)
-	err := os.MkdirAll(dir, persistDirMode)
+// failFile is a persisted failing test case, see package failfile for the format.
+type failFile struct {
+	version string // rapid version which has written the file
+	genHash uint64 // hash of the draws made by the failing test case (0 if unknown)
+	seed    uint64
+	buf     []uint64
+}
+
+// counterexample is the JSON representation of a failing test case.
+type counterexample struct {
+	Test      string               `json:"test"`
+	Version   string               `json:"version"`
+	Seed      uint64               `json:"seed"`
+	Error     string               `json:"error"`
+	Bitstream []uint64             `json:"bitstream"`
+	Draws     []counterexampleDraw `json:"draws"`
+	Metadata  *PropertyMetadata    `json:"metadata,omitempty"`
+}
+
+type counterexampleDraw struct {
+	Label string          `json:"label"`
+	Type  string          `json:"type"`
+	Repr  string          `json:"repr"`            // Go syntax representation
+	Value json.RawMessage `json:"value,omitempty"` // omitted for values without JSON representation, and sensitive ones
+}
+
+func makeCounterexample(tb tb, prop func(*T), seed uint64, buf []uint64) counterexample {
+	draws, err := topLevelDraws(tb, prop, buf)
+
+	ce := counterexample{
+		Test:      tb.Name(),
+		Version:   rapidVersion,
+		Seed:      seed,
+		Bitstream: buf,
+		Draws:     []counterexampleDraw{},
+	}
+	if ce.Bitstream == nil {
+		ce.Bitstream = []uint64{}
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create directory for fail file %q: %w", filename, err)
+		ce.Error = err.Error()
 	}
+	for _, d := range draws {
+		cd := counterexampleDraw{
+			Label: d.label,
+			Type:  fmt.Sprintf("%T", d.v),
+			Repr:  d.String(),
+		}
+		if b, err := json.Marshal(d.v); err == nil && !d.redacted() {
+			cd.Value = b
+		}
+		ce.Draws = append(ce.Draws, cd)
+	}
+
+	return ce
+}
 
-	f, err := ioutil.TempFile(dir, failfileTmpPattern)
+func saveCounterexample(filename string, ce counterexample) error {
+	b, err := json.MarshalIndent(ce, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to create temporary file for fail file %q: %w", filename, err)
+		return fmt.Errorf("failed to encode counterexample %q: %w", filename, err)
 	}
-	defer func() { _ = os.Remove(f.Name()) }()
-	defer func() { _ = f.Close() }()
 
-	out := strings.Split(string(output), "\n")
-	for _, s := range out {
-		_, err := f.WriteString("# " + s + "\n")
-		if err != nil {
-			return fmt.Errorf("failed to write data to fail file %q: %w", filename, err)
-		}
+	return writeFileAtomic(filename, append(b, '\n'))
+}
+
+func saveFailFile(filename string, output []byte, ff failFile) error {
+	return writeFileAtomic(filename, formatFailFile(output, ff))
+}
+
+// formatFailFile returns the contents of the fail file; output (if any) is
+// stored as comments.
+func formatFailFile(output []byte, ff failFile) []byte {
+	f := failfile.File{Version: ff.version, GenHash: ff.genHash, Seed: ff.seed, Buf: ff.buf}
+	if output != nil {
+		f.Output = strings.Split(string(output), "\n")
 	}
 
-	bs := []string{fmt.Sprintf("%v#%v", version, seed)}
-	for _, u := range buf {
-		bs = append(bs, fmt.Sprintf("0x%x", u))
+	return failfile.Encode(f)
+}
+
+// writeFileAtomic writes the file using a temporary file in the same directory,
+// so that incomplete files are never left behind.
+func writeFileAtomic(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+	err := os.MkdirAll(dir, persistDirMode)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for %q: %w", filename, err)
 	}
 
-	_, err = f.WriteString(strings.Join(bs, "\n"))
+	f, err := ioutil.TempFile(dir, persistTmpPattern)
 	if err != nil {
-		return fmt.Errorf("failed to write data to fail file %q: %w", filename, err)
+		return fmt.Errorf("failed to create temporary file for %q: %w", filename, err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	defer func() { _ = f.Close() }()
+
+	_, err = f.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to write data to %q: %w", filename, err)
 	}
 
 	_ = f.Close() // early close, otherwise os.Rename will fail on Windows
 	err = os.Rename(f.Name(), filename)
 	if err != nil {
-		return fmt.Errorf("failed to save fail file %q: %w", filename, err)
+		return fmt.Errorf("failed to save %q: %w", filename, err)
 	}
 
 	return nil
 }
 
-func loadFailFile(filename string) (string, uint64, []uint64, error) {
+func loadFailFile(filename string) (failFile, error) {
 	f, err := os.Open(filename)
 	if err != nil {
-		return "", 0, nil, fmt.Errorf("failed to open fail file: %w", err)
+		return failFile{}, fmt.Errorf("failed to open fail file: %w", err)
 	}
 	defer func() { _ = f.Close() }()
 
-	var data []string
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		s := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(s, "#") || s == "" {
-			continue
-		}
-		data = append(data, s)
-	}
-	if err := scanner.Err(); err != nil {
-		return "", 0, nil, fmt.Errorf("failed to load fail file %q: %w", filename, err)
+	ff, err := readFailFile(f)
+	if err != nil {
+		return failFile{}, fmt.Errorf("failed to load fail file %q: %w", filename, err)
 	}
 
-	if len(data) == 0 {
-		return "", 0, nil, fmt.Errorf("no data in fail file %q", filename)
+	return ff, nil
+}
+
+func readFailFile(r io.Reader) (failFile, error) {
+	f, err := failfile.Read(r)
+	if err != nil {
+		return failFile{}, err
 	}
 
-	split := strings.Split(data[0], "#")
-	if len(split) != 2 {
-		return "", 0, nil, fmt.Errorf("invalid version/seed field %q in %q", data[0], filename)
+	return failFile{version: f.Version, genHash: f.GenHash, seed: f.Seed, buf: f.Buf}, nil
+}
+
+// encodeReplayToken encodes the failing test case as a short string, which can
+// be passed back using the RAPID_REPLAY environment variable. The token contains
+// a hash of the test name, so that it only affects the test it was printed for.
+func encodeReplayToken(testName string, seed uint64, buf []uint64) string {
+	b := make([]byte, 0, binary.MaxVarintLen64*(len(buf)+4))
+	b = failfile.AppendUvarint(b, replayTokenFormat)
+	b = failfile.AppendUvarint(b, uint64(replayTokenTestHash(testName)))
+	b = failfile.AppendUvarint(b, seed)
+	b = failfile.AppendUvarint(b, uint64(len(buf)))
+	for _, u := range buf {
+		b = failfile.AppendUvarint(b, u)
 	}
-	seed, err := strconv.ParseUint(split[1], 10, 64)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeReplayToken(token string) (uint32, uint64, []uint64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
 	if err != nil {
-		return "", 0, nil, fmt.Errorf("invalid seed %q in %q", split[1], filename)
+		return 0, 0, nil, fmt.Errorf("invalid token encoding: %w", err)
 	}
 
-	var buf []uint64
-	for _, b := range data[1:] {
-		u, err := strconv.ParseUint(b, 0, 64)
+	var header [4]uint64
+	for i := range header {
+		header[i], err = failfile.ReadUvarint(&b)
 		if err != nil {
-			return "", 0, nil, fmt.Errorf("failed to load fail file %q: %w", filename, err)
+			return 0, 0, nil, err
 		}
-		buf = append(buf, u)
 	}
+	format, testHash, seed, n := header[0], header[1], header[2], header[3]
+	if format != replayTokenFormat {
+		return 0, 0, nil, fmt.Errorf("unsupported token format %v", format)
+	}
+	if n > uint64(len(b)) {
+		return 0, 0, nil, fmt.Errorf("invalid data length %v", n)
+	}
+	buf := make([]uint64, n)
+	for i := range buf {
+		buf[i], err = failfile.ReadUvarint(&b)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+	}
+	if len(b) != 0 {
+		return 0, 0, nil, fmt.Errorf("%v trailing token bytes", len(b))
+	}
+
+	return uint32(testHash), seed, buf, nil
+}
 
-	return split[0], seed, buf, nil
+func replayTokenTestHash(testName string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(testName))
+	return h.Sum32()
 }
@@ -7,41 +7,81 @@
 package rapid
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+
+	"pgregory.net/rapid/internal/failfile"
 )
 
+// tempFailFileName returns the name of a fail file in a temporary directory.
+func tempFailFileName(t *testing.T, testName string) string {
+	dir, err := ioutil.TempDir("", "rapid-failfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	return filepath.Join(dir, filepath.Base(failFileName(testName)))
+}
+
+// removeFailFiles removes the fail files saved for the test, together with
+// the testdata directories if they become empty.
+func removeFailFiles(testName string) {
+	dir := failFileDir(testName)
+	_ = os.RemoveAll(dir)
+	_ = os.Remove(filepath.Dir(dir))
+	_ = os.Remove(filepath.Dir(filepath.Dir(dir)))
+}
+
+// testFailFile returns the fail file of the test case, as saved by rapid.
+func testFailFile(tb tb, prop func(*T), seed uint64, buf []uint64) failFile {
+	_, genHash := captureTestOutput(tb, prop, buf)
+	return failFile{version: rapidVersion, genHash: genHash, seed: seed, buf: buf}
+}
+
 func TestFailFileRoundtrip(t *testing.T) {
 	t.Parallel()
 
+	dir := filepath.Dir(tempFailFileName(t, t.Name()))
 	Check(t, func(t *T) {
 		var (
 			// OS X seems to have issues with Go 1.16 and String(), reporting "illegal byte sequence" when trying to rename the file
 			testName = StringMatching(`[a-zA-Z0-9._-]+`).Draw(t, "testName").(string)
 			version  = StringMatching(`[a-zA-Z0-9._-]+`).Draw(t, "version").(string)
+			genHash  = Uint64().Draw(t, "genHash").(uint64)
 			seed     = Uint64().Draw(t, "seed").(uint64)
 			output   = SliceOf(Byte()).Draw(t, "output").([]byte)
 			buf      = SliceOf(Uint64()).Draw(t, "buf").([]uint64)
 		)
 
-		fileName := failFileName(testName)
-		err := saveFailFile(fileName, version, output, seed, buf)
+		fileName := filepath.Join(dir, filepath.Base(failFileName(testName)))
+		err := saveFailFile(fileName, output, failFile{version: version, genHash: genHash, seed: seed, buf: buf})
 		if err != nil {
 			t.Fatal(err)
 		}
 		defer func() { _ = os.Remove(fileName) }()
 
-		version2, seed2, buf2, err := loadFailFile(fileName)
+		ff, err := loadFailFile(fileName)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		if version2 != version {
-			t.Fatalf("got version %q instead of %q", version2, version)
+		if ff.version != version {
+			t.Fatalf("got version %q instead of %q", ff.version, version)
 		}
-		if seed2 != seed {
-			t.Fatalf("got seed %v instead of %v", seed2, seed)
+		if ff.genHash != genHash {
+			t.Fatalf("got generator hash %v instead of %v", ff.genHash, genHash)
 		}
+		if ff.seed != seed {
+			t.Fatalf("got seed %v instead of %v", ff.seed, seed)
+		}
+		buf2 := ff.buf
 		if len(buf2) != len(buf) {
 			t.Fatalf("got buf of length %v instead of %v", len(buf2), len(buf))
 		}
@@ -52,3 +92,173 @@ func TestFailFileRoundtrip(t *testing.T) {
 		}
 	})
 }
+
+func TestFailFileLegacyFormat(t *testing.T) {
+	t.Parallel()
+
+	fileName := tempFailFileName(t, t.Name())
+	err := ioutil.WriteFile(fileName, []byte("# output\nv0.4.6#42\n0x1\n0xff\n0x0"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ff, err := loadFailFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ff.version != "v0.4.6" || ff.genHash != 0 || ff.seed != 42 || fmt.Sprint(ff.buf) != "[1 255 0]" {
+		t.Fatalf("got %+v", ff)
+	}
+}
+
+func TestFailFileUnsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	fileName := tempFailFileName(t, t.Name())
+	err := ioutil.WriteFile(fileName, []byte(fmt.Sprintf("%v %v\nversion v0.4.6\n", failfile.Magic, failfile.FormatVersion+1)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = loadFailFile(fileName)
+	if err == nil || !strings.Contains(err.Error(), "unsupported format") {
+		t.Fatalf("got error %v instead of unsupported format", err)
+	}
+}
+
+func TestCounterexampleExport(t *testing.T) {
+	t.Parallel()
+
+	_, _, seed, buf, _, err := doCheck(t, newSettings(nil), baseSeed(), reproProp)
+	if err == nil {
+		t.Fatal("property did not fail")
+	}
+
+	dir, err1 := ioutil.TempDir("", "rapid-export")
+	if err1 != nil {
+		t.Fatal(err1)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	fileName := filepath.Join(dir, counterexampleFileName(t.Name()))
+	err1 = saveCounterexample(fileName, makeCounterexample(t, reproProp, seed, buf))
+	if err1 != nil {
+		t.Fatal(err1)
+	}
+
+	b, err1 := ioutil.ReadFile(fileName)
+	if err1 != nil {
+		t.Fatal(err1)
+	}
+	var ce map[string]interface{}
+	if err1 := json.Unmarshal(b, &ce); err1 != nil {
+		t.Fatal(err1)
+	}
+
+	draws := fmt.Sprint(ce["draws"])
+	want := "[map[label:x repr:11 type:int value:11] map[label:p repr:rapid.reproPair{A:0, B:0} type:rapid.reproPair value:map[A:0 B:0]]]"
+	if draws != want {
+		t.Fatalf("got draws %v instead of %v", draws, want)
+	}
+	if ce["error"] != err.Error() || ce["test"] != t.Name() || len(ce["bitstream"].([]interface{})) != len(buf) {
+		t.Fatalf("unexpected counterexample %v", ce)
+	}
+}
+
+func TestFuzzCorpusExport(t *testing.T) {
+	t.Parallel()
+
+	_, _, _, buf, _, err := doCheck(t, newSettings(nil), baseSeed(), reproProp)
+	if err == nil {
+		t.Fatal("property did not fail")
+	}
+
+	dir, err1 := ioutil.TempDir("", "rapid-fuzz")
+	if err1 != nil {
+		t.Fatal(err1)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	fileName, err1 := saveFuzzCorpusEntry(dir, wordsToBytes(buf))
+	if err1 != nil {
+		t.Fatal(err1)
+	}
+
+	b, err1 := ioutil.ReadFile(fileName)
+	if err1 != nil {
+		t.Fatal(err1)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 || lines[0] != fuzzCorpusHeader || !strings.HasPrefix(lines[1], "[]byte(") || !strings.HasSuffix(lines[1], ")") {
+		t.Fatalf("invalid fuzz corpus entry %q", b)
+	}
+	input, err1 := strconv.Unquote(strings.TrimSuffix(strings.TrimPrefix(lines[1], "[]byte("), ")"))
+	if err1 != nil {
+		t.Fatal(err1)
+	}
+
+	tb := &logTB{T: t}
+	checkFuzz(tb, reproProp, []byte(input))
+	if !tb.failed {
+		t.Fatalf("fuzz corpus entry %q does not fail", b)
+	}
+}
+
+func TestReplayTokenRoundtrip(t *testing.T) {
+	t.Parallel()
+
+	Check(t, func(t *T) {
+		var (
+			testName = String().Draw(t, "testName").(string)
+			seed     = Uint64().Draw(t, "seed").(uint64)
+			buf      = SliceOf(Uint64()).Draw(t, "buf").([]uint64)
+		)
+
+		token := encodeReplayToken(testName, seed, buf)
+		testHash, seed2, buf2, err := decodeReplayToken(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if testHash != replayTokenTestHash(testName) {
+			t.Fatalf("got test hash %v instead of %v", testHash, replayTokenTestHash(testName))
+		}
+		if seed2 != seed {
+			t.Fatalf("got seed %v instead of %v", seed2, seed)
+		}
+		if fmt.Sprint(buf2) != fmt.Sprint(buf) {
+			t.Fatalf("got %v instead of %v", buf2, buf)
+		}
+	})
+}
+
+func TestSavedFailFilesReplayedFirst(t *testing.T) {
+	prop := func(t *T) {
+		if Int().Draw(t, "x").(int) > 10 {
+			t.Fatal("too big")
+		}
+	}
+
+	_, _, seed, buf, err, _ := doCheck(t, newSettings(nil), baseSeed(), prop)
+	if err == nil {
+		t.Fatal("property did not fail")
+	}
+
+	fileName := failFileName(t.Name())
+	defer removeFailFiles(t.Name())
+	err1 := saveFailFile(fileName, nil, testFailFile(t, prop, seed, buf))
+	if err1 != nil {
+		t.Fatal(err1)
+	}
+	if files := savedFailFiles(t.Name()); len(files) != 1 || files[0] != fileName {
+		t.Fatalf("got saved fail files %v instead of %q", files, fileName)
+	}
+
+	cfg := newSettings([]Option{func(s *settings) { s.checks = 0 }})
+	_, _, _, _, err, _ = doCheck(t, cfg, baseSeed(), prop)
+	if err == nil {
+		t.Fatal("saved fail file was not replayed")
+	}
+	if cfg.failfile != fileName {
+		t.Fatalf("failure reported using %q instead of %q", cfg.failfile, fileName)
+	}
+}
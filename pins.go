@@ -0,0 +1,91 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const pinEnv = "RAPID_PIN"
+
+// parsePins parses the draws pinned like "n=17;s=\"abc\"", with the values
+// encoded as JSON (decoded when the types of the draws are known).
+func parsePins(s string) (map[string]interface{}, error) {
+	pins := map[string]interface{}{}
+	for _, pin := range strings.Split(s, ";") {
+		pin = strings.TrimSpace(pin)
+		if pin == "" {
+			continue
+		}
+		i := strings.Index(pin, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("%q is not of the form label=value", pin)
+		}
+		label, raw := strings.TrimSpace(pin[:i]), json.RawMessage(strings.TrimSpace(pin[i+1:]))
+		if !json.Valid(raw) {
+			return nil, fmt.Errorf("value of draw %v is not valid JSON: %s", label, raw)
+		}
+		pins[label] = traceArg{label: label, raw: raw}
+	}
+
+	return pins, nil
+}
+
+// formatPins describes the pinned draws, in order of their labels.
+func formatPins(pins map[string]interface{}) string {
+	labels := make([]string, 0, len(pins))
+	for label := range pins {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	s := make([]string, len(labels))
+	for i, label := range labels {
+		if arg, ok := pins[label].(traceArg); ok {
+			s[i] = fmt.Sprintf("%v=%s", label, arg.raw)
+		} else {
+			s[i] = fmt.Sprintf("%v=%v", label, printValue(pins[label]))
+		}
+	}
+	return strings.Join(s, "; ")
+}
+
+// withPins returns the property which draws the pinned values instead of the generated ones.
+func withPins(prop func(*T), pins map[string]interface{}) func(*T) {
+	return func(t *T) {
+		t.pins = pins
+		prop(t)
+	}
+}
+
+// pinnedValue returns the value pinned for the draw with the label, or v if there is none.
+// The pinned draws still generate their values, so that the other draws are the same.
+func (t *T) pinnedValue(g *Generator, label string, v value) value {
+	p, ok := t.pins[label]
+	if !ok {
+		return v
+	}
+
+	if arg, ok := p.(traceArg); ok {
+		var err error
+		p, err = arg.decode(g.type_())
+		if err != nil {
+			t.Helper()
+			t.Fatalf("[rapid] value %s pinned for draw %v is not a valid %v: %v", arg.raw, label, g.type_(), err)
+		}
+	}
+	if p == nil || !reflect.TypeOf(p).AssignableTo(g.type_()) {
+		t.Helper()
+		t.Fatalf("[rapid] value %#v pinned for draw %v is not assignable to %v", p, label, g.type_())
+	}
+
+	return p
+}
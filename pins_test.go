@@ -0,0 +1,85 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePins(t *testing.T) {
+	t.Parallel()
+
+	pins, err := parsePins(` n=17; s = "a;b" ;`)
+	if err == nil {
+		t.Fatalf("value with a semicolon parsed as %v", formatPins(pins))
+	}
+
+	pins, err = parsePins(`n=17; s = "ab"; xs=[1, 2]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatPins(pins); got != `n=17; s="ab"; xs=[1, 2]` {
+		t.Errorf("got pins %v", got)
+	}
+
+	for _, s := range []string{"n", "=1", "n=x"} {
+		if _, err := parsePins(s); err == nil {
+			t.Errorf("%q parsed without an error", s)
+		}
+	}
+}
+
+func TestPinnedDraws(t *testing.T) {
+	t.Parallel()
+
+	pins, _ := parsePins(`xs=[1,2]`)
+	pins["n"] = 17
+	prop := func(t *T) {
+		_ = IntRange(0, 1000).Draw(t, "n")
+		_ = SliceOf(Int()).Draw(t, "xs")
+		_ = String().Draw(t, "s")
+	}
+
+	seed := baseSeed()
+	draws := func(pins map[string]interface{}) []drawnValue {
+		nt := newT(nil, newRandomBitStream(seed, false), false, nil)
+		nt.topDraws, nt.pins = []drawnValue{}, pins
+		if err := checkOnce(nt, prop); err != nil {
+			t.Fatal(err)
+		}
+		return nt.topDraws
+	}
+	generated, pinned := draws(nil), draws(pins)
+
+	if pinned[0].v != 17 || !reflect.DeepEqual(pinned[1].v, []int{1, 2}) {
+		t.Errorf("got pinned draws %v, %v", pinned[0], pinned[1])
+	}
+	if pinned[2].v != generated[2].v {
+		t.Errorf("draw after the pinned ones changed from %v to %v", generated[2], pinned[2])
+	}
+}
+
+func TestPin(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		n := IntRange(0, 1000).Draw(t, "n").(int)
+		m := IntRange(0, 1000).Draw(t, "m").(int)
+		if n == 17 && m > 100 {
+			t.Fatalf("n = %v, m = %v", n, m)
+		}
+	}, Pin("n", 17))
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	for _, s := range []string{"[rapid] pinned draws: n=17\n", "n = 17, m = 101"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in output:\n%v", s, out)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	printersMu     sync.RWMutex
+	printers       = map[reflect.Type]func(interface{}) string{}
+	sensitiveTypes = map[reflect.Type]bool{}
+)
+
+// RegisterPrinter makes rapid render the values of type typ with fn when describing
+// drawn values in failures and verbose logs, replacing the printer of typ, if any.
+// Slices, arrays and pointers of typ are rendered element by element. This is useful
+// for opaque types (protobuf messages, decimals, handles), which are otherwise
+// rendered with %#v, often as bare pointer addresses.
+func RegisterPrinter(typ reflect.Type, fn func(v interface{}) string) {
+	assertf(typ != nil, "printer type should not be nil")
+	assertf(fn != nil, "printer of %v should not be nil", typ)
+
+	printersMu.Lock()
+	defer printersMu.Unlock()
+
+	printers[typ] = fn
+	delete(sensitiveTypes, typ)
+}
+
+func lookupPrinter(typ reflect.Type) func(interface{}) string {
+	printersMu.RLock()
+	defer printersMu.RUnlock()
+
+	return printers[typ]
+}
+
+// printable reports whether the values of typ are rendered by a printer,
+// directly or element by element.
+func printable(typ reflect.Type) bool {
+	for {
+		if lookupPrinter(typ) != nil {
+			return true
+		}
+		switch typ.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Ptr:
+			typ = typ.Elem()
+		default:
+			return false
+		}
+	}
+}
+
+// printValue renders v like %#v, using the registered printers.
+func printValue(v interface{}) string {
+	printersMu.RLock()
+	n := len(printers)
+	printersMu.RUnlock()
+
+	if n == 0 || v == nil {
+		return fmt.Sprintf("%#v", v)
+	}
+	return printReflect(reflect.ValueOf(v))
+}
+
+func printReflect(v reflect.Value) string {
+	if !v.CanInterface() {
+		return fmt.Sprintf("%#v", v)
+	}
+	if fn := lookupPrinter(v.Type()); fn != nil {
+		return fn(v.Interface())
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if (v.Kind() == reflect.Array || !v.IsNil()) && printable(v.Type().Elem()) {
+			elems := make([]string, v.Len())
+			for i := range elems {
+				elems[i] = printReflect(v.Index(i))
+			}
+			return fmt.Sprintf("%v{%v}", v.Type(), strings.Join(elems, ", "))
+		}
+	case reflect.Ptr:
+		if !v.IsNil() && printable(v.Type().Elem()) {
+			return "&" + printReflect(v.Elem())
+		}
+	}
+
+	return fmt.Sprintf("%#v", v.Interface())
+}
@@ -0,0 +1,65 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type opaqueHandle struct {
+	id int
+}
+
+func init() {
+	RegisterPrinter(reflect.TypeOf(opaqueHandle{}), func(v interface{}) string {
+		return fmt.Sprintf("handle#%v", v.(opaqueHandle).id)
+	})
+}
+
+func TestPrintValue(t *testing.T) {
+	t.Parallel()
+
+	h := opaqueHandle{3}
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{h, "handle#3"},
+		{&h, "&handle#3"},
+		{[]opaqueHandle{{1}, {2}}, "[]rapid.opaqueHandle{handle#1, handle#2}"},
+		{[1]*opaqueHandle{&h}, "[1]*rapid.opaqueHandle{&handle#3}"},
+		{[]opaqueHandle(nil), "[]rapid.opaqueHandle(nil)"},
+		{[]int{1}, "[]int{1}"},
+		{nil, "<nil>"},
+	}
+
+	for _, tt := range tests {
+		if got := printValue(tt.v); got != tt.want {
+			t.Errorf("printValue(%#v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterPrinter_Failure(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		h := Custom(func(t *T) opaqueHandle {
+			return opaqueHandle{IntRange(0, 9).Draw(t, "id").(int)}
+		}).Draw(t, "h").(opaqueHandle)
+		if h.id > 0 {
+			t.Fatal("non-zero handle")
+		}
+	})
+	removeFailFiles(t.Name())
+
+	if out := tb.out.String(); !strings.Contains(out, "draw h: handle#1") {
+		t.Errorf("printed handle not found in output:\n%v", out)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapidtest
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"pgregory.net/rapid"
+)
+
+const (
+	// minPValue is the significance level of the statistical tests. Since the
+	// values are generated from fixed seeds, the outcome of a test does not
+	// change between runs, unless the generator does.
+	minPValue = 0.001
+	// minExpected is the smallest expected count of a value for the chi-square test.
+	minExpected = 5
+)
+
+// HasDistribution checks with the chi-square test that the values g produces
+// for the seeds from 0 to n-1 are distributed as want, which maps the keys
+// of the values (the values themselves if key is nil) to their relative weights,
+// e.g. the weights of the branches of a generator:
+//
+//	rapidtest.HasDistribution(t, g, 10000, func(v interface{}) interface{} {
+//		return v.(Shape).Kind
+//	}, map[interface{}]float64{Circle: 1, Square: 1, Polygon: 2})
+//
+// Every key should be expected at least 5 times in n values.
+func HasDistribution(t rapid.TB, g *rapid.Generator, n int, key func(v interface{}) interface{}, want map[interface{}]float64) {
+	t.Helper()
+
+	total := 0.0
+	for _, w := range want {
+		total += w
+	}
+	for k, w := range want {
+		if e := w / total * float64(n); e < minExpected {
+			t.Errorf("%v is expected %.1f times in %v values, increase the number of values to at least %v", k, e, n, int(math.Ceil(minExpected*total/w)))
+			return
+		}
+	}
+
+	counts := map[interface{}]int{}
+	for seed := 0; seed < n; seed++ {
+		v, err := example(g, seed)
+		if err != nil {
+			t.Errorf("%v panicked for seed %v: %v", g, seed, err)
+			return
+		}
+		k := v
+		if key != nil {
+			k = key(v)
+		}
+		if _, ok := want[k]; !ok {
+			t.Errorf("%v produced %#v for seed %v, which is not expected", g, k, seed)
+			return
+		}
+		counts[k]++
+	}
+
+	stat := 0.0
+	for k, w := range want {
+		e := w / total * float64(n)
+		stat += (float64(counts[k]) - e) * (float64(counts[k]) - e) / e
+	}
+	if p := gammaQ(float64(len(want)-1)/2, stat/2); p < minPValue {
+		t.Errorf("%v does not have the expected distribution (chi-square p-value %.2g):\n%v", g, p, compareCounts(counts, want, total, n))
+	}
+}
+
+// IsUniform checks with the chi-square test that the integers g produces for
+// the seeds from 0 to n-1 are distributed uniformly between min and max (inclusive).
+func IsUniform(t rapid.TB, g *rapid.Generator, n int, min int64, max int64) {
+	t.Helper()
+
+	want := map[interface{}]float64{}
+	for i := min; i <= max && i >= min; i++ {
+		want[i] = 1
+	}
+	key := func(v interface{}) interface{} {
+		f, ok := toFloat(v)
+		if !ok || f != math.Trunc(f) {
+			return v
+		}
+		return int64(f)
+	}
+
+	HasDistribution(t, g, n, key, want)
+}
+
+// FollowsCDF checks with the Kolmogorov-Smirnov test that the numbers g
+// produces for the seeds from 0 to n-1 are distributed according to the
+// cumulative distribution function cdf, which should be continuous.
+func FollowsCDF(t rapid.TB, g *rapid.Generator, n int, cdf func(x float64) float64) {
+	t.Helper()
+
+	xs := make([]float64, n)
+	for seed := range xs {
+		v, err := example(g, seed)
+		if err != nil {
+			t.Errorf("%v panicked for seed %v: %v", g, seed, err)
+			return
+		}
+		x, ok := toFloat(v)
+		if !ok {
+			t.Errorf("%v produced %#v for seed %v, which is not a number", g, v, seed)
+			return
+		}
+		xs[seed] = x
+	}
+	sort.Float64s(xs)
+
+	d := 0.0
+	for i, x := range xs {
+		f := cdf(x)
+		d = math.Max(d, math.Max(f-float64(i)/float64(n), float64(i+1)/float64(n)-f))
+	}
+	sn := math.Sqrt(float64(n))
+	if p := ksQ((sn + 0.12 + 0.11/sn) * d); p < minPValue {
+		t.Errorf("%v does not follow the distribution (Kolmogorov-Smirnov distance %.3g, p-value %.2g)", g, d, p)
+	}
+}
+
+// compareCounts formats the counts of the keys next to the expected ones.
+func compareCounts(counts map[interface{}]int, want map[interface{}]float64, total float64, n int) string {
+	keys := make([]interface{}, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+
+	s := ""
+	for _, k := range keys {
+		s += fmt.Sprintf("%v: got %v, want %.1f\n", k, counts[k], want[k]/total*float64(n))
+	}
+	return s
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(r.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(r.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return r.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// gammaQ returns the regularized upper incomplete gamma function Q(a, x),
+// so that the p-value of the chi-square statistic x with k degrees of freedom
+// is gammaQ(k/2, x/2).
+func gammaQ(a float64, x float64) float64 {
+	const (
+		eps  = 1e-15
+		tiny = 1e-300
+	)
+	if x <= 0 {
+		return 1
+	}
+	lg, _ := math.Lgamma(a)
+	scale := math.Exp(-x + a*math.Log(x) - lg)
+
+	if x < a+1 {
+		// series for P(a, x)
+		sum, del := 1/a, 1/a
+		for n := 1; n < 1000 && math.Abs(del) >= math.Abs(sum)*eps; n++ {
+			del *= x / (a + float64(n))
+			sum += del
+		}
+		return 1 - sum*scale
+	}
+
+	// continued fraction for Q(a, x), by the modified Lentz's method
+	b := x + 1 - a
+	c, d := 1/tiny, 1/b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		if math.Abs(d*c-1) < eps {
+			break
+		}
+	}
+	return scale * h
+}
+
+// ksQ returns the probability that the Kolmogorov-Smirnov statistic,
+// scaled by the square root of the number of values, exceeds lambda.
+func ksQ(lambda float64) float64 {
+	if lambda < 0.2 {
+		return 1
+	}
+
+	sum, sign := 0.0, 1.0
+	for j := 1; j <= 100; j++ {
+		term := 2 * math.Exp(-2*float64(j*j)*lambda*lambda)
+		sum += sign * term
+		if term < 1e-12 {
+			break
+		}
+		sign = -sign
+	}
+	return math.Max(0, math.Min(1, sum))
+}
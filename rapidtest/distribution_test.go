@@ -0,0 +1,84 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapidtest
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+)
+
+// uniformBits generates the integers of n bits uniformly, unlike IntRange,
+// which prefers the small ones.
+func uniformBits(n int) *rapid.Generator {
+	return rapid.Custom(func(t *rapid.T) int {
+		u := 0
+		for i := 0; i < n; i++ {
+			u <<= 1
+			if rapid.Bool().Draw(t, "bit").(bool) {
+				u |= 1
+			}
+		}
+		return u
+	})
+}
+
+func TestStatistics(t *testing.T) {
+	for _, tt := range []struct {
+		got  float64
+		want float64
+	}{
+		{gammaQ(0.5, 3.841/2), 0.05}, // chi-square with 1 degree of freedom
+		{gammaQ(5, 18.307/2), 0.05},  // 10 degrees of freedom
+		{gammaQ(5, 2.558/2), 0.99},
+		{ksQ(1.358), 0.05},
+		{ksQ(1.628), 0.01},
+	} {
+		if math.Abs(tt.got-tt.want) > 0.001 {
+			t.Errorf("got p-value %v, want %v", tt.got, tt.want)
+		}
+	}
+}
+
+func TestDistributions(t *testing.T) {
+	IsUniform(t, uniformBits(3), 2000, 0, 7)
+
+	fault := rapid.NewFaultPoint("write", rapid.FaultError, rapid.FaultDelay)
+	faults := rapid.Custom(func(t *rapid.T) rapid.Fault { return fault.Inject(t) })
+	HasDistribution(t, faults, 2000, nil, map[interface{}]float64{
+		rapid.FaultNone:  0.9,
+		rapid.FaultError: 0.05,
+		rapid.FaultDelay: 0.05,
+	})
+
+	uniform := uniformBits(20).Map(func(u int) float64 { return (float64(u) + 0.5) / (1 << 20) })
+	FollowsCDF(t, uniform, 1000, func(x float64) float64 { return math.Max(0, math.Min(1, x)) })
+}
+
+func TestDistributions_Failures(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		assert func(tb rapid.TB)
+		want   string
+	}{
+		{"skewed", func(tb rapid.TB) { IsUniform(tb, rapid.IntRange(0, 7), 2000, 0, 7) }, "does not have the expected distribution"},
+		{"unexpected", func(tb rapid.TB) { IsUniform(tb, uniformBits(3), 2000, 0, 6) }, "produced 7 for seed"},
+		{"too few", func(tb rapid.TB) { IsUniform(tb, uniformBits(3), 20, 0, 7) }, "increase the number of values to at least 40"},
+		{"cdf", func(tb rapid.TB) {
+			FollowsCDF(tb, rapid.Float64Range(0, 1), 1000, func(x float64) float64 { return x })
+		}, "does not follow the distribution"},
+		{"not a number", func(tb rapid.TB) { FollowsCDF(tb, rapid.String(), 10, nil) }, "which is not a number"},
+	} {
+		tb := &recordTB{T: t}
+		tt.assert(tb)
+		if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], tt.want) {
+			t.Errorf("%v: got errors %q, want %q", tt.name, tb.errors, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapidtest
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"pgregory.net/rapid"
+)
+
+const goldenDirMode = 0775
+
+var update = flag.Bool("rapidtest.update", false, "rapidtest: write the values generators produce to the golden files, instead of comparing them")
+
+// MatchesGolden checks that the values g produces for the seeds from 0 to n-1
+// are the ones recorded in the golden file testdata/rapidtest/<test>/<name>.golden.
+// Run the test with -rapidtest.update to record the values, and commit the file.
+//
+// The changes of the values (e.g. after a change to a Custom generator, or
+// an upgrade of rapid) also change the test cases of the saved fail files and
+// the example databases (see Database) into different ones, which can not
+// reproduce the failures any more. MatchesGolden makes these changes deliberate.
+func MatchesGolden(t rapid.TB, g *rapid.Generator, name string, n int) {
+	t.Helper()
+
+	matchesGolden(t, g, filepath.Join("testdata", "rapidtest", safeFilename(t.Name()), safeFilename(name)+".golden"), n)
+}
+
+func matchesGolden(t rapid.TB, g *rapid.Generator, file string, n int) {
+	t.Helper()
+
+	lines := make([]string, n)
+	for seed := range lines {
+		v, err := example(g, seed)
+		if err != nil {
+			t.Errorf("%v panicked for seed %v: %v", g, seed, err)
+			return
+		}
+		lines[seed] = formatValue(reflect.ValueOf(v))
+	}
+
+	if *update {
+		data := fmt.Sprintf("# %v, seeds 0 to %v\n%v\n", g, n-1, strings.Join(lines, "\n"))
+		err := os.MkdirAll(filepath.Dir(file), goldenDirMode)
+		if err == nil {
+			err = ioutil.WriteFile(file, []byte(data), 0644)
+		}
+		if err != nil {
+			t.Errorf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Errorf("failed to read golden file (run with -rapidtest.update to write it): %v", err)
+		return
+	}
+	golden := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(golden) > 0 && strings.HasPrefix(golden[0], "#") {
+		golden = golden[1:]
+	}
+	for seed, line := range lines {
+		switch {
+		case seed >= len(golden):
+			t.Errorf("golden file %q has %v values, want %v (run with -rapidtest.update to write them)", file, len(golden), n)
+			return
+		case line != golden[seed]:
+			t.Errorf("%v produced %v for seed %v, but golden file %q has %v\n"+
+				"If the change is deliberate, run with -rapidtest.update: the saved fail files and example databases will not reproduce their failures",
+				g, line, seed, file, golden[seed])
+			return
+		}
+	}
+}
+
+// formatValue renders v like %#v, but with the values of the pointers instead
+// of their addresses, which differ between runs.
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return "<nil>"
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			break
+		}
+		if v.Kind() == reflect.Ptr {
+			return "&" + formatValue(v.Elem())
+		}
+		return formatValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			break
+		}
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elems[i] = formatValue(v.Index(i))
+		}
+		return fmt.Sprintf("%v{%v}", v.Type(), strings.Join(elems, ", "))
+	case reflect.Map:
+		if v.IsNil() {
+			break
+		}
+		elems := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			elems = append(elems, formatValue(k)+":"+formatValue(v.MapIndex(k)))
+		}
+		sort.Strings(elems)
+		return fmt.Sprintf("%v{%v}", v.Type(), strings.Join(elems, ", "))
+	case reflect.Struct:
+		fields := make([]string, v.NumField())
+		for i := range fields {
+			fields[i] = v.Type().Field(i).Name + ":" + formatValue(v.Field(i))
+		}
+		return fmt.Sprintf("%v{%v}", v.Type(), strings.Join(fields, ", "))
+	}
+
+	return fmt.Sprintf("%#v", v)
+}
+
+func safeFilename(f string) string {
+	var s strings.Builder
+	for _, r := range f {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			s.WriteRune(r)
+		} else {
+			s.WriteRune('_')
+		}
+	}
+	return s.String()
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapidtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+)
+
+func TestMatchesGolden(t *testing.T) {
+	MatchesGolden(t, pairGen, "pairs", 20)
+	MatchesGolden(t, rapid.MapOfN(rapid.String(), rapid.Ptr(rapid.Int(), true), 0, 3), "maps", 20)
+}
+
+func TestMatchesGolden_Changes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rapidtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	file := filepath.Join(dir, "ints", "a.golden")
+
+	for _, tt := range []struct {
+		name   string
+		g      *rapid.Generator
+		n      int
+		update bool
+		want   string
+	}{
+		{"missing", rapid.IntRange(0, 100), 10, false, "failed to read golden file"},
+		{"update", rapid.IntRange(0, 100), 10, true, ""},
+		{"same", rapid.IntRange(0, 100), 10, false, ""},
+		{"fewer", rapid.IntRange(0, 100), 5, false, ""},
+		{"more", rapid.IntRange(0, 100), 11, false, "has 10 values, want 11"},
+		{"changed", rapid.IntRange(1, 101), 10, false, "produced 1 for seed 0, but golden file"},
+	} {
+		*update = tt.update
+		tb := &recordTB{T: t}
+		matchesGolden(tb, tt.g, file, tt.n)
+		*update = false
+
+		if tt.want == "" && len(tb.errors) != 0 || tt.want != "" && (len(tb.errors) != 1 || !strings.Contains(tb.errors[0], tt.want)) {
+			t.Errorf("%v: got errors %q, want %q", tt.name, tb.errors, tt.want)
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	i := 1
+	for _, tt := range []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, "<nil>"},
+		{&i, "&1"},
+		{[]*int{&i, nil}, "[]*int{&1, (*int)(nil)}"},
+		{map[string]*int{"b": nil, "a": &i}, `map[string]*int{"a":&1, "b":(*int)(nil)}`},
+		{struct{ p *int }{&i}, "struct { p *int }{p:&1}"},
+		{[]interface{}{"x", 2}, `[]interface {}{"x", 2}`},
+	} {
+		if got := formatValue(reflect.ValueOf(tt.v)); got != tt.want {
+			t.Errorf("got %v, want %v", got, tt.want)
+		}
+	}
+}
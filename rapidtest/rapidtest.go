@@ -0,0 +1,93 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package rapidtest provides assertions for the unit tests of generators,
+// like the Custom generators of the domain types of a project, and of the way
+// their values shrink:
+//
+//	func TestOrderGen(t *testing.T) {
+//		rapidtest.NeverPanics(t, orderGen, 1000)
+//		rapidtest.AllSatisfy(t, orderGen, 1000, func(v interface{}) bool {
+//			return v.(Order).Total() >= 0
+//		})
+//		rapidtest.ShrinksTo(t, orderGen, func(v interface{}) bool {
+//			return len(v.(Order).Items) < 2
+//		}, Order{Items: []Item{{Qty: 1}, {Qty: 1}}})
+//	}
+//
+// HasDistribution, IsUniform and FollowsCDF check the distribution of the values
+// with statistical tests, to catch the changes which silently skew it, and
+// MatchesGolden compares the values with the ones recorded in a golden file.
+//
+// The values are generated from the seeds 0, 1, ..., so the assertions
+// are deterministic.
+package rapidtest
+
+import (
+	"fmt"
+	"reflect"
+
+	"pgregory.net/rapid"
+)
+
+// NeverPanics checks that g produces a value for each of the seeds from 0
+// to n-1, without panicking or failing to generate it (e.g. because of
+// a Filter which rejects too many values).
+func NeverPanics(t rapid.TB, g *rapid.Generator, n int) {
+	t.Helper()
+
+	for seed := 0; seed < n; seed++ {
+		if _, err := example(g, seed); err != nil {
+			t.Errorf("%v panicked for seed %v: %v", g, seed, err)
+			return
+		}
+	}
+}
+
+// AllSatisfy checks that the values g produces for the seeds from 0 to n-1
+// satisfy pred.
+func AllSatisfy(t rapid.TB, g *rapid.Generator, n int, pred func(v interface{}) bool) {
+	t.Helper()
+
+	for seed := 0; seed < n; seed++ {
+		v, err := example(g, seed)
+		if err != nil {
+			t.Errorf("%v panicked for seed %v: %v", g, seed, err)
+			return
+		}
+		if !pred(v) {
+			t.Errorf("%v produced %#v for seed %v, which does not satisfy the predicate", g, v, seed)
+			return
+		}
+	}
+}
+
+// ShrinksTo checks that the simplest value produced by g which does not
+// satisfy pred (the value rapid reports when the property pred fails) is want.
+// Values are compared with reflect.DeepEqual. Shrinking can get stuck at
+// a local minimum, so want should be the value rapid finds, not the one
+// which is the simplest in theory.
+func ShrinksTo(t rapid.TB, g *rapid.Generator, pred func(v interface{}) bool, want interface{}, opts ...rapid.Option) {
+	t.Helper()
+
+	v, ok := rapid.Minimize(g, 0, func(v interface{}) bool { return !pred(v) }, opts...)
+	switch {
+	case !ok:
+		t.Errorf("%v has not produced a value which does not satisfy the predicate", g)
+	case !reflect.DeepEqual(v, want):
+		t.Errorf("%v shrinks to %#v, want %#v", g, v, want)
+	}
+}
+
+func example(g *rapid.Generator, seed int) (v interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return g.Example(seed), nil
+}
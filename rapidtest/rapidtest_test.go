@@ -0,0 +1,70 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapidtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+)
+
+// recordTB records the errors instead of failing the test.
+type recordTB struct {
+	*testing.T
+	errors []string
+}
+
+func (tb *recordTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+type pair struct {
+	A, B int
+}
+
+var pairGen = rapid.Custom(func(t *rapid.T) pair {
+	a := rapid.IntRange(0, 100).Draw(t, "a").(int)
+	return pair{A: a, B: a + rapid.IntRange(0, 100).Draw(t, "d").(int)}
+})
+
+func TestAssertions(t *testing.T) {
+	NeverPanics(t, pairGen, 100)
+	AllSatisfy(t, pairGen, 100, func(v interface{}) bool { return v.(pair).A <= v.(pair).B })
+	ShrinksTo(t, pairGen, func(v interface{}) bool { return v.(pair).A < 10 }, pair{A: 10, B: 10})
+}
+
+func TestAssertions_Failures(t *testing.T) {
+	panicky := rapid.Custom(func(t *rapid.T) int {
+		n := rapid.IntRange(0, 10).Draw(t, "n").(int)
+		return 100 / (n - n)
+	})
+
+	for _, tt := range []struct {
+		name   string
+		assert func(tb rapid.TB)
+		want   string
+	}{
+		{"panic", func(tb rapid.TB) { NeverPanics(tb, panicky, 10) }, "panicked for seed 0: "},
+		{"predicate", func(tb rapid.TB) {
+			AllSatisfy(tb, pairGen, 100, func(v interface{}) bool { return v.(pair).A == v.(pair).B })
+		}, "which does not satisfy the predicate"},
+		{"shrink", func(tb rapid.TB) {
+			ShrinksTo(tb, pairGen, func(v interface{}) bool { return v.(pair).A < 10 }, pair{A: 10, B: 20})
+		}, "shrinks to rapidtest.pair{A:10, B:10}, want rapidtest.pair{A:10, B:20}"},
+		{"unsatisfiable", func(tb rapid.TB) {
+			ShrinksTo(tb, pairGen, func(v interface{}) bool { return true }, pair{})
+		}, "has not produced a value"},
+	} {
+		tb := &recordTB{T: t}
+		tt.assert(tb)
+		if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], tt.want) {
+			t.Errorf("%v: got errors %q, want %q", tt.name, tb.errors, tt.want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	reportTmpl = template.Must(template.New("rapid-report").Parse(reportHTML))
+
+	reportMu     sync.Mutex
+	reportStart  = time.Now()
+	reportChecks = map[string][]checkReport{} // checks of the run in order of completion, by report file
+)
+
+// checkReport is the outcome of a check, as shown in the run reports.
+type checkReport struct {
+	Test     string
+	Duration time.Duration
+	Valid    int
+	Invalid  int
+	Error    string      // empty if the check has passed
+	Repro    string      // flags to reproduce the failure
+	Draws    []reportRow // top-level draws of the minimized failing test case
+	Charts   []reportChart
+	Tables   []reportTable
+	Metadata PropertyMetadata
+}
+
+type reportRow struct {
+	Cells []string
+}
+
+type reportTable struct {
+	Title   string
+	Columns []string
+	Rows    []reportRow
+}
+
+type reportChart struct {
+	Title string
+	Bars  []reportBar
+}
+
+type reportBar struct {
+	Name    string
+	Count   int
+	Percent float64
+}
+
+type reportTmplData struct {
+	Title  string
+	Passed int
+	Failed int
+	Checks []checkReport
+}
+
+// newCheckReport summarizes the check, and the statistics of its run.
+func newCheckReport(name string, dt time.Duration, valid int, invalid int, st *runStats) checkReport {
+	r := checkReport{
+		Test:     name,
+		Duration: dt.Round(time.Millisecond),
+		Valid:    valid,
+		Invalid:  invalid,
+	}
+
+	if len(st.events) > 0 {
+		r.Charts = append(r.Charts, distributionChart(fmt.Sprintf("Events (%v test cases)", st.cases), st.events, st.cases))
+	}
+	for _, label := range st.labels {
+		total := 0
+		for _, n := range st.collected[label] {
+			total += n
+		}
+		r.Charts = append(r.Charts, distributionChart(fmt.Sprintf("Collected %q (%v values)", label, total), st.collected[label], total))
+	}
+
+	if len(st.obsLabels) > 0 {
+		t := reportTable{Title: "Observed", Columns: []string{"label", "values", "min", "median", "p90", "max"}}
+		for _, label := range st.obsLabels {
+			values := append([]float64(nil), st.observed[label]...)
+			sort.Float64s(values)
+			t.Rows = append(t.Rows, reportRow{[]string{label, fmt.Sprint(len(values)),
+				fmt.Sprint(values[0]), fmt.Sprint(percentile(values, 50)), fmt.Sprint(percentile(values, 90)), fmt.Sprint(values[len(values)-1])}})
+		}
+		r.Tables = append(r.Tables, t)
+	}
+
+	if len(st.actions) > 0 {
+		t := reportTable{Title: "Actions", Columns: []string{"action", "chosen", "executed", "disabled"}}
+		var names []string
+		for name := range st.actions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			as := st.actions[name]
+			t.Rows = append(t.Rows, reportRow{[]string{name, fmt.Sprint(as.chosen), fmt.Sprint(as.executed), fmt.Sprint(as.disabled)}})
+		}
+		r.Tables = append(r.Tables, t)
+	}
+
+	return r
+}
+
+func distributionChart(title string, counts map[string]int, total int) reportChart {
+	c := reportChart{Title: title}
+	for i, k := range mostFrequent(counts) {
+		if i == distributionMaxRows {
+			c.Bars = append(c.Bars, reportBar{Name: fmt.Sprintf("... (%v more)", len(counts)-i)})
+			break
+		}
+		c.Bars = append(c.Bars, reportBar{Name: k, Count: counts[k], Percent: percentage(counts[k], total)})
+	}
+	return c
+}
+
+// failed adds the failure, and the top-level draws of the failing test case, to the report.
+func (r *checkReport) failed(err *testError, repro string, draws []drawnValue, maxValueLen int) {
+	r.Error = err.Error()
+	r.Repro = repro
+	for _, d := range draws {
+		r.Draws = append(r.Draws, reportRow{[]string{d.label, d.truncated(maxValueLen)}})
+	}
+}
+
+// addToHTMLReport adds the check to the checks of the run, and writes the HTML report of all of them.
+func addToHTMLReport(filename string, r checkReport) error {
+	reportMu.Lock()
+	defer reportMu.Unlock()
+
+	reportChecks[filename] = append(reportChecks[filename], r)
+
+	d := reportTmplData{
+		Title:  fmt.Sprintf("%v (%v)", strings.TrimSuffix(filepath.Base(os.Args[0]), ".test"), reportStart.Format(time.RFC1123)),
+		Checks: reportChecks[filename],
+	}
+	for _, c := range d.Checks {
+		if c.Error == "" {
+			d.Passed++
+		} else {
+			d.Failed++
+		}
+	}
+
+	var b bytes.Buffer
+	if err := reportTmpl.Execute(&b, d); err != nil {
+		return fmt.Errorf("failed to render HTML report %q: %w", filename, err)
+	}
+
+	return writeFileAtomic(filename, b.Bytes())
+}
+
+const reportHTML = `<!doctype html>
+<html lang="en">
+	<head>
+		<meta charset="utf-8">
+		<meta name="description" content="rapid run report">
+		<meta name="viewport" content="width=device-width, initial-scale=1">
+		<title>[rapid] {{.Title}}</title>
+		<style>
+body { margin: 1rem; font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1rem; }
+th, td { text-align: left; padding: 0.2rem 0.6rem; border-bottom: 1px solid #ddd; vertical-align: top; }
+pre { margin: 0; white-space: pre-wrap; }
+.passed { color: green; }
+.failed { color: red; }
+.check { margin-bottom: 2rem; }
+.bar { background-color: steelblue; height: 0.8rem; }
+.bar-cell { width: 20rem; }
+		</style>
+	</head>
+	<body>
+		<h1>{{.Title}}</h1>
+		<p><span class="passed">{{.Passed}} passed</span>, <span class="failed">{{.Failed}} failed</span></p>
+		<table>
+			<tr><th>property</th><th>result</th><th>test cases</th><th>discarded</th><th>time</th></tr>
+			{{range .Checks -}}
+			<tr>
+				<td><a href="#{{.Test}}">{{.Test}}</a></td>
+				<td>{{if .Error}}<span class="failed">failed</span>{{else}}<span class="passed">passed</span>{{end}}</td>
+				<td>{{.Valid}}</td><td>{{.Invalid}}</td><td>{{.Duration}}</td>
+			</tr>
+			{{end}}
+		</table>
+		{{range .Checks -}}
+		<div class="check" id="{{.Test}}">
+			<h2>{{.Test}}</h2>
+			{{with .Metadata -}}
+			{{if .Description}}<p>{{.Description}}</p>{{end}}
+			{{if .Owner}}<p>Owner: {{.Owner}}</p>{{end}}
+			{{if .Link}}<p>See <a href="{{.Link}}">{{.Link}}</a></p>{{end}}
+			{{end -}}
+			{{if .Error -}}
+			<h3 class="failed">Failure</h3>
+			<pre>{{.Error}}</pre>
+			<p>To reproduce, specify <code>{{.Repro}}</code></p>
+			<table>
+				<tr><th>draw</th><th>value</th></tr>
+				{{range .Draws}}<tr>{{range .Cells}}<td><pre>{{.}}</pre></td>{{end}}</tr>{{end}}
+			</table>
+			{{end -}}
+			{{range .Charts -}}
+			<h3>{{.Title}}</h3>
+			<table>
+				{{range .Bars}}<tr><td>{{.Name}}</td><td>{{if .Count}}{{printf "%.1f%%" .Percent}} ({{.Count}}){{end}}</td><td class="bar-cell"><div class="bar" style="width: {{printf "%.1f" .Percent}}%"></div></td></tr>{{end}}
+			</table>
+			{{end -}}
+			{{range .Tables -}}
+			<h3>{{.Title}}</h3>
+			<table>
+				<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+				{{range .Rows}}<tr>{{range .Cells}}<td>{{.}}</td>{{end}}</tr>{{end}}
+			</table>
+			{{end -}}
+		</div>
+		{{end}}
+	</body>
+</html>`
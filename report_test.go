@@ -0,0 +1,61 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTMLReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rapid-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	filename := filepath.Join(dir, "report.html")
+
+	t.Run("pass", func(t *testing.T) {
+		checkTB(t, func(t *T) {
+			n := IntRange(0, 10).Draw(t, "n").(int)
+			t.Classify(n == 0, "zero")
+			t.Observe("n", float64(n))
+		}, HTMLReport(filename))
+	})
+	t.Run("fail", func(t *testing.T) {
+		tb := &logTB{T: t}
+		checkTB(tb, func(t *T) {
+			s := SampledFrom([]string{"a", "<"}).Draw(t, "s").(string)
+			if strings.Contains(s, "<") {
+				t.Fatalf("got <")
+			}
+		}, HTMLReport(filename))
+		removeFailFiles(t.Name())
+	})
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(b)
+	for _, s := range []string{
+		`<span class="passed">1 passed</span>, <span class="failed">1 failed</span>`,
+		`<h2>TestHTMLReport/pass</h2>`,
+		`<h3>Events (`,
+		`<td>zero</td>`,
+		`<h2>TestHTMLReport/fail</h2>`,
+		`<pre>got &lt;</pre>`,
+		`<td><pre>&#34;&lt;&#34;</pre></td>`,
+	} {
+		if !strings.Contains(html, s) {
+			t.Errorf("%q not found in report:\n%v", s, html)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Replay runs prop once, returning values (in order) from the top-level draws
+// instead of generating them. Together with the reproduction code Check prints
+// for a minimized failing test case, Replay allows to keep the failing test case
+// as a deterministic regression test.
+func Replay(t *testing.T, prop func(*T), values ...interface{}) {
+	t.Helper()
+
+	nt := newT(t, newBufBitStream(nil, false), true, nil)
+	nt.replay = make([]value, 0, len(values))
+	for _, v := range values {
+		nt.replay = append(nt.replay, v)
+	}
+
+	err := checkOnce(nt, prop)
+	if err != nil {
+		if err.isStopTest() {
+			t.Fatalf("[rapid] replay failed: %v", err)
+		} else {
+			t.Fatalf("[rapid] replay panicked: %v\nTraceback:\n%v", err, traceback(err))
+		}
+	}
+	if len(nt.replay) != 0 {
+		t.Fatalf("[rapid] replay used only %v of %v values", len(values)-len(nt.replay), len(values))
+	}
+}
+
+func (t *T) replayValue(g *Generator, label string) value {
+	if len(t.replay) == 0 {
+		t.Helper()
+		t.Fatalf("[rapid] no value to replay for draw %v", label)
+	}
+
+	v := t.replay[0]
+	t.replay = t.replay[1:]
+	if arg, ok := v.(traceArg); ok {
+		if arg.label != label {
+			t.Helper()
+			t.Fatalf("[rapid] value to replay for draw %v is labeled %v", label, arg.label)
+		}
+		var err error
+		v, err = arg.decode(g.type_())
+		if err != nil {
+			t.Helper()
+			t.Fatalf("[rapid] value %s to replay for draw %v is not a valid %v: %v", arg.raw, label, g.type_(), err)
+		}
+	}
+	if v == nil || !reflect.TypeOf(v).AssignableTo(g.type_()) {
+		t.Helper()
+		t.Fatalf("[rapid] value %#v to replay for draw %v is not assignable to %v", v, label, g.type_())
+	}
+
+	return v
+}
+
+type drawnValue struct {
+	label     string
+	v         value
+	sensitive bool // drawn from a generator marked with Sensitive, or built from such a value
+}
+
+// String renders the value, redacted if it is sensitive.
+func (d drawnValue) String() string {
+	if d.sensitive {
+		return redact(d.v)
+	}
+	return printValue(d.v)
+}
+
+// redacted reports whether the value, or a part of it, is sensitive.
+func (d drawnValue) redacted() bool {
+	return d.sensitive || (d.v != nil && sensitiveType(reflect.TypeOf(d.v)))
+}
+
+// topLevelDraws runs the test case and returns the top-level draws it makes,
+// together with its error.
+func topLevelDraws(tb tb, prop func(*T), buf []uint64) ([]drawnValue, *testError) {
+	t := newT(tb, newBufBitStream(buf, false), false, nil)
+	t.topDraws = []drawnValue{}
+	err := checkOnce(t, prop)
+
+	return t.topDraws, err
+}
+
+// reproCode returns the Go test which replays the top-level draws made by
+// the test case with Replay (the property draws its values from *T, so it
+// can not be called without rapid), or an empty string if the test case does
+// not fail. The test calls the property by the name of user, the property
+// before wrapping (e.g. by withOutputLimits), if it is a named function, and
+// by the placeholder prop otherwise. Sensitive values and values which can not
+// be written as Go literals are left for the user to fill in, as nil with a comment.
+func reproCode(tb tb, prop func(*T), user func(*T), buf []uint64) string {
+	draws, err := topLevelDraws(tb, prop, buf)
+	if err == nil {
+		return ""
+	}
+
+	propName := runtime.FuncForPC(reflect.ValueOf(user).Pointer()).Name()
+	pkg := propName
+	if i := strings.LastIndex(propName, "/"); i >= 0 {
+		propName = propName[i+1:]
+	}
+	if i := strings.Index(propName, "."); i >= 0 {
+		pkg = pkg[:len(pkg)-len(propName)+i]
+		propName = propName[i+1:]
+	}
+	if strings.Contains(propName, ".func") {
+		propName = "prop"
+	}
+
+	lits := make([]string, len(draws))
+	for i, d := range draws {
+		typ := "nil"
+		if d.v != nil {
+			typ = typeName(reflect.TypeOf(d.v), pkg)
+		}
+		if d.redacted() {
+			lits[i] = fmt.Sprintf("nil /* TODO: sensitive %v */", typ) // the code would reveal the value
+		} else if lit, ok := goLiteral(d.v, pkg); ok {
+			lits[i] = lit
+		} else {
+			lits[i] = fmt.Sprintf("nil /* TODO: %v, which can not be written as a literal */", typ)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "func %v_Repro(t *testing.T) {\n", kindaSafeFilename(tb.Name()))
+	if propName == "prop" {
+		b.WriteString("\t// prop is the property passed to rapid.Check\n")
+	}
+	fmt.Fprintf(&b, "\trapid.Replay(t, %v", propName)
+	if len(draws) > 0 {
+		b.WriteString(",\n")
+		for _, lit := range lits {
+			fmt.Fprintf(&b, "\t\t%v,\n", lit)
+		}
+		b.WriteString("\t")
+	}
+	b.WriteString(")\n}")
+
+	return b.String()
+}
+
+// goLiteral formats v as a Go expression of the same dynamic type, to be used
+// in package pkg (the types of which are written unqualified). It returns
+// false if v can not be written as a literal, e.g. because it is (or contains)
+// a pointer, a channel, a function or a struct with unexported fields.
+func goLiteral(v value, pkg string) (string, bool) {
+	if v == nil {
+		return "nil", true
+	}
+
+	return reflectLiteral(reflect.ValueOf(v), pkg)
+}
+
+// typeName returns the name of typ in package pkg.
+func typeName(typ reflect.Type, pkg string) string {
+	if typ.Name() != "" {
+		if typ.PkgPath() != "" && typ.PkgPath() == pkg {
+			return typ.Name()
+		}
+		return typ.String()
+	}
+
+	switch typ.Kind() {
+	case reflect.Slice:
+		return "[]" + typeName(typ.Elem(), pkg)
+	case reflect.Array:
+		return fmt.Sprintf("[%v]%v", typ.Len(), typeName(typ.Elem(), pkg))
+	case reflect.Map:
+		return fmt.Sprintf("map[%v]%v", typeName(typ.Key(), pkg), typeName(typ.Elem(), pkg))
+	case reflect.Ptr:
+		return "*" + typeName(typ.Elem(), pkg)
+	default:
+		return typ.String()
+	}
+}
+
+func reflectLiteral(rv reflect.Value, pkg string) (string, bool) {
+	typ := typeName(rv.Type(), pkg)
+	named := rv.Type().PkgPath() != ""
+
+	// format the underlying value, ignoring the GoString method of the named type, if any
+	switch rv.Kind() {
+	case reflect.Bool:
+		if !named {
+			return strconv.FormatBool(rv.Bool()), true
+		}
+		return fmt.Sprintf("%v(%v)", typ, rv.Bool()), true
+	case reflect.String:
+		if !named {
+			return strconv.Quote(rv.String()), true
+		}
+		return fmt.Sprintf("%v(%v)", typ, strconv.Quote(rv.String())), true
+	case reflect.Int:
+		if !named {
+			return strconv.FormatInt(rv.Int(), 10), true
+		}
+		return fmt.Sprintf("%v(%v)", typ, rv.Int()), true
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("%v(%v)", typ, rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return fmt.Sprintf("%v(%#v)", typ, rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case math.IsNaN(f):
+			return fmt.Sprintf("%v(math.NaN())", typ), true
+		case math.IsInf(f, 0):
+			return fmt.Sprintf("%v(math.Inf(%v))", typ, int(math.Copysign(1, f))), true
+		default:
+			return fmt.Sprintf("%v(%v)", typ, strconv.FormatFloat(f, 'g', -1, rv.Type().Bits())), true
+		}
+	case reflect.Complex64, reflect.Complex128:
+		return fmt.Sprintf("%v(%#v)", typ, rv.Complex()), true
+	case reflect.Interface:
+		if rv.IsNil() {
+			return "nil", true
+		}
+		return reflectLiteral(rv.Elem(), pkg)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return fmt.Sprintf("%v(nil)", typ), true
+		}
+		elems := make([]string, rv.Len())
+		for i := range elems {
+			lit, ok := reflectLiteral(rv.Index(i), pkg)
+			if !ok {
+				return "", false
+			}
+			elems[i] = lit
+		}
+		return fmt.Sprintf("%v{%v}", typ, strings.Join(elems, ", ")), true
+	case reflect.Map:
+		if rv.IsNil() {
+			return fmt.Sprintf("%v(nil)", typ), true
+		}
+		elems := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			kl, ok1 := reflectLiteral(k, pkg)
+			vl, ok2 := reflectLiteral(rv.MapIndex(k), pkg)
+			if !ok1 || !ok2 {
+				return "", false
+			}
+			elems = append(elems, kl+": "+vl)
+		}
+		sort.Strings(elems)
+		return fmt.Sprintf("%v{%v}", typ, strings.Join(elems, ", ")), true
+	case reflect.Struct:
+		fields := make([]string, rv.NumField())
+		for i := range fields {
+			f := rv.Type().Field(i)
+			if f.PkgPath != "" {
+				return "", false // unexported
+			}
+			lit, ok := reflectLiteral(rv.Field(i), pkg)
+			if !ok {
+				return "", false
+			}
+			fields[i] = f.Name + ":" + lit
+		}
+		return fmt.Sprintf("%v{%v}", typ, strings.Join(fields, ", ")), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+type reproPair struct {
+	A int
+	B int
+}
+
+type (
+	reproColor  string
+	reproFlag   bool
+	reproWeight float32
+	reproHidden struct{ a int }
+)
+
+func reproProp(t *T) {
+	x := Int().Draw(t, "x").(int)
+	p := Custom(func(t *T) reproPair {
+		return reproPair{Int().Draw(t, "a").(int), Int().Draw(t, "b").(int)}
+	}).Draw(t, "p").(reproPair)
+	if x > 10 && p.A >= 0 {
+		t.Fatalf("x = %v, p = %v", x, p)
+	}
+}
+
+func TestGoLiteral(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		v   value
+		lit string
+	}{
+		{nil, "nil"},
+		{5, "5"},
+		{int8(-5), "int8(-5)"},
+		{uint(3), "uint(0x3)"},
+		{1.0, "float64(1)"},
+		{float32(0.5), "float32(0.5)"},
+		{math.NaN(), "float64(math.NaN())"},
+		{math.Inf(-1), "float64(math.Inf(-1))"},
+		{true, "true"},
+		{"a\n", `"a\n"`},
+		{'x', "int32(120)"},
+		{[]int{1, 2}, "[]int{1, 2}"},
+		{reproPair{1, 2}, "reproPair{A:1, B:2}"},
+		{FaultError, "Fault(1)"},
+		{reproColor("red"), `reproColor("red")`},
+		{reproFlag(true), "reproFlag(true)"},
+		{reproWeight(1.5), "reproWeight(1.5)"},
+		{[]reproColor{"a"}, `[]reproColor{reproColor("a")}`},
+		{map[reproColor][2]reproFlag{"a": {}}, `map[reproColor][2]reproFlag{reproColor("a"): [2]reproFlag{reproFlag(false), reproFlag(false)}}`},
+		{[]int(nil), "[]int(nil)"},
+		{map[string]int{"b": 2, "a": 1}, `map[string]int{"a": 1, "b": 2}`},
+		{[]interface{}{1, nil}, "[]interface {}{1, nil}"},
+	}
+
+	for _, td := range testData {
+		if lit, ok := goLiteral(td.v, "pgregory.net/rapid"); !ok || lit != td.lit {
+			t.Errorf("got %v (%v) instead of %v for %#v", lit, ok, td.lit, td.v)
+		}
+	}
+	if lit, _ := goLiteral([]reproColor{"a"}, "example.com/p"); lit != `[]rapid.reproColor{rapid.reproColor("a")}` {
+		t.Errorf("got %v for a type from another package", lit)
+	}
+
+	one := 1
+	for _, v := range []value{&one, reproHidden{1}, make(chan int), []*int{nil}, TestGoLiteral} {
+		if lit, ok := goLiteral(v, "pgregory.net/rapid"); ok {
+			t.Errorf("got literal %v for %#v", lit, v)
+		}
+	}
+}
+
+func TestReproCode_PropName(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, reproProp, MaxDuration(time.Minute), Pin("x", 11))
+	removeFailFiles(t.Name())
+
+	if out := tb.out.String(); !strings.Contains(out, "rapid.Replay(t, reproProp,") {
+		t.Errorf("property name not found in output:\n%v", out)
+	}
+}
+
+func TestReproCode_NotLiteral(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		c := SampledFrom([]reproColor{"red", "green"}).Draw(t, "c").(reproColor)
+		p := Ptr(Int(), false).Draw(t, "p").(*int)
+		if c == "green" && *p > 0 {
+			t.Fatalf("green")
+		}
+	})
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	for _, s := range []string{"// prop is the property passed to rapid.Check", "rapid.Replay(t, prop,", `reproColor("green")`, "nil /* TODO: *int, which can not be written as a literal */"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in output:\n%v", s, out)
+		}
+	}
+}
+
+func TestReproCode(t *testing.T) {
+	t.Parallel()
+
+	_, _, _, buf, err1, err2 := doCheck(t, newSettings(nil), baseSeed(), reproProp)
+	if err1 == nil || err2 == nil {
+		t.Fatalf("unexpected errors %v and %v", err1, err2)
+	}
+
+	code := reproCode(t, reproProp, reproProp, buf)
+	want := "func TestReproCode_Repro(t *testing.T) {\n\trapid.Replay(t, reproProp,\n\t\t11,\n\t\treproPair{A:0, B:0},\n\t)\n}"
+	if code != want {
+		t.Fatalf("got code\n%v\ninstead of\n%v", code, want)
+	}
+
+	nt := newT(t, newBufBitStream(nil, false), false, nil)
+	nt.replay = []value{11, reproPair{}}
+	err := checkOnce(nt, reproProp)
+	if err == nil || !strings.Contains(err.Error(), "x = 11") {
+		t.Fatalf("replay did not reproduce the failure: %v", err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	Replay(t, reproProp, 11, reproPair{-1, 0})
+	Replay(t, func(t *T) {})
+}
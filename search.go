@@ -0,0 +1,154 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import "strings"
+
+const mutateMaxWords = 4
+
+// corpus holds the test cases found interesting while searching for a bug
+// (e.g. because they have covered new code, or have been saved earlier),
+// to derive new test cases from.
+type corpus struct {
+	entries []recordedBits
+}
+
+func (c *corpus) add(rec *recordedBits) {
+	c.entries = append(c.entries, recordedBits{
+		data:    append([]uint64(nil), rec.data...),
+		groups:  append([]groupInfo(nil), rec.groups...),
+		persist: true,
+	})
+}
+
+// pick returns a random corpus entry, preferring the most recent one
+// (which is usually the most interesting so far).
+func (c *corpus) pick(ctx *jsf64ctx) *recordedBits {
+	if ctx.rand()&1 == 0 {
+		return &c.entries[len(c.entries)-1]
+	}
+	return &c.entries[randIntn(ctx, len(c.entries))]
+}
+
+// mutate derives a new test case from a random corpus entry, using one
+// of the structured mutations.
+func (c *corpus) mutate(ctx *jsf64ctx) []uint64 {
+	e := c.pick(ctx)
+	if len(e.data) == 0 {
+		return nil
+	}
+
+	switch randIntn(ctx, 5) {
+	case 0:
+		return mutateWords(e, ctx)
+	case 1:
+		return regenerateGroup(e, ctx)
+	case 2:
+		return duplicateGroup(e, ctx)
+	case 3:
+		return splice(e, c.pick(ctx), ctx)
+	default:
+		return crossover(e, c.pick(ctx), ctx)
+	}
+}
+
+// mutateWords replaces some of the words with random ones.
+func mutateWords(e *recordedBits, ctx *jsf64ctx) []uint64 {
+	buf := append([]uint64(nil), e.data...)
+	n := 1 + randIntn(ctx, mutateMaxWords)
+	for i := 0; i < n; i++ {
+		buf[randIntn(ctx, len(buf))] = ctx.rand()
+	}
+
+	return buf
+}
+
+// regenerateGroup replaces the data of a random group with random words,
+// which makes the group generate a fresh value.
+func regenerateGroup(e *recordedBits, ctx *jsf64ctx) []uint64 {
+	buf := append([]uint64(nil), e.data...)
+	g := randomGroup(e, ctx)
+	for i := g.begin; i < g.end; i++ {
+		buf[i] = ctx.rand()
+	}
+
+	return buf
+}
+
+// duplicateGroup inserts a copy of the data of a random group right after it,
+// which e.g. makes a collection contain one more copy of an element.
+func duplicateGroup(e *recordedBits, ctx *jsf64ctx) []uint64 {
+	g := randomGroup(e, ctx)
+	buf := make([]uint64, 0, len(e.data)+g.end-g.begin)
+	buf = append(buf, e.data[:g.end]...)
+	buf = append(buf, e.data[g.begin:g.end]...)
+	buf = append(buf, e.data[g.end:]...)
+
+	return buf
+}
+
+// splice joins the data of e before the start of a random group and the data
+// of f starting from a random group.
+func splice(e *recordedBits, f *recordedBits, ctx *jsf64ctx) []uint64 {
+	i := randomGroup(e, ctx).begin
+	j := len(f.data)
+	if len(f.groups) > 0 {
+		j = f.groups[randIntn(ctx, len(f.groups))].begin
+	}
+	buf := make([]uint64, 0, i+len(f.data)-j)
+	buf = append(buf, e.data[:i]...)
+	buf = append(buf, f.data[j:]...)
+
+	return buf
+}
+
+// crossover replaces the data of a random value group (a labeled draw, or
+// a value of a generator) of e with the data of a group of f with the same label,
+// so that one of the values of the test case comes from the other parent.
+// When the parents have no such groups in common, crossover falls back to splice.
+func crossover(e *recordedBits, f *recordedBits, ctx *jsf64ctx) []uint64 {
+	var labeled []groupInfo
+	for _, g := range e.groups {
+		if g.label != "" && (g.standalone || strings.HasPrefix(g.label, drawLabelPrefix)) {
+			labeled = append(labeled, g)
+		}
+	}
+	if len(labeled) == 0 {
+		return splice(e, f, ctx)
+	}
+
+	g := labeled[randIntn(ctx, len(labeled))]
+	var matching []groupInfo
+	for _, h := range f.groups {
+		if h.label == g.label {
+			matching = append(matching, h)
+		}
+	}
+	if len(matching) == 0 {
+		return splice(e, f, ctx)
+	}
+
+	h := matching[randIntn(ctx, len(matching))]
+	buf := make([]uint64, 0, len(e.data)-(g.end-g.begin)+(h.end-h.begin))
+	buf = append(buf, e.data[:g.begin]...)
+	buf = append(buf, f.data[h.begin:h.end]...)
+	buf = append(buf, e.data[g.end:]...)
+
+	return buf
+}
+
+// randomGroup returns a random group of e, or all the data when e has no groups.
+func randomGroup(e *recordedBits, ctx *jsf64ctx) groupInfo {
+	if len(e.groups) == 0 {
+		return groupInfo{begin: 0, end: len(e.data)}
+	}
+	return e.groups[randIntn(ctx, len(e.groups))]
+}
+
+func randIntn(ctx *jsf64ctx, n int) int {
+	return int(ctx.rand() % uint64(n))
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import "testing"
+
+func TestCorpusMutate(t *testing.T) {
+	t.Parallel()
+
+	g := SliceOf(MapOf(IntRange(-100, 100), String()))
+	c := &corpus{}
+	for seed := uint64(1); seed <= 5; seed++ {
+		s := newRandomBitStream(seed, true)
+		if _, err := recoverValue(g, newT(nil, s, false, nil)); err != nil {
+			t.Fatal(err)
+		}
+		c.add(&s.recordedBits)
+	}
+
+	var ctx jsf64ctx
+	ctx.init(baseSeed())
+	for i := 0; i < 1000; i++ {
+		s := newBufBitStream(c.mutate(&ctx), false)
+		s.zeroPad = true
+		_, err := recoverValue(g, newT(nil, s, false, nil))
+		if err != nil && !err.isInvalidData() {
+			t.Fatalf("mutated test case has failed to generate a value: %v", err)
+		}
+	}
+}
+
+func TestCheckSavedCaseAddsToCorpus(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		Int().Draw(t, "x")
+	}
+
+	c := &corpus{}
+	_, err1, err2 := checkSavedCase(t, "test case", testFailFile(t, prop, 0, []uint64{0, 5, 0, 5}), c, prop)
+	if err1 != nil || err2 != nil {
+		t.Fatalf("passing test case has failed: %v, %v", err1, err2)
+	}
+	if len(c.entries) != 1 || len(c.entries[0].groups) == 0 {
+		t.Fatalf("passing test case was not added to the corpus: %v", c.entries)
+	}
+}
+
+func TestCrossover(t *testing.T) {
+	t.Parallel()
+
+	draw := func(s bitStream) [2]int {
+		nt := newT(nil, s, false, nil)
+		return [2]int{Int().Draw(nt, "x").(int), Int().Draw(nt, "y").(int)}
+	}
+
+	var parents []*recordedBits
+	var values [][2]int
+	for seed := uint64(1); len(parents) < 2; seed++ {
+		s := newRandomBitStream(seed, true)
+		v := draw(s)
+		if len(values) > 0 && (v[0] == values[0][0] || v[1] == values[0][1]) {
+			continue
+		}
+		parents = append(parents, &s.recordedBits)
+		values = append(values, v)
+	}
+
+	var ctx jsf64ctx
+	ctx.init(baseSeed())
+	mixed := false
+	for i := 0; i < 100; i++ {
+		s := newBufBitStream(crossover(parents[0], parents[1], &ctx), false)
+		v := draw(s)
+		for _, u := range v {
+			if u != values[0][0] && u != values[0][1] && u != values[1][0] && u != values[1][1] {
+				t.Fatalf("got %v from crossover of %v and %v", v, values[0], values[1])
+			}
+		}
+		mixed = mixed || v[0] != values[0][0] || v[1] != values[0][1]
+	}
+	if !mixed {
+		t.Fatalf("crossover has never used the values of the second parent")
+	}
+}
+
+func TestFindBugTarget(t *testing.T) {
+	t.Parallel()
+
+	prop := func(target bool) func(*T) {
+		return func(t *T) {
+			xs := SliceOf(IntRange(0, 100)).Draw(t, "xs").([]int)
+			sum := 0
+			for _, x := range xs {
+				sum += x
+			}
+			if target {
+				t.Target(float64(sum))
+			}
+			if sum > 5000 {
+				t.Fatalf("sum is %v", sum)
+			}
+		}
+	}
+
+	_, _, _, _, err := findBug(t, &settings{checks: 1000}, &corpus{}, baseSeed(), prop(false))
+	if err != nil {
+		t.Fatalf("large sum found without targeting: %v", err)
+	}
+
+	_, _, _, _, err = findBug(t, &settings{checks: 5000}, &corpus{}, baseSeed(), prop(true))
+	if err == nil {
+		t.Fatal("large sum not found with targeting")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+)
+
+// RegisterSensitive makes rapid replace the values of type typ (and their elements
+// in slices, arrays and pointers) with hashes when describing them in failures,
+// logs and exported reports (see RegisterPrinter, which it replaces for typ).
+// The persisted failing test cases still reproduce the values exactly.
+func RegisterSensitive(typ reflect.Type) {
+	RegisterPrinter(typ, redact)
+
+	printersMu.Lock()
+	defer printersMu.Unlock()
+
+	sensitiveTypes[typ] = true
+}
+
+// sensitiveType reports whether the values of typ are redacted, directly
+// or element by element.
+func sensitiveType(typ reflect.Type) bool {
+	printersMu.RLock()
+	defer printersMu.RUnlock()
+
+	for {
+		if sensitiveTypes[typ] {
+			return true
+		}
+		switch typ.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Ptr:
+			typ = typ.Elem()
+		default:
+			return false
+		}
+	}
+}
+
+// redact renders v as a hash, so that equal values can still be recognized.
+func redact(v interface{}) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%T\x00%#v", v, v)))
+	return fmt.Sprintf("<sensitive %x>", sum[:6])
+}
+
+func sensitive(g *Generator) *Generator {
+	return newGenerator(&sensitiveGen{g: g})
+}
+
+type sensitiveGen struct {
+	g *Generator
+}
+
+func (g *sensitiveGen) String() string {
+	return "Sensitive(<redacted>)" // descriptions of g can include its values
+}
+
+func (g *sensitiveGen) type_() reflect.Type {
+	return g.g.type_()
+}
+
+func (g *sensitiveGen) value(t *T) value {
+	v := g.g.value(t)
+	t.sensitive = true // redacts the values built from v as well
+
+	return v
+}
+
+func isSensitive(g *Generator) bool {
+	_, ok := g.impl.(*sensitiveGen)
+	return ok
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type apiKey string
+
+func init() {
+	RegisterSensitive(reflect.TypeOf(apiKey("")))
+}
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+
+	if redact("a") != redact("a") || redact("a") == redact("b") {
+		t.Fatalf("hashes of a and b: %v, %v", redact("a"), redact("b"))
+	}
+
+	for _, v := range []interface{}{apiKey("s3cr3t"), []apiKey{"s3cr3t"}, Diff(apiKey("s3cr3t"), apiKey("other"))} {
+		if s := printValue(v); strings.Contains(s, "s3cr3t") || !strings.Contains(s, "<sensitive ") {
+			t.Errorf("%v is not redacted", s)
+		}
+	}
+}
+
+func TestSensitive(t *testing.T) {
+	prop := func(t *T) {
+		password := Just("hunter2").Sensitive().Draw(t, "password").(string)
+		key := Just(apiKey("s3cr3t")).Draw(t, "key").(apiKey)
+		if len(password) > 0 && len(key) > 0 {
+			t.Fatal("leaked")
+		}
+	}
+
+	tb := &logTB{T: t}
+	checkTB(tb, prop)
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	if !strings.Contains(out, "draw password: <sensitive ") || !strings.Contains(out, "draw key: <sensitive ") {
+		t.Errorf("draws are not redacted in output:\n%v", out)
+	}
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "s3cr3t") {
+		t.Errorf("sensitive values found in output:\n%v", out)
+	}
+
+	_, _, seed, buf, _, err := doCheck(t, newSettings(nil), baseSeed(), prop)
+	if err == nil {
+		t.Fatal("property did not fail")
+	}
+	ce := makeCounterexample(t, prop, seed, buf)
+	for _, d := range ce.Draws {
+		if d.Value != nil || !strings.HasPrefix(d.Repr, "<sensitive ") {
+			t.Errorf("draw %v is not redacted: %v %s", d.Label, d.Repr, d.Value)
+		}
+	}
+	draws, _ := topLevelDraws(t, prop, buf)
+	if len(draws) != 2 || draws[0].v != "hunter2" || draws[1].v != apiKey("s3cr3t") {
+		t.Errorf("failing test case does not reproduce the values: %v", draws)
+	}
+}
+
+type sensitiveUser struct {
+	Name     string
+	Password string
+}
+
+func TestSensitive_Nested(t *testing.T) {
+	pw := SampledFrom([]string{"hunter2", "letmein"}).Sensitive()
+	prop := func(t *T) {
+		user := Custom(func(t *T) sensitiveUser {
+			return sensitiveUser{Name: StringOf(RuneFrom([]rune("AB"))).Draw(t, "name").(string), Password: pw.Draw(t, "pw").(string)}
+		}).Draw(t, "user").(sensitiveUser)
+		pws := SliceOfN(pw, 1, 2).Draw(t, "pws").([]string)
+		n := IntRange(0, 10).Draw(t, "n").(int)
+		if user.Password != "" && len(pws) > 0 {
+			t.Fatalf("n = %v", n)
+		}
+	}
+
+	tb := &logTB{T: t}
+	checkTB(tb, prop)
+	out := tb.out.String()
+	files, _ := filepath.Glob(filepath.Join(failFileDir(t.Name()), "*"))
+	for _, file := range files {
+		data, _ := ioutil.ReadFile(file)
+		out += string(data)
+	}
+	removeFailFiles(t.Name())
+
+	for _, s := range []string{"draw user: <sensitive ", "draw pws: <sensitive ", "draw n: 0"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in output:\n%v", s, out)
+		}
+	}
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "letmein") {
+		t.Errorf("sensitive values found in output:\n%v", out)
+	}
+	if s := pw.String(); strings.Contains(s, "hunter2") {
+		t.Errorf("sensitive values found in generator description %q", s)
+	}
+}
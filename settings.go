@@ -0,0 +1,449 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"testing"
+	"time"
+)
+
+// Option customizes the behavior of Check and MakeCheck. Options take precedence
+// over the corresponding -rapid.* command-line flags.
+type Option func(*settings)
+
+type settings struct {
+	checks          int
+	failfile        string
+	shrinkTime      time.Duration
+	shrinkAttempts  int
+	shrinkWorkers   int
+	onShrink        func(ShrinkStep)
+	onFailure       func(Counterexample)
+	shrinkPasses    []string
+	exportDir       string
+	dotDir          string
+	traceDir        string
+	drawTraceDir    string
+	fuzzName        string
+	coverage        func() float64 // if not nil, guides the search for a bug
+	maxDuration     time.Duration
+	actionTimeout   time.Duration
+	maxValueLen     int
+	maxReportLen    int
+	valueDir        string
+	htmlReport      string
+	annotations     bool
+	metadata        PropertyMetadata
+	pins            map[string]interface{}
+	exhaustiveSteps int
+	exhaustive      int
+	vacuity         bool
+	mutations       []mutation
+	resume          []uint64 // data every generated test case starts with
+	resumeHash      uint32   // hash of the name of the test resume was captured in
+	complexity      bool
+	requiredEvents  []eventRequirement
+	requiredActions []actionRequirement
+	maxDiscardRatio float64
+	degenerateRatio float64
+	failDegenerate  bool
+	stats           *runStats // collected during the run
+	original        []uint64  // data of the failing test case before minimization, set during the run
+	caseIndex       int       // index (from 1) of the test case being generated, set during the run
+	caseSeed        uint64    // seed of the test case being generated (0 if not generated from a seed), set during the run
+	db              ExampleDatabase
+}
+
+func newSettings(opts []Option) *settings {
+	s := &settings{
+		checks:          flags.checks,
+		failfile:        flags.failfile,
+		shrinkTime:      flags.shrinkTime,
+		shrinkAttempts:  flags.shrinkAttempts,
+		shrinkWorkers:   1,
+		exportDir:       flags.exportDir,
+		dotDir:          flags.dotDir,
+		traceDir:        flags.traceDir,
+		drawTraceDir:    flags.drawTraceDir,
+		maxDuration:     flags.maxDuration,
+		actionTimeout:   flags.actionTimeout,
+		maxValueLen:     flags.maxValueLen,
+		maxReportLen:    flags.maxReportLen,
+		valueDir:        flags.valueDir,
+		htmlReport:      flags.htmlReport,
+		annotations:     flags.annotations,
+		complexity:      flags.complexity,
+		vacuity:         flags.vacuity,
+		degenerateRatio: degenerateRatio,
+		stats:           &runStats{},
+	}
+	if flags.coverage {
+		GuideByCoverage()(s)
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// ShrinkParallel marks the property as safe for concurrent execution, and allows
+// rapid to evaluate up to workers independent shrink candidates at once.
+// Candidates are always considered in a fixed order, so the minimized test case
+// does not depend on the number of workers.
+func ShrinkParallel(workers int) Option {
+	assertf(workers > 0, "number of shrink workers should be positive, not %v", workers)
+
+	return func(s *settings) {
+		s.shrinkWorkers = workers
+	}
+}
+
+// ShrinkTime limits the time rapid spends on minimization of a failing test case.
+// Zero means no limit. Unlike ShrinkAttempts, a time limit makes the minimized
+// test case depend on the speed of the machine running the test; the default
+// limit (-rapid.shrinktime, 30s) is only a safety net for slow properties, which
+// the default attempt budget normally exhausts first.
+func ShrinkTime(d time.Duration) Option {
+	return func(s *settings) {
+		s.shrinkTime = d
+	}
+}
+
+// ShrinkAttempts limits the number of smaller test cases rapid tries while minimizing
+// a failing test case. Zero means no limit.
+func ShrinkAttempts(n int) Option {
+	assertf(n >= 0, "number of shrink attempts should not be negative, not %v", n)
+
+	return func(s *settings) {
+		s.shrinkAttempts = n
+	}
+}
+
+// OnShrink registers a callback which is called after every accepted step
+// of failing test case minimization, e.g. to monitor the progress of a long shrink.
+func OnShrink(fn func(ShrinkStep)) Option {
+	return func(s *settings) {
+		s.onShrink = fn
+	}
+}
+
+// OnCounterexample registers a callback which is called with the minimized
+// failing test case of the check, e.g. to post-process failures in tools
+// which wrap rapid, without parsing its output.
+func OnCounterexample(fn func(Counterexample)) Option {
+	return func(s *settings) {
+		s.onFailure = fn
+	}
+}
+
+// ShrinkPasses selects the passes (by name) which form the failing test case
+// minimization pipeline, and the order in which they are run.
+// By default, all registered passes are used, in order of registration.
+func ShrinkPasses(names ...string) Option {
+	return func(s *settings) {
+		s.shrinkPasses = names
+	}
+}
+
+// MaxDuration makes rapid treat test cases which take longer than d as failing,
+// and search for the slowest test cases; the failing test case is then minimized
+// as usual. This allows to find inputs which trigger unexpectedly slow paths
+// (e.g. quadratic algorithms, or regular expression backtracking).
+// Zero means no limit.
+func MaxDuration(d time.Duration) Option {
+	assertf(d >= 0, "maximum duration should not be negative, not %v", d)
+
+	return func(s *settings) {
+		s.maxDuration = d
+	}
+}
+
+// ActionTimeout makes rapid treat state machine test cases with an action
+// which has not finished in d (e.g. because of a deadlock) as failing, reporting
+// the stacks of all goroutines. Blocked actions are abandoned, and keep blocking
+// in the background; as they may still use their test case, the check stops at
+// the first of them, without minimizing it. Zero means no limit.
+func ActionTimeout(d time.Duration) Option {
+	assertf(d >= 0, "action timeout should not be negative, not %v", d)
+
+	return func(s *settings) {
+		s.actionTimeout = d
+	}
+}
+
+// MaxValueLength limits the rendered length of every drawn value in the output
+// of rapid to about n bytes: longer values lose their last elements (or bytes),
+// replaced by a "… N more elements" (or "… N more bytes") marker.
+// Zero means no limit.
+func MaxValueLength(n int) Option {
+	assertf(n >= 0, "maximum value length should not be negative, not %v", n)
+
+	return func(s *settings) {
+		s.maxValueLen = n
+	}
+}
+
+// MaxReportLength limits the output logged by a failed test case to n bytes;
+// the length of the rest is reported instead. Zero means no limit.
+func MaxReportLength(n int) Option {
+	assertf(n >= 0, "maximum report length should not be negative, not %v", n)
+
+	return func(s *settings) {
+		s.maxReportLen = n
+	}
+}
+
+// ExportTruncatedValues makes rapid write every drawn value it has truncated
+// in the output (see MaxValueLength) in full to a file in dir.
+// Empty dir disables the export.
+func ExportTruncatedValues(dir string) Option {
+	return func(s *settings) {
+		s.valueDir = dir
+	}
+}
+
+// HTMLReport makes rapid add the check to a self-contained HTML report
+// of all the checks of the run (e.g. of a package), written to filename
+// after every check: the outcomes and test case counts, the statistics
+// (see Event, Collect, Observe and Run), and the failures with their
+// minimized test cases. Empty filename disables the report.
+func HTMLReport(filename string) Option {
+	return func(s *settings) {
+		s.htmlReport = filename
+	}
+}
+
+// GitHubAnnotations makes rapid write every failure to stdout as a GitHub Actions
+// error annotation, which shows the failure, with the minimized test case, inline
+// on the diff of the pull request (at the location of the failure in the property).
+func GitHubAnnotations() Option {
+	return func(s *settings) {
+		s.annotations = true
+	}
+}
+
+// Metadata attaches the description, the owner and the link (e.g. to the issue,
+// or the requirement) to the property, for the failure output and the reports
+// (see ExportCounterexamples, HTMLReport, GitHubAnnotations and OnCounterexample).
+func Metadata(m PropertyMetadata) Option {
+	return func(s *settings) {
+		s.metadata = m
+	}
+}
+
+// Pin makes every draw with the label (or the label path, like "users[2].name",
+// of a nested draw) return value, while all the other draws are generated as usual,
+// e.g. to test the hypotheses about which part of the input triggers a failure.
+// Draws can also be pinned using the RAPID_PIN environment variable, set to
+// label=value pairs separated by semicolons, with the values encoded as JSON
+// (like RAPID_PIN='n=17;name="x"'); options take precedence over it.
+func Pin(label string, value interface{}) Option {
+	return func(s *settings) {
+		if s.pins == nil {
+			s.pins = map[string]interface{}{}
+		}
+		s.pins[label] = value
+	}
+}
+
+// ExhaustiveSteps makes rapid check state machine tests (see Run) with every
+// sequence of at most k actions (shortest first, skipping the sequences with
+// an action which can not be executed) before generating random test cases.
+// This guarantees the coverage of all short sequences for APIs with few
+// operations, but the number of sequences grows exponentially with k. Only
+// the sequences of actions are enumerated: the values the actions draw are
+// random, so actions with small argument domains benefit the most.
+func ExhaustiveSteps(k int) Option {
+	assertf(k >= 0, "number of exhaustive steps should not be negative, not %v", k)
+
+	return func(s *settings) {
+		s.exhaustiveSteps = k
+	}
+}
+
+// Exhaustive makes rapid check the property with every combination of the
+// values it draws (each exactly once, instead of random ones) when all of them
+// come from the small finite domains of Bool, integer ranges, SampledFrom
+// (e.g. of the values of an enum) and Just, also through Map and Custom,
+// and there are at most maxCases combinations. Passing the exhaustive check
+// proves the property, and no random test cases are generated. Otherwise,
+// the property is checked with random test cases as usual.
+func Exhaustive(maxCases int) Option {
+	assertf(maxCases > 0, "number of exhaustive test cases should be positive, not %v", maxCases)
+
+	return func(s *settings) {
+		s.exhaustive = maxCases
+	}
+}
+
+// CheckVacuity makes rapid verify that the property, once it has passed the
+// check, can fail at all: rapid checks it again with the value of one of
+// the top-level draws corrupted (e.g. replaced with the zero value, or the
+// largest one), and fails the test if none of these test cases fails. This
+// catches the assertions which have become tautologies, but only if they
+// depend on the drawn values; a known bad mutation (see KnownBadMutation)
+// is more reliable.
+func CheckVacuity() Option {
+	return func(s *settings) {
+		s.vacuity = true
+	}
+}
+
+// GuideByCoverage makes rapid keep the test cases which cover new code, and
+// mutate them to produce new ones (see Check). It has no effect unless the test
+// binary is built with coverage instrumentation (go test -cover).
+func GuideByCoverage() Option {
+	return func(s *settings) {
+		if testing.CoverMode() != "" {
+			s.coverage = testing.Coverage
+		}
+	}
+}
+
+// KnownBadMutation makes rapid verify that the property, once it has passed
+// the check, detects a known bug: inject introduces the bug into the code
+// under test (e.g. by replacing a function variable) and returns the function
+// which removes it. rapid checks the property again with the bug, and fails
+// the test if no test case fails.
+func KnownBadMutation(name string, inject func() (undo func())) Option {
+	assertf(inject != nil, "known bad mutation %q should not be nil", name)
+
+	return func(s *settings) {
+		s.mutations = append(s.mutations, mutation{name: name, inject: inject})
+	}
+}
+
+// ResumeFrom makes every generated test case start with the data drawn before
+// the snapshot token was returned by (*T).Snapshot, so that the property reaches
+// the state of the snapshot before drawing new random data. The snapshot should
+// come from the same test, and is ignored otherwise. Snapshots are not stable
+// across changes to the property.
+func ResumeFrom(token string) Option {
+	testHash, _, buf, err := decodeReplayToken(token)
+	assertf(err == nil, "invalid snapshot: %v", err)
+
+	return func(s *settings) {
+		s.resume = buf
+		s.resumeHash = testHash
+	}
+}
+
+// EstimateComplexity makes rapid time the test cases, and report the apparent
+// complexity of the property (as a function of the amount of data the test case
+// uses). The complexity is saved as a baseline under testdata/rapid, and rapid
+// warns when the complexity of later runs differs from it.
+func EstimateComplexity() Option {
+	return func(s *settings) {
+		s.complexity = true
+	}
+}
+
+// RequireEvent makes the run fail when less than percent of the test cases
+// have reported the event with (*T).Event or (*T).Classify, e.g. because
+// the generators never exercise an important scenario.
+func RequireEvent(event string, percent float64) Option {
+	assertf(percent >= 0 && percent <= 100, "required percentage should be between 0 and 100, not %v", percent)
+
+	return func(s *settings) {
+		s.requiredEvents = append(s.requiredEvents, eventRequirement{event, percent})
+	}
+}
+
+// RequireAction makes the run fail when less than percent of the executed steps
+// of the state machine test (see Run) were the action, e.g. because its
+// precondition rarely holds and the action is effectively never tested.
+func RequireAction(action string, percent float64) Option {
+	assertf(percent >= 0 && percent <= 100, "required percentage should be between 0 and 100, not %v", percent)
+
+	return func(s *settings) {
+		s.requiredActions = append(s.requiredActions, actionRequirement{action, percent})
+	}
+}
+
+// MaxDiscardRatio makes the run fail when the ratio of discarded test cases
+// (because of filters that could not find a suitable value, or calls to (*T).Skip)
+// to all test cases is higher than ratio. Zero means no limit.
+func MaxDiscardRatio(ratio float64) Option {
+	assertf(ratio >= 0 && ratio <= 1, "maximum discard ratio should be between 0 and 1, not %v", ratio)
+
+	return func(s *settings) {
+		s.maxDiscardRatio = ratio
+	}
+}
+
+// FailOnDegenerate makes the run fail when the same value was drawn under
+// a label in more than ratio of the draws, which usually means that the generator
+// (e.g. a Custom one) ignores its input. By default, rapid only warns when
+// the ratio is higher than 0.9.
+func FailOnDegenerate(ratio float64) Option {
+	assertf(ratio >= 0 && ratio < 1, "degenerate ratio should be between 0 and 1, not %v", ratio)
+
+	return func(s *settings) {
+		s.degenerateRatio = ratio
+		s.failDegenerate = true
+	}
+}
+
+// ExportCounterexamples makes rapid write every failing test case (after
+// minimization) to a JSON file in dir, for consumption by other tools.
+// Empty dir disables the export.
+func ExportCounterexamples(dir string) Option {
+	return func(s *settings) {
+		s.exportDir = dir
+	}
+}
+
+// ExportStateMachineGraphs makes rapid write the steps of every failing
+// state machine test case (after minimization) to a Graphviz DOT file in dir,
+// with states as nodes and actions as edges. States are labeled using
+// the String method of the state machine, if it has one.
+// Empty dir disables the export.
+func ExportStateMachineGraphs(dir string) Option {
+	return func(s *settings) {
+		s.dotDir = dir
+	}
+}
+
+// ExportStateMachineTraces makes rapid write the steps of every failing
+// state machine test case (after minimization) to a trace file in dir:
+// a human-editable list of the actions and of the values they have drawn,
+// which can be run against the system under test using ReplayTrace.
+// Empty dir disables the export.
+func ExportStateMachineTraces(dir string) Option {
+	return func(s *settings) {
+		s.traceDir = dir
+	}
+}
+
+// ExportDrawTraces makes rapid write every draw of every failing test case
+// (after minimization), nested ones included, to a JSON file in dir: the path,
+// generator, value and number of bits of each draw, in the order they begin.
+// Empty dir disables the export.
+func ExportDrawTraces(dir string) Option {
+	return func(s *settings) {
+		s.drawTraceDir = dir
+	}
+}
+
+// FuzzCorpus makes rapid add every failing test case (after minimization)
+// to the seed corpus of the fuzz test fuzzName, which checks the same property
+// using MakeFuzz. This way, native fuzzing starts from the failures rapid has found.
+func FuzzCorpus(fuzzName string) Option {
+	return func(s *settings) {
+		s.fuzzName = fuzzName
+	}
+}
+
+// Database makes rapid replay the failing test cases saved in db (by test name)
+// before trying new ones, and save every new failing test case to db.
+func Database(db ExampleDatabase) Option {
+	return func(s *settings) {
+		s.db = db
+	}
+}
@@ -7,12 +7,14 @@
 package rapid
 
 import (
-	"encoding/binary"
+	"bufio"
 	"fmt"
+	"io"
 	"math"
 	"math/bits"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,6 +22,9 @@ const (
 	labelLowerFloatExp       = "lower_float_exp"
 	labelLowerFloatSignif    = "lower_float_signif"
 	labelLowerFloatFrac      = "lower_float_frac"
+	labelLowerIntSize        = "lower_int_size"
+	labelSelectShrinkHint    = "select_shrink_hint"
+	labelSimplifyFloat       = "simplify_float"
 	labelMinBlockBinSearch   = "minblock_binsearch"
 	labelMinBlockShift       = "minblock_shift"
 	labelMinBlockSort        = "minblock_sort"
@@ -28,24 +33,235 @@ const (
 	labelRemoveGroup         = "remove_group"
 	labelRemoveGroupAndLower = "remove_group_lower"
 	labelRemoveGroupSpan     = "remove_groupspan"
+	labelRemoveChunk         = "remove_chunk"
+	labelLowerSiblings       = "lower_siblings"
+	labelSimplifyRune        = "simplify_rune"
+	labelZeroLabel           = "zero_label"
+	labelResetDraw           = "reset_draw"
+	labelZeroMapEntry        = "zero_map_entry"
 	labelSortGroups          = "sort_groups"
+	labelMergeSteps          = "merge_steps"
+	labelRemoveStepPair      = "remove_step_pair"
+	labelRemoveActor         = "remove_actor"
+
+	passRemoveGroups      = "remove_groups"
+	passSimplifyRunes     = "simplify_runes"
+	passZeroMapEntries    = "zero_map_entries"
+	passZeroLabels        = "zero_labels"
+	passResetDraws        = "reset_draws"
+	passMinimizeBlocks    = "minimize_blocks"
+	passLowerFloat        = "lower_float"
+	passLowerInt          = "lower_int"
+	passRemoveGroupsLower = "remove_groups_lower"
+	passSortGroups        = "sort_groups"
+	passRemoveGroupSpans  = "remove_group_spans"
+	passRemoveChunks      = "remove_chunks"
+	passLowerSiblings     = "lower_siblings"
+	passMergeSteps        = "merge_steps"
+	passRemoveStepPairs   = "remove_step_pairs"
+	passRemoveActors      = "remove_actors"
+	passShrinkHints       = "shrink_hints"
+	passSimplifyFloats    = "simplify_floats"
+
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+var (
+	shrinkPassesMu sync.RWMutex
+	shrinkPasses   = []shrinkPass{
+		{passRemoveChunks, false, (*shrinker).removeChunks},
+		{passRemoveGroups, false, (*shrinker).removeGroups},
+		{passMergeSteps, false, (*shrinker).mergeSteps},
+		{passRemoveActors, false, (*shrinker).removeActors},
+		{passSimplifyRunes, false, (*shrinker).simplifyRunes},
+		{passMinimizeBlocks, false, (*shrinker).minimizeBlocks},
+		{passLowerFloat, true, (*shrinker).lowerFloatHack},
+		{passLowerInt, true, (*shrinker).lowerIntHack},
+		{passRemoveGroupsLower, true, (*shrinker).removeGroupsAndLower},
+		{passSortGroups, true, (*shrinker).sortGroups},
+		{passRemoveGroupSpans, true, (*shrinker).removeGroupSpans},
+		{passRemoveStepPairs, true, (*shrinker).removeStepPairs},
+		{passLowerSiblings, true, (*shrinker).lowerSiblings},
+		{passZeroMapEntries, true, (*shrinker).zeroMapEntries},
+		{passZeroLabels, true, (*shrinker).zeroLabels},
+		{passResetDraws, true, (*shrinker).resetDraws},
+		{passShrinkHints, true, (*shrinker).shrinkHints},
+		{passSimplifyFloats, true, (*shrinker).simplifyFloats},
+	}
 )
 
-func shrink(tb tb, rec recordedBits, err *testError, prop func(*T)) ([]uint64, *testError) {
+type shrinkPass struct {
+	name      string
+	expensive bool
+	run       func(*shrinker)
+}
+
+// ShrinkPass is a single step of the failing test case minimization pipeline.
+// On every round, rapid runs all cheap passes in order; expensive passes are
+// only run when cheap ones were unable to make any progress.
+type ShrinkPass struct {
+	Name      string
+	Expensive bool
+	Run       func(*ShrinkState)
+}
+
+// ShrinkState is the view of the failing test case given to a ShrinkPass.
+type ShrinkState struct {
+	s *shrinker
+}
+
+// ShrinkGroup describes a group of bitstream blocks which were drawn together.
+type ShrinkGroup struct {
+	Begin      int    // index of the first block of the group
+	End        int    // index after the last block of the group
+	Label      string // label of the group
+	Standalone bool   // group corresponds to a whole value, and can be removed independently
+}
+
+// RegisterShrinkPass appends p to the default minimization pipeline, replacing
+// the pass with the same name, if any. Built-in passes are named
+// remove_chunks, remove_groups, merge_steps, remove_actors, simplify_runes,
+// minimize_blocks, lower_float, lower_int, remove_groups_lower, sort_groups,
+// remove_group_spans, remove_step_pairs, lower_siblings, zero_map_entries,
+// zero_labels, reset_draws, shrink_hints and simplify_floats.
+func RegisterShrinkPass(p ShrinkPass) {
+	assertf(p.Name != "", "shrink pass should have a name")
+	assertf(p.Run != nil, "shrink pass %q should have a Run function", p.Name)
+
+	pass := shrinkPass{
+		name:      p.Name,
+		expensive: p.Expensive,
+		run: func(s *shrinker) {
+			p.Run(&ShrinkState{s: s})
+		},
+	}
+
+	shrinkPassesMu.Lock()
+	defer shrinkPassesMu.Unlock()
+
+	for i := range shrinkPasses {
+		if shrinkPasses[i].name == p.Name {
+			shrinkPasses[i] = pass
+			return
+		}
+	}
+	shrinkPasses = append(shrinkPasses, pass)
+}
+
+// shrinkPipeline returns the registered passes with the specified names, in order,
+// or all registered passes if no names are given.
+func shrinkPipeline(names []string) []shrinkPass {
+	shrinkPassesMu.RLock()
+	defer shrinkPassesMu.RUnlock()
+
+	if len(names) == 0 {
+		return append([]shrinkPass(nil), shrinkPasses...)
+	}
+
+	var passes []shrinkPass
+	for _, name := range names {
+		found := false
+		for _, p := range shrinkPasses {
+			if p.name == name {
+				passes = append(passes, p)
+				found = true
+				break
+			}
+		}
+		assertf(found, "unknown shrink pass %q", name)
+	}
+
+	return passes
+}
+
+// Data returns a copy of the blocks of the current failing test case.
+func (st *ShrinkState) Data() []uint64 {
+	return append([]uint64(nil), st.s.rec.data...)
+}
+
+// Groups returns the groups of blocks of the current failing test case,
+// ordered by their first block.
+func (st *ShrinkState) Groups() []ShrinkGroup {
+	groups := make([]ShrinkGroup, 0, len(st.s.rec.groups))
+	for _, g := range st.s.rec.groups {
+		if g.end < 0 {
+			continue
+		}
+		groups = append(groups, ShrinkGroup{
+			Begin:      g.begin,
+			End:        g.end,
+			Label:      g.label,
+			Standalone: g.standalone,
+		})
+	}
+
+	return groups
+}
+
+// Try checks if buf still reproduces the failure, and makes it the current
+// failing test case if it does. Only candidates which are smaller than the
+// current test case (shorter, or lexicographically smaller) are considered.
+func (st *ShrinkState) Try(buf []uint64, desc string) bool {
+	return st.s.accept(buf, st.s.pass, "", "%v", desc)
+}
+
+// Done reports if the minimization budget has been exhausted.
+func (st *ShrinkState) Done() bool {
+	return st.s.done()
+}
+
+func shrink(tb tb, cfg *settings, rec recordedBits, err *testError, prop func(*T)) ([]uint64, *testError) {
+	tb.Helper()
+
 	rec.prune()
 
 	s := &shrinker{
-		tb:      tb,
-		rec:     rec,
-		err:     err,
-		prop:    prop,
-		visBits: []recordedBits{rec},
-		tries:   map[string]int{},
-		cache:   map[string]struct{}{},
+		tb:          tb,
+		rec:         rec,
+		err:         err,
+		prop:        prop,
+		workers:     cfg.shrinkWorkers,
+		start:       time.Now(),
+		maxAttempts: cfg.shrinkAttempts,
+		onShrink:    cfg.onShrink,
+		passes:      shrinkPipeline(cfg.shrinkPasses),
+		visBits:     []recordedBits{rec},
+		tries:       map[string]int{},
+		cache:       map[uint64][]uint64{},
+	}
+
+	if cfg.shrinkTime > 0 {
+		s.deadline = s.start.Add(cfg.shrinkTime)
+	}
+
+	if flags.debugshrink {
+		name := fmt.Sprintf("shrink-%v.txt", strings.Replace(tb.Name(), "/", "_", -1))
+		f, err := os.Create(name)
+		if err != nil {
+			tb.Logf("failed to create debugshrink file %v: %v", name, err)
+		} else {
+			w := bufio.NewWriter(f)
+			defer func() {
+				if err := w.Flush(); err != nil {
+					tb.Logf("failed to write debugshrink file %v: %v", name, err)
+				}
+				_ = f.Close()
+			}()
+
+			s.history = w
+			_, _ = fmt.Fprintf(w, "initial test case (%v blocks): %v\n", len(rec.data), rec.data)
+		}
 	}
 
 	buf, err := s.shrink()
 
+	if s.done() {
+		tb.Logf("[rapid] minimization stopped after %v attempts (%v) because shrink budget was exhausted; failing test case may not be minimal", s.attempts, time.Since(s.start))
+	} else {
+		tb.Logf("[rapid] minimized failing test case in %v attempts (%v)", s.attempts, time.Since(s.start))
+	}
+
 	if flags.debugvis {
 		name := fmt.Sprintf("vis-%v.html", strings.Replace(tb.Name(), "/", "_", -1))
 		f, err := os.Create(name)
@@ -64,15 +280,47 @@ func shrink(tb tb, rec recordedBits, err *testError, prop func(*T)) ([]uint64, *
 }
 
 type shrinker struct {
-	tb      tb
-	rec     recordedBits
-	err     *testError
-	prop    func(*T)
-	visBits []recordedBits
-	tries   map[string]int
-	shrinks int
-	cache   map[string]struct{}
-	hits    int
+	tb          tb
+	rec         recordedBits
+	err         *testError
+	prop        func(*T)
+	workers     int
+	start       time.Time
+	deadline    time.Time
+	maxAttempts int
+	attempts    int
+	onShrink    func(ShrinkStep)
+	passes      []shrinkPass
+	pass        string
+	visBits     []recordedBits
+	tries       map[string]int
+	shrinks     int
+	roundStart  int                 // number of shrinks before the current round
+	cache       map[uint64][]uint64 // candidates already tried, by dataHash
+	hits        int
+	filtered    int
+	filterRun   int
+	repairing   bool
+	history     io.Writer
+}
+
+// ShrinkStep describes a single accepted step of failing test case minimization.
+type ShrinkStep struct {
+	Pass       string        // name of the shrink pass which made the step
+	Group      string        // label of the affected group, if any
+	Draw       string        // label of the affected top-level draw, if any
+	SizeBefore int           // test case size (in bitstream blocks) before the step
+	SizeAfter  int           // test case size (in bitstream blocks) after the step
+	Attempts   int           // total number of shrink attempts so far
+	Elapsed    time.Duration // time since the start of minimization
+}
+
+type shrinkCandidate struct {
+	buf    []uint64
+	label  string
+	group  string
+	format string
+	args   []interface{}
 }
 
 func (s *shrinker) debugf(verbose_ bool, format string, args ...interface{}) {
@@ -82,28 +330,34 @@ func (s *shrinker) debugf(verbose_ bool, format string, args ...interface{}) {
 	}
 }
 
+// done reports whether the shrink budget is exhausted. Only the time limit
+// makes the result of minimization depend on the machine speed.
+func (s *shrinker) done() bool {
+	return (s.maxAttempts > 0 && s.attempts >= s.maxAttempts) || (!s.deadline.IsZero() && !time.Now().Before(s.deadline))
+}
+
 func (s *shrinker) shrink() (buf []uint64, err *testError) {
 	defer func() {
 		if r := recover(); r != nil {
-			buf, err = s.rec.data, r.(*testError)
+			e, ok := r.(*testError)
+			if !ok {
+				panic(r) // not a failure of the property
+			}
+			buf, err = s.rec.data, e
 		}
 	}()
 
 	i := 0
-	deadline := time.Now().Add(flags.shrinkTime)
-	for shrinks := -1; s.shrinks > shrinks && time.Now().Before(deadline); i++ {
+	for shrinks := -1; s.shrinks > shrinks && !s.done(); i++ {
 		shrinks = s.shrinks
+		s.roundStart = shrinks
 
 		s.debugf(false, "round %v start", i)
-		s.removeGroups(deadline)
-		s.minimizeBlocks(deadline)
+		s.runPasses(false)
 
 		if s.shrinks == shrinks {
 			s.debugf(false, "trying expensive algorithms for round %v", i)
-			s.lowerFloatHack(deadline)
-			s.removeGroupsAndLower(deadline)
-			s.sortGroups(deadline)
-			s.removeGroupSpans(deadline)
+			s.runPasses(true)
 		}
 	}
 
@@ -111,36 +365,234 @@ func (s *shrinker) shrink() (buf []uint64, err *testError) {
 	for _, n := range s.tries {
 		tries += n
 	}
-	s.debugf(false, "done, %v rounds total (%v tries, %v shrinks, %v cache hits):\n%v", i, tries, s.shrinks, s.hits, s.tries)
+	s.debugf(false, "done, %v rounds total (%v tries, %v shrinks, %v cache hits, %v rejected by filters):\n%v", i, tries, s.shrinks, s.hits, s.filtered, s.tries)
 
 	return s.rec.data, s.err
 }
 
-func (s *shrinker) removeGroups(deadline time.Time) {
-	for i := 0; i < len(s.rec.groups) && time.Now().Before(deadline); i++ {
+func (s *shrinker) runPasses(expensive bool) {
+	for _, p := range s.passes {
+		if p.expensive == expensive && !s.done() {
+			s.pass = p.name
+			p.run(s)
+		}
+	}
+}
+
+func (s *shrinker) removeGroups() {
+	for i := 0; i < len(s.rec.groups) && !s.done(); {
+		i = s.tryEach(i, len(s.rec.groups), func(i int) (shrinkCandidate, bool) {
+			g := s.rec.groups[i]
+			if !g.standalone || g.end < 0 {
+				return shrinkCandidate{}, false
+			}
+
+			return candidate(without(s.rec.data, g), labelRemoveGroup, g.label, "remove group %q at %v: [%v, %v)", g.label, i, g.begin, g.end), true
+		})
+		if i < 0 {
+			break
+		}
+	}
+}
+
+// simplifyRunes tries to replace every rune with the simplest one possible: first
+// rune of the generator ('A' for Rune()), second one ('a'), or the first rune
+// of the table the rune was drawn from (e.g. '0' for decimal digits).
+func (s *shrinker) simplifyRunes() {
+	for i := 0; i < len(s.rec.groups) && !s.done(); i++ {
 		g := s.rec.groups[i]
-		if !g.standalone || g.end < 0 {
+		if g.label != runeLabel || g.end <= g.begin {
 			continue
 		}
 
-		if s.accept(without(s.rec.data, g), labelRemoveGroup, "remove group %q at %v: [%v, %v)", g.label, i, g.begin, g.end) {
-			i--
+		zero := append([]uint64(nil), s.rec.data...)
+		for j := g.begin; j < g.end; j++ {
+			zero[j] = 0
+		}
+		second := append([]uint64(nil), zero...)
+		second[g.end-1] = 1
+		cands := []shrinkCandidate{
+			candidate(zero, labelSimplifyRune, g.label, "zero rune at %v: [%v, %v)", i, g.begin, g.end),
+			candidate(second, labelSimplifyRune, g.label, "set rune at %v to second one: [%v, %v)", i, g.begin, g.end),
+		}
+		if i+1 < len(s.rec.groups) && s.rec.groups[i+1].label == dieRollLabel && s.rec.groups[i+1].end < g.end {
+			first := append([]uint64(nil), s.rec.data...)
+			for j := s.rec.groups[i+1].end; j < g.end; j++ {
+				first[j] = 0
+			}
+			cands = append(cands, candidate(first, labelSimplifyRune, g.label, "set rune at %v to first one in table: [%v, %v)", i, g.begin, g.end))
+		}
+
+		s.tryEach(0, len(cands), func(j int) (shrinkCandidate, bool) {
+			return cands[j], true
+		})
+	}
+}
+
+// zeroMapEntries tries to replace keys and values of every map entry with
+// the simplest ones possible, independently of each other.
+func (s *shrinker) zeroMapEntries() {
+	for i := 0; i < len(s.rec.groups) && !s.done(); {
+		i = s.tryEach(i, len(s.rec.groups), func(i int) (shrinkCandidate, bool) {
+			g := s.rec.groups[i]
+			if (g.label != mapKeyLabel && g.label != mapValueLabel) || g.end <= g.begin {
+				return shrinkCandidate{}, false
+			}
+
+			buf := append([]uint64(nil), s.rec.data...)
+			zero := true
+			for j := g.begin; j < g.end; j++ {
+				zero = zero && buf[j] == 0
+				buf[j] = 0
+			}
+			if zero {
+				return shrinkCandidate{}, false
+			}
+
+			return candidate(buf, labelZeroMapEntry, g.label, "zero %v group at %v: [%v, %v)", g.label, i, g.begin, g.end), true
+		})
+		if i < 0 {
+			break
+		}
+		i++
+	}
+}
+
+// resetDraws tries to replace every labeled draw (e.g. a field of a Custom
+// value) with its simplest value, independently of the other draws. As the
+// simplest value can be encoded in fewer blocks, shorter runs of zero blocks
+// are tried first, to keep the following draws intact.
+func (s *shrinker) resetDraws() {
+	for i := 0; i < len(s.rec.groups) && !s.done(); i++ {
+		g := s.rec.groups[i]
+		if !strings.HasPrefix(g.label, drawLabelPrefix) || g.end < 0 {
+			continue
 		}
+
+		n := g.end - g.begin
+		i_ := i
+		s.tryEach(1, n+1, func(k int) (shrinkCandidate, bool) {
+			if k > small*2 && k < n {
+				return shrinkCandidate{}, false
+			}
+
+			buf := append([]uint64(nil), s.rec.data[:g.begin]...)
+			buf = append(buf, make([]uint64, k)...)
+			buf = append(buf, s.rec.data[g.end:]...)
+
+			return candidate(buf, labelResetDraw, g.label, "reset draw %q at %v to %v zero blocks: [%v, %v)", g.label, i_, k, g.begin, g.end), true
+		})
 	}
 }
 
-func (s *shrinker) minimizeBlocks(deadline time.Time) {
-	for i := 0; i < len(s.rec.data) && time.Now().Before(deadline); i++ {
+// zeroLabels tries to zero all groups with the same label at once, for every
+// label in order of first appearance.
+func (s *shrinker) zeroLabels() {
+	var labels []string
+	seen := map[string]bool{}
+	for _, g := range s.rec.groups {
+		if g.label != "" && g.end >= 0 && !seen[g.label] {
+			seen[g.label] = true
+			labels = append(labels, g.label)
+		}
+	}
+
+	s.tryEach(0, len(labels), func(i int) (shrinkCandidate, bool) {
+		buf := append([]uint64(nil), s.rec.data...)
+		zero := true
+		for _, g := range s.rec.groups {
+			if g.label != labels[i] || g.end < 0 {
+				continue
+			}
+			for j := g.begin; j < g.end; j++ {
+				zero = zero && buf[j] == 0
+				buf[j] = 0
+			}
+		}
+		if zero {
+			return shrinkCandidate{}, false
+		}
+
+		return candidate(buf, labelZeroLabel, labels[i], "zero all groups %q", labels[i]), true
+	})
+}
+
+// minimizeBlocks minimizes every block independently. When several candidates
+// for a block in a row are rejected by a filter, the rest of the block is skipped.
+func (s *shrinker) minimizeBlocks() {
+	for i := 0; i < len(s.rec.data) && !s.done(); i++ {
+		if s.blockGroup(i) == shrinkHintLabel {
+			continue // see shrinkHints
+		}
+		s.filterRun = 0
 		minimize(s.rec.data[i], func(u uint64, label string) bool {
+			if s.filterRun >= small {
+				return false
+			}
+
 			buf := append([]uint64(nil), s.rec.data...)
 			buf[i] = u
-			return s.accept(buf, label, "minimize block %v: %v to %v", i, s.rec.data[i], u)
+			return s.accept(buf, label, s.blockGroup(i), "minimize block %v: %v to %v", i, s.rec.data[i], u)
 		})
 	}
 }
 
-func (s *shrinker) lowerFloatHack(deadline time.Time) {
-	for i := 0; i < len(s.rec.groups) && time.Now().Before(deadline); i++ {
+// shrinkHints selects the domain-specific shrink candidates of the values (see
+// genShrinkHint), simplest first. Other passes leave the hint blocks alone, and
+// the candidates are only tried once no other pass is able to make progress,
+// so that they only ever shortcut the regular minimization.
+func (s *shrinker) shrinkHints() {
+	if s.shrinks > s.roundStart {
+		return
+	}
+
+	for i := 0; i < len(s.rec.groups) && !s.done(); i++ {
+		g := s.rec.groups[i]
+		if g.label != shrinkHintLabel || g.end != g.begin+2 {
+			continue
+		}
+
+		for h := uint64(1); h <= shrinkHintMax && !s.done(); h++ {
+			buf := append(append(append([]uint64(nil), s.rec.data[:g.begin]...), h), s.rec.data[g.end:]...)
+			if s.accept(buf, labelSelectShrinkHint, g.label, "select shrink candidate %v of group at %v", h-1, i) {
+				break
+			}
+		}
+	}
+}
+
+// simplifyFloats replaces the float values with their human-friendly simplifications
+// (see simpleFloats), simplest first. Like shrinkHints, the candidates are only
+// tried once no other pass is able to make progress.
+func (s *shrinker) simplifyFloats() {
+	if s.shrinks > s.roundStart {
+		return
+	}
+
+	for i := 0; !s.done(); i++ {
+		t := newT(s.tb, newBufBitStream(s.rec.data, false), false, nil)
+		t.floats = &[]floatDraw{}
+		_ = checkOnce(t, s.prop)
+		if i >= len(*t.floats) {
+			return
+		}
+
+		d := (*t.floats)[i]
+		for _, c := range d.g.simpleFloats(d.f) {
+			words, ok := d.g.words(c)
+			if !ok {
+				continue
+			}
+			buf := append(append(append([]uint64(nil), s.rec.data[:d.begin]...), words...), s.rec.data[d.end:]...)
+			if s.accept(buf, labelSimplifyFloat, s.blockGroup(d.begin), "simplify float %v at %v to %v", d.f, d.begin, c) {
+				break
+			}
+		}
+	}
+}
+
+func (s *shrinker) lowerFloatHack() {
+	for i := 0; i < len(s.rec.groups) && !s.done(); i++ {
 		g := s.rec.groups[i]
 		if !g.standalone || g.end != g.begin+7 {
 			continue
@@ -152,25 +604,64 @@ func (s *shrinker) lowerFloatHack(deadline time.Time) {
 		buf[g.begin+5] = math.MaxUint64
 		buf[g.begin+6] = math.MaxUint64
 
-		if !s.accept(buf, labelLowerFloatExp, "lower float exponent of group %q at %v to %v", g.label, i, buf[g.begin+3]) {
+		if !s.accept(buf, labelLowerFloatExp, g.label, "lower float exponent of group %q at %v to %v", g.label, i, buf[g.begin+3]) {
 			buf := append([]uint64(nil), s.rec.data...)
 			buf[g.begin+4] -= 1
 			buf[g.begin+5] = math.MaxUint64
 			buf[g.begin+6] = math.MaxUint64
 
-			if !s.accept(buf, labelLowerFloatSignif, "lower float significant of group %q at %v to %v", g.label, i, buf[g.begin+4]) {
+			if !s.accept(buf, labelLowerFloatSignif, g.label, "lower float significant of group %q at %v to %v", g.label, i, buf[g.begin+4]) {
 				buf := append([]uint64(nil), s.rec.data...)
 				buf[g.begin+5] -= 1
 				buf[g.begin+6] = math.MaxUint64
 
-				s.accept(buf, labelLowerFloatFrac, "lower float frac of group %q at %v to %v", g.label, i, buf[g.begin+5])
+				s.accept(buf, labelLowerFloatFrac, g.label, "lower float frac of group %q at %v to %v", g.label, i, buf[g.begin+5])
+			}
+		}
+	}
+}
+
+// lowerIntHack lowers the bit length of biased integers, filling the bits with ones.
+// Bits of the integers drawn at maximum bit length are not used, and are usually
+// already minimized to 0 by the time the bit length could be lowered. As all ones
+// are out of range for most bit lengths of such integers, each shorter bit length
+// is also tried with all ones but the top bit, which is always in range.
+func (s *shrinker) lowerIntHack() {
+	for i := 0; i+1 < len(s.rec.groups) && !s.done(); i++ {
+		g, h := s.rec.groups[i], s.rec.groups[i+1]
+		if g.label != biasLabel || h.label != intBitsLabel || g.end != h.begin || h.end != h.begin+1 || s.rec.data[h.begin] == math.MaxUint64 {
+			continue
+		}
+
+		j := g.end - 1
+		minimize(s.rec.data[j], func(u uint64, label string) bool {
+			if u >= s.rec.data[j] || s.rec.data[h.begin] == math.MaxUint64 {
+				return false
+			}
+
+			buf := append([]uint64(nil), s.rec.data...)
+			buf[j] = u
+			buf[h.begin] = math.MaxUint64
+			return s.accept(buf, labelLowerIntSize, s.blockGroup(j), "lower int size at %v: %v to %v", j, s.rec.data[j], u)
+		})
+
+		for bitlen := uint(1); bitlen < 64 && !s.done(); bitlen++ {
+			w := uintNBiasedWords(1<<(bitlen-1), 1<<(bitlen-1)-1)
+			if w[0] >= s.rec.data[j] {
+				break
+			}
+
+			buf := append([]uint64(nil), s.rec.data...)
+			buf[j], buf[h.begin] = w[0], w[1]
+			if s.accept(buf, labelLowerIntSize, s.blockGroup(j), "lower int size at %v: %v to %v bits", j, s.rec.data[j], bitlen) {
+				break
 			}
 		}
 	}
 }
 
-func (s *shrinker) removeGroupsAndLower(deadline time.Time) {
-	for i := 0; i < len(s.rec.data) && time.Now().Before(deadline); i++ {
+func (s *shrinker) removeGroupsAndLower() {
+	for i := 0; i < len(s.rec.data) && !s.done(); i++ {
 		if s.rec.data[i] == 0 {
 			continue
 		}
@@ -178,22 +669,22 @@ func (s *shrinker) removeGroupsAndLower(deadline time.Time) {
 		buf := append([]uint64(nil), s.rec.data...)
 		buf[i] -= 1
 
-		for j := 0; j < len(s.rec.groups); j++ {
+		j := s.tryEach(0, len(s.rec.groups), func(j int) (shrinkCandidate, bool) {
 			g := s.rec.groups[j]
 			if !g.standalone || g.end < 0 || (i >= g.begin && i < g.end) {
-				continue
+				return shrinkCandidate{}, false
 			}
 
-			if s.accept(without(buf, g), labelRemoveGroupAndLower, "lower block %v to %v and remove group %q at %v: [%v, %v)", i, buf[i], g.label, j, g.begin, g.end) {
-				i--
-				break
-			}
+			return candidate(without(buf, g), labelRemoveGroupAndLower, g.label, "lower block %v to %v and remove group %q at %v: [%v, %v)", i, buf[i], g.label, j, g.begin, g.end), true
+		})
+		if j >= 0 {
+			i--
 		}
 	}
 }
 
-func (s *shrinker) sortGroups(deadline time.Time) {
-	for i := 1; i < len(s.rec.groups) && time.Now().Before(deadline); i++ {
+func (s *shrinker) sortGroups() {
+	for i := 1; i < len(s.rec.groups) && !s.done(); i++ {
 		for j := i; j > 0; {
 			g := s.rec.groups[j]
 			if !g.standalone || g.end < 0 {
@@ -201,10 +692,11 @@ func (s *shrinker) sortGroups(deadline time.Time) {
 			}
 
 			j_ := j
-			for j--; j >= 0; j-- {
+			k := s.tryEach(0, j_, func(k int) (shrinkCandidate, bool) {
+				j := j_ - 1 - k
 				h := s.rec.groups[j]
 				if !h.standalone || h.end < 0 || h.end > g.begin || h.label != g.label {
-					continue
+					return shrinkCandidate{}, false
 				}
 
 				buf := append([]uint64(nil), s.rec.data[:h.begin]...)
@@ -213,77 +705,530 @@ func (s *shrinker) sortGroups(deadline time.Time) {
 				buf = append(buf, s.rec.data[h.begin:h.end]...)
 				buf = append(buf, s.rec.data[g.end:]...)
 
-				if s.accept(buf, labelSortGroups, "swap groups %q at %v: [%v, %v) and %q at %v: [%v, %v)", g.label, j_, g.begin, g.end, h.label, j, h.begin, h.end) {
-					break
-				}
+				return candidate(buf, labelSortGroups, g.label, "swap groups %q at %v: [%v, %v) and %q at %v: [%v, %v)", g.label, j_, g.begin, g.end, h.label, j, h.begin, h.end), true
+			})
+			if k < 0 {
+				break
 			}
+			j = j_ - 1 - k
 		}
 	}
 }
 
-func (s *shrinker) removeGroupSpans(deadline time.Time) {
-	for i := 0; i < len(s.rec.groups) && time.Now().Before(deadline); i++ {
+func (s *shrinker) removeGroupSpans() {
+	for i := 0; i < len(s.rec.groups) && !s.done(); i++ {
 		g := s.rec.groups[i]
 		if !g.standalone || g.end < 0 {
 			continue
 		}
 
 		groups := []groupInfo{g}
-		for j := i + 1; j < len(s.rec.groups); j++ {
+		j := s.tryEach(i+1, len(s.rec.groups), func(j int) (shrinkCandidate, bool) {
 			h := s.rec.groups[j]
 			if !h.standalone || h.end < 0 || h.begin < groups[len(groups)-1].end {
-				continue
+				return shrinkCandidate{}, false
 			}
 
 			groups = append(groups, h)
-			buf := without(s.rec.data, groups...)
 
-			if s.accept(buf, labelRemoveGroupSpan, "remove %v groups %v", len(groups), groups) {
-				i--
+			return candidate(without(s.rec.data, groups...), labelRemoveGroupSpan, g.label, "remove %v groups %v", len(groups), groups), true
+		})
+		if j >= 0 {
+			i--
+		}
+	}
+}
+
+// steps returns the steps of a state machine (or of a sequence of commands),
+// which are repeat groups containing the choice of an action.
+func (s *shrinker) steps() []groupInfo {
+	var steps []groupInfo
+	for i, g := range s.rec.groups {
+		if !g.standalone || g.end < 0 || !strings.HasSuffix(g.label, repeatLabel) {
+			continue
+		}
+		for _, h := range s.rec.groups[i+1:] {
+			if h.begin >= g.end {
+				break
+			}
+			if h.label == actionLabel || h.label == drawLabelPrefix+commandLabel {
+				steps = append(steps, g)
 				break
 			}
 		}
 	}
+
+	return steps
 }
 
-func (s *shrinker) accept(buf []uint64, label string, format string, args ...interface{}) bool {
-	if compareData(buf, s.rec.data) >= 0 {
+// traceSteps describes the steps (including the unfinished ones) of the current
+// test case, or returns nil when the first n steps can not be traced.
+func (s *shrinker) traceSteps(n int) []machineStep {
+	t := newT(s.tb, newBufBitStream(s.rec.data, false), false, nil)
+	t.trace = &machineTrace{}
+	_ = checkOnce(t, s.prop)
+
+	steps := t.trace.steps
+	if t.trace.pending != nil {
+		steps = append(steps, *t.trace.pending)
+	}
+	if len(steps) > 0 && steps[0].action == initMethodName {
+		steps = steps[1:]
+	}
+	if len(steps) < n {
+		return nil
+	}
+
+	return steps
+}
+
+// mergeSteps tries to remove all repetitions of a step (the same action with
+// the same values drawn), which merges the repeated calls of idempotent actions into one.
+func (s *shrinker) mergeSteps() {
+	for i := 0; i < len(s.steps()) && !s.done(); i++ {
+		steps := s.steps()
+		trace := s.traceSteps(len(steps))
+		if trace == nil {
+			return
+		}
+
+		key := trace[i].key()
+		var dups []groupInfo
+		for j := i + 1; j < len(steps); j++ {
+			if trace[j].key() == key {
+				dups = append(dups, steps[j])
+			}
+		}
+		if len(dups) > 0 {
+			g := steps[i]
+			s.accept(without(s.rec.data, dups...), labelMergeSteps, g.label, "merge %v repetitions of step %v (%v): [%v, %v)", len(dups), i, key, g.begin, g.end)
+		}
+	}
+}
+
+// removeStepPairs tries to remove a step together with a later one, like
+// a call that creates something together with a call that uses it. Removing
+// either one alone usually makes the test case pass (or be invalid).
+func (s *shrinker) removeStepPairs() {
+	for i := 0; i < len(s.steps()) && !s.done(); i++ {
+		steps := s.steps()
+		g := steps[i]
+		j := s.tryEach(i+2, len(steps), func(j int) (shrinkCandidate, bool) {
+			h := steps[j]
+			return candidate(without(s.rec.data, g, h), labelRemoveStepPair, g.label, "remove steps %v: [%v, %v) and %v: [%v, %v)", i, g.begin, g.end, j, h.begin, h.end), true
+		})
+		if j >= 0 {
+			i--
+		}
+	}
+}
+
+// removeActors tries to remove an instance created by a step (see Actors),
+// together with every step which has used it. Ordinals of the instances
+// created later are decremented, so that the remaining steps keep using them.
+func (s *shrinker) removeActors() {
+	for i := 0; !s.done(); i++ {
+		steps := s.steps()
+		trace := s.traceSteps(len(steps))
+		if trace == nil {
+			return
+		}
+
+		var created []int // step which has created each instance
+		var refs []actorRef
+		for j, step := range trace[:len(steps)] {
+			for _, r := range step.created {
+				created = append(created, j)
+				refs = append(refs, r)
+			}
+		}
+		if i >= len(refs) {
+			return
+		}
+
+		r := refs[i]
+		unfinished := false
+		for _, step := range trace[len(steps):] {
+			unfinished = unfinished || step.uses(r)
+		}
+		if unfinished {
+			continue
+		}
+
+		var groups []groupInfo
+		for j, step := range trace[:len(steps)] {
+			if j == created[i] || step.uses(r) {
+				groups = append(groups, steps[j])
+			}
+		}
+
+		buf := append([]uint64(nil), s.rec.data...)
+		for _, g := range s.rec.groups {
+			if g.label == actorLabelPrefix+r.kind && g.end == g.begin+1 && buf[g.begin] > uint64(r.id) {
+				buf[g.begin]--
+			}
+		}
+
+		g := steps[created[i]]
+		if s.accept(without(buf, groups...), labelRemoveActor, g.label, "remove %v and %v steps using it: [%v, %v)", r, len(groups)-1, g.begin, g.end) {
+			i--
+		}
+	}
+}
+
+// siblingRuns returns runs of adjacent standalone groups with the same label,
+// like elements of a single slice.
+func (s *shrinker) siblingRuns() [][]groupInfo {
+	var runs [][]groupInfo
+	last := map[string]int{}
+	for _, g := range s.rec.groups {
+		if !g.standalone || g.end < 0 || !strings.HasSuffix(g.label, repeatLabel) {
+			continue
+		}
+		if r, ok := last[g.label]; ok && runs[r][len(runs[r])-1].end == g.begin {
+			runs[r] = append(runs[r], g)
+		} else {
+			last[g.label] = len(runs)
+			runs = append(runs, []groupInfo{g})
+		}
+	}
+
+	return runs
+}
+
+// removeChunks tries to remove contiguous chunks of sibling groups at once,
+// halving the chunk size from half of the run down to 2.
+func (s *shrinker) removeChunks() {
+	for progress := true; progress && !s.done(); {
+		progress = false
+		for _, run := range s.siblingRuns() {
+			for size := len(run) / 2; size >= 2 && !progress; size /= 2 {
+				size_ := size
+				j := s.tryEach(0, len(run)-size+1, func(j int) (shrinkCandidate, bool) {
+					chunk := run[j : j+size_]
+					return candidate(without(s.rec.data, chunk...), labelRemoveChunk, chunk[0].label, "remove chunk of %v groups %q at [%v, %v)", size_, chunk[0].label, chunk[0].begin, chunk[len(chunk)-1].end), true
+				})
+				progress = j >= 0
+			}
+			if progress {
+				break
+			}
+		}
+	}
+}
+
+// lowerSiblings minimizes the blocks at the same offset of all equally-sized
+// sibling groups together (optionally filling the rest of each group, like
+// lowerFloatHack does). This is required when the failure depends on several
+// elements being large (or equal), and lowering any single one of them makes
+// the test pass.
+func (s *shrinker) lowerSiblings() {
+	for _, run := range s.siblingRuns() {
+		var groups []groupInfo
+		for _, g := range run {
+			if g.end-g.begin == run[0].end-run[0].begin {
+				groups = append(groups, g)
+			}
+		}
+		if len(groups) < 2 {
+			continue
+		}
+
+		for k := 0; k < groups[0].end-groups[0].begin && !s.done(); k++ {
+			var max uint64
+			n := 0
+			for _, g := range groups {
+				if u := s.rec.data[g.begin+k]; u > 0 {
+					n++
+					if u > max {
+						max = u
+					}
+				}
+			}
+			if n < 2 {
+				continue
+			}
+
+			k_ := k
+			minimize(max, func(u uint64, label string) bool {
+				lowered := append([]uint64(nil), s.rec.data...)
+				filled := append([]uint64(nil), s.rec.data...)
+				for _, g := range groups {
+					if g.end > len(lowered) || lowered[g.begin+k_] <= u {
+						continue
+					}
+					lowered[g.begin+k_] = u
+					filled[g.begin+k_] = u
+					for j := g.begin + k_ + 1; j < g.end; j++ {
+						filled[j] = math.MaxUint64
+					}
+				}
+
+				return s.accept(lowered, labelLowerSiblings, groups[0].label, "lower block %v of %v groups %q to %v (%v)", k_, len(groups), groups[0].label, u, label) ||
+					s.accept(filled, labelLowerSiblings, groups[0].label, "lower block %v of %v groups %q to %v and fill the rest (%v)", k_, len(groups), groups[0].label, u, label)
+			})
+		}
+	}
+}
+
+func candidate(buf []uint64, label string, group string, format string, args ...interface{}) shrinkCandidate {
+	return shrinkCandidate{
+		buf:    buf,
+		label:  label,
+		group:  group,
+		format: format,
+		args:   args,
+	}
+}
+
+// tryEach considers candidates gen(i) for i in [from, to) in order, and returns
+// the index of the first accepted one, or -1. Candidates are generated from the
+// current state in batches of s.workers, which are then checked concurrently.
+func (s *shrinker) tryEach(from int, to int, gen func(int) (shrinkCandidate, bool)) int {
+	for i := from; i < to && !s.done(); {
+		var (
+			cands []shrinkCandidate
+			ixs   []int
+		)
+		for ; i < to && len(cands) < s.workers; i++ {
+			if c, ok := gen(i); ok {
+				cands = append(cands, c)
+				ixs = append(ixs, i)
+			}
+		}
+
+		if k := s.acceptFirst(cands); k >= 0 {
+			return ixs[k]
+		}
+	}
+
+	return -1
+}
+
+func (s *shrinker) accept(buf []uint64, label string, group string, format string, args ...interface{}) bool {
+	return s.acceptFirst([]shrinkCandidate{candidate(buf, label, group, format, args...)}) == 0
+}
+
+// acceptFirst accepts the first candidate (in order) which reproduces the failure,
+// and returns its index, or -1 if there is none.
+func (s *shrinker) acceptFirst(cands []shrinkCandidate) int {
+	var (
+		ixs   []int
+		keys  []uint64
+		batch = map[uint64][]uint64{}
+	)
+	for i, c := range cands {
+		if s.maxAttempts > 0 && s.attempts+len(ixs) >= s.maxAttempts {
+			break
+		}
+		if compareData(c.buf, s.rec.data) >= 0 {
+			continue
+		}
+		key := dataHash(c.buf)
+		cached, inCache := s.cache[key]
+		dup, inBatch := batch[key]
+		if (inCache && compareData(cached, c.buf) == 0) || (inBatch && compareData(dup, c.buf) == 0) {
+			s.hits++
+			s.writeHistory(c, "skipped, already tried")
+			continue
+		}
+		batch[key] = c.buf
+
+		ixs = append(ixs, i)
+		keys = append(keys, key)
+	}
+
+	errs := make([]*testError, len(ixs))
+	filtered := make([]bool, len(ixs))
+	if len(ixs) == 1 {
+		errs[0], filtered[0] = s.check(cands[ixs[0]].buf)
+	} else {
+		var wg sync.WaitGroup
+		for j, i := range ixs {
+			wg.Add(1)
+			go func(j int, buf []uint64) {
+				defer wg.Done()
+				errs[j], filtered[j] = s.check(buf)
+			}(j, cands[i].buf)
+		}
+		wg.Wait()
+	}
+
+	// Results are processed exactly like the candidates were checked one by one,
+	// in order: results of candidates after the accepted one are thrown away,
+	// so that neither the number of workers nor their timing affects the outcome.
+	for j, i := range ixs {
+		c := cands[i]
+		s.debugf(true, c.label+": trying to reproduce the failure with a smaller test case: "+c.format, c.args...)
+		s.tries[c.label]++
+		s.attempts++
+
+		if traceback(errs[j]) != traceback(s.err) {
+			s.cache[keys[j]] = c.buf
+			if filtered[j] {
+				s.filtered++
+				s.filterRun++
+				s.writeHistory(c, "rejected by filter")
+				if !s.repairing && s.repairFiltered(c) {
+					return i
+				}
+			} else {
+				s.filterRun = 0
+				if errs[j] == nil {
+					s.writeHistory(c, "passed")
+				} else {
+					s.writeHistory(c, fmt.Sprintf("failed differently (%v)", errs[j]))
+				}
+			}
+			continue
+		}
+		s.filterRun = 0
+		s.writeHistory(c, "reproduced")
+
+		size := len(s.rec.data)
+		draw := blockDraw(s.rec, firstDiff(s.rec.data, c.buf))
+		s.debugf(true, c.label+": trying to reproduce the failure")
+		s.tries[c.label]++
+		s.err = errs[j]
+		s2 := newBufBitStream(c.buf, true)
+		err2 := checkOnce(newT(s.tb, s2, flags.debug && flags.verbose, nil), s.prop)
+		if !sameError(errs[j], err2) {
+			panic(err2)
+		}
+		s.rec = s2.recordedBits
+		s.rec.prune()
+		assert(compareData(s.rec.data, c.buf) <= 0)
+		if flags.debugvis {
+			s.visBits = append(s.visBits, s.rec)
+		}
+
+		s.debugf(false, c.label+" success: "+c.format, c.args...)
+		s.shrinks++
+		s.report(ShrinkStep{
+			Pass:       c.label,
+			Group:      c.group,
+			Draw:       draw,
+			SizeBefore: size,
+			SizeAfter:  len(s.rec.data),
+			Attempts:   s.attempts,
+			Elapsed:    time.Since(s.start),
+		})
+
+		return i
+	}
+
+	return -1
+}
+
+// repairFiltered tries to move a candidate rejected by a filter back inside the
+// filter predicate, by lowering the last block it has changed a bit further.
+func (s *shrinker) repairFiltered(c shrinkCandidate) bool {
+	if len(c.buf) != len(s.rec.data) {
 		return false
 	}
-	bufStr := dataStr(buf)
-	if _, ok := s.cache[bufStr]; ok {
-		s.hits++
+	j := len(c.buf) - 1
+	for j >= 0 && c.buf[j] == s.rec.data[j] {
+		j--
+	}
+	if j < 0 {
 		return false
 	}
 
-	s.debugf(true, label+": trying to reproduce the failure with a smaller test case: "+format, args...)
-	s.tries[label]++
-	s1 := newBufBitStream(buf, false)
-	err1 := checkOnce(newT(s.tb, s1, flags.debug && flags.verbose, nil), s.prop)
-	if traceback(err1) != traceback(s.err) {
-		s.cache[bufStr] = struct{}{}
-		return false
+	s.repairing = true
+	defer func() { s.repairing = false }()
+
+	return s.tryEach(1, small+1, func(k int) (shrinkCandidate, bool) {
+		if c.buf[j] < uint64(k) {
+			return shrinkCandidate{}, false
+		}
+
+		buf := append([]uint64(nil), c.buf...)
+		buf[j] -= uint64(k)
+
+		args := append(append([]interface{}(nil), c.args...), j, k)
+
+		return candidate(buf, c.label, c.group, c.format+" (lowered block %v by %v to satisfy filter)", args...), true
+	}) >= 0
+}
+
+// writeHistory records the outcome of trying candidate c, together with
+// the difference between it and the current test case, for -rapid.debugshrink.
+func (s *shrinker) writeHistory(c shrinkCandidate, outcome string) {
+	if s.history == nil {
+		return
 	}
 
-	s.debugf(true, label+": trying to reproduce the failure")
-	s.tries[label]++
-	s.err = err1
-	s2 := newBufBitStream(buf, true)
-	err2 := checkOnce(newT(s.tb, s2, flags.debug && flags.verbose, nil), s.prop)
-	s.rec = s2.recordedBits
-	s.rec.prune()
-	assert(compareData(s.rec.data, buf) <= 0)
-	if flags.debugvis {
-		s.visBits = append(s.visBits, s.rec)
+	i := firstDiff(s.rec.data, c.buf)
+	j, k := len(s.rec.data), len(c.buf)
+	for j > i && k > i && s.rec.data[j-1] == c.buf[k-1] {
+		j--
+		k--
+	}
+
+	_, _ = fmt.Fprintf(s.history, "%v: "+c.format+": %v\n", append(append([]interface{}{c.label}, c.args...), outcome)...)
+	_, _ = fmt.Fprintf(s.history, "\t[%v, %v) %v -> %v\n", i, j, s.rec.data[i:j], c.buf[i:k])
+}
+
+func (s *shrinker) report(step ShrinkStep) {
+	if flags.shrinkProgress {
+		s.tb.Helper()
+		s.tb.Logf("[rapid] shrink step %v (%v attempts, %v): %v (draw %q, group %q), %v -> %v blocks", s.shrinks, step.Attempts, step.Elapsed, step.Pass, step.Draw, step.Group, step.SizeBefore, step.SizeAfter)
+	}
+	if s.onShrink != nil {
+		s.onShrink(step)
+	}
+}
+
+// blockGroup returns the label of the innermost group containing the block at i.
+func (s *shrinker) blockGroup(i int) string {
+	label := ""
+	for _, g := range s.rec.groups {
+		if g.begin > i {
+			break
+		}
+		if i < g.end {
+			label = g.label
+		}
 	}
-	if !sameError(err1, err2) {
-		panic(err2)
+
+	return label
+}
+
+// blockDraw returns the label of the outermost draw containing the block at i.
+func blockDraw(rec recordedBits, i int) string {
+	for _, g := range rec.groups {
+		if g.begin > i {
+			break
+		}
+		if i < g.end && strings.HasPrefix(g.label, drawLabelPrefix) {
+			return strings.TrimPrefix(g.label, drawLabelPrefix)
+		}
 	}
 
-	s.debugf(false, label+" success: "+format, args...)
-	s.shrinks++
+	return ""
+}
 
-	return true
+func firstDiff(a []uint64, b []uint64) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+
+	return i
+}
+
+// check runs the property on buf, and reports whether any of the values
+// drawn were rejected by a filter.
+func (s *shrinker) check(buf []uint64) (*testError, bool) {
+	s_ := getBufBitStream(buf)
+	err := checkOnce(newT(s.tb, s_, flags.debug && flags.verbose, nil), s.prop)
+	if err != nil && err.isActionTimeout() {
+		return err, false // the action is still using s_
+	}
+	rejected := s_.rejections > 0
+	putBufBitStream(s_)
+
+	return err, rejected
 }
 
 func minimize(u uint64, cond func(uint64, string) bool) uint64 {
@@ -381,11 +1326,20 @@ func without(data []uint64, groups ...groupInfo) []uint64 {
 	return buf
 }
 
-func dataStr(data []uint64) string {
-	b := &strings.Builder{}
-	err := binary.Write(b, binary.LittleEndian, data)
-	assert(err == nil)
-	return b.String()
+// dataHash is a 64-bit FNV-1a hash of the little-endian representation of data.
+// Shrinker memoizes the candidates it has already tried by their hashes, and
+// compares the candidates on a hit, so that a collision never skips a candidate.
+func dataHash(data []uint64) uint64 {
+	h := uint64(fnvOffset64)
+	for _, u := range data {
+		for i := 0; i < 8; i++ {
+			h ^= u & 0xff
+			h *= fnvPrime64
+			u >>= 8
+		}
+	}
+
+	return h
 }
 
 func compareData(a []uint64, b []uint64) int {
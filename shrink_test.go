@@ -7,12 +7,14 @@
 package rapid
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"math/bits"
 	"sort"
 	"strconv"
 	"testing"
+	"unicode"
 )
 
 const shrinkTestRuns = 10
@@ -80,6 +82,17 @@ func TestShrink_FloatCmp(t *testing.T) {
 	}
 }
 
+func TestShrink_IntShrinkTowards(t *testing.T) {
+	t.Parallel()
+
+	checkShrink(t, func(t *T) {
+		i := IntRange(100, 599).ShrinkTowards(200).Draw(t, "i").(int)
+		if i >= 150 {
+			t.Fail()
+		}
+	}, 200)
+}
+
 func TestShrink_IntSliceNElemsGt(t *testing.T) {
 	t.Parallel()
 
@@ -138,6 +151,83 @@ func TestShrink_IntSliceNoDuplicates(t *testing.T) {
 	}, []int{1, 2, 3, 4, 5})
 }
 
+func TestShrink_IntSliceEqualElems(t *testing.T) {
+	t.Parallel()
+
+	checkShrinkChecks(t, 10000, func(t *T) {
+		s := SliceOfN(IntRange(0, 1000), 2, 2).Draw(t, "s").([]int)
+		if s[0] >= 10 && s[0] == s[1] {
+			t.Fail()
+		}
+	}, []int{10, 10})
+}
+
+func TestShrink_IntSliceChunk(t *testing.T) {
+	t.Parallel()
+
+	checkShrinkChecks(t, 1000, func(t *T) {
+		s := SliceOf(IntRange(0, 9)).Draw(t, "s").([]int)
+		if len(s) >= 8 && len(s)%8 == 0 {
+			t.Fail()
+		}
+	}, []int{0, 0, 0, 0, 0, 0, 0, 0})
+}
+
+func TestShrink_Filter(t *testing.T) {
+	t.Parallel()
+
+	checkShrink(t, func(t *T) {
+		s := SliceOf(IntRange(0, 99).Filter(func(i int) bool { return i%2 == 0 })).Draw(t, "s").([]int)
+		for _, i := range s {
+			if i >= 10 {
+				t.Fail()
+			}
+		}
+	}, []int{10})
+}
+
+func TestShrink_Custom(t *testing.T) {
+	t.Parallel()
+
+	type point struct {
+		X    int
+		Name string
+		Tags []string
+		Y    int
+	}
+
+	gen := Custom(func(t *T) point {
+		return point{
+			X:    Int().Draw(t, "x").(int),
+			Name: String().Draw(t, "name").(string),
+			Tags: SliceOf(String()).Draw(t, "tags").([]string),
+			Y:    Int().Draw(t, "y").(int),
+		}
+	})
+
+	checkShrink(t, func(t *T) {
+		ps := SliceOf(gen).Draw(t, "ps").([]point)
+		for _, p := range ps {
+			if p.Y > 100 && len(p.Tags) > 0 {
+				t.Fail()
+			}
+		}
+	}, []point{{Tags: []string{""}, Y: 101}})
+}
+
+func TestShrink_MapOf(t *testing.T) {
+	t.Parallel()
+
+	checkShrink(t, func(t *T) {
+		m := MapOf(String(), SliceOf(Int())).Draw(t, "m").(map[string][]int)
+		for _, v := range m {
+			if len(m) >= 2 && len(v) >= 2 {
+				t.Fail()
+			}
+		}
+	}, map[string][]int{"": {}, "A": {0, 0}})
+}
+
 func TestShrink_String(t *testing.T) {
 	t.Parallel()
 
@@ -150,6 +240,33 @@ func TestShrink_String(t *testing.T) {
 	}, "A", "")
 }
 
+func TestShrink_StringRunes(t *testing.T) {
+	t.Parallel()
+
+	ref := []struct {
+		name string
+		fail func(r rune) bool
+		out  string
+	}{
+		{"any", func(r rune) bool { return true }, "A"},
+		{"lower", unicode.IsLower, "a"},
+		{"digit", unicode.IsDigit, "0"},
+	}
+
+	for _, r := range ref {
+		t.Run(r.name, func(t *testing.T) {
+			checkShrink(t, func(t *T) {
+				s := String().Draw(t, "s").(string)
+				for _, c := range s {
+					if r.fail(c) {
+						t.Fail()
+					}
+				}
+			}, r.out)
+		})
+	}
+}
+
 func TestShrink_StringOfBytes(t *testing.T) {
 	t.Parallel()
 
@@ -162,6 +279,394 @@ func TestShrink_StringOfBytes(t *testing.T) {
 	}, "\x00", "")
 }
 
+func TestShrink_ShrinkWith(t *testing.T) {
+	t.Parallel()
+
+	g := Int().ShrinkWith(func(i int) []int { return []int{42} })
+
+	checkShrink(t, func(t *T) {
+		i := g.Draw(t, "i").(int)
+		if i == 42 || i > 1000000 {
+			t.Fail()
+		}
+	}, 42)
+}
+
+func TestShrink_ParallelDeterministic(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		s := SliceOf(Int()).Draw(t, "s").([]int)
+		n := 0
+		for _, i := range s {
+			if i > 1000000 {
+				n++
+			}
+		}
+		if n > 1 {
+			t.Fail()
+		}
+	}
+
+	for i := 0; i < shrinkTestRuns; i++ {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			seed := baseSeed()
+			for _, attempts := range []int{0, 50} {
+				cfg1, cfg4 := shrinkTestSettings(1), shrinkTestSettings(4)
+				cfg1.shrinkAttempts, cfg4.shrinkAttempts = attempts, attempts
+				_, _, _, buf1, _, _ := doCheck(t, cfg1, seed, prop)
+				_, _, _, buf4, _, _ := doCheck(t, cfg4, seed, prop)
+				if compareData(buf1, buf4) != 0 {
+					t.Fatalf("parallel shrink result with %v attempts budget differs from sequential one (seed %v):\n%v\nvs\n%v", attempts, seed, buf4, buf1)
+				}
+			}
+		})
+	}
+}
+
+func TestShrink_AttemptsBudget(t *testing.T) {
+	t.Parallel()
+
+	const attempts = 10
+
+	failed, runs := false, 0
+	prop := func(t *T) {
+		if failed {
+			runs++
+		}
+		s := SliceOf(Int()).Draw(t, "s").([]int)
+		if len(s) > 3 {
+			failed = true
+			t.Fail()
+		}
+	}
+
+	cfg := shrinkTestSettings(1)
+	cfg.shrinkAttempts = attempts
+	_, _, seed, _, err1, err2 := doCheck(t, cfg, baseSeed(), prop)
+	if err1 == nil || err2 == nil {
+		t.Fatalf("shrink test did not fail (seed %v)", seed)
+	}
+	if runs > 1+2*attempts {
+		t.Fatalf("property was executed %v times with a budget of %v shrink attempts", runs, attempts)
+	}
+}
+
+func TestShrink_OnShrink(t *testing.T) {
+	t.Parallel()
+
+	var steps []ShrinkStep
+	cfg := shrinkTestSettings(1)
+	OnShrink(func(step ShrinkStep) { steps = append(steps, step) })(cfg)
+
+	_, _, seed, buf, err1, err2 := doCheck(t, cfg, baseSeed(), func(t *T) {
+		s := SliceOf(Int()).Draw(t, "s").([]int)
+		if len(s) > 3 {
+			t.Fail()
+		}
+	})
+	if err1 == nil || err2 == nil {
+		t.Fatalf("shrink test did not fail (seed %v)", seed)
+	}
+	if len(steps) == 0 {
+		t.Fatalf("no shrink steps reported (seed %v)", seed)
+	}
+	if last := steps[len(steps)-1]; last.SizeAfter != len(buf) {
+		t.Fatalf("last shrink step %+v does not end at the final test case of size %v", last, len(buf))
+	}
+
+	attempts := 0
+	for i, step := range steps {
+		if step.Pass == "" || step.SizeAfter > step.SizeBefore || step.Attempts < attempts {
+			t.Fatalf("invalid shrink step %v: %+v", i, step)
+		}
+		attempts = step.Attempts
+	}
+}
+
+func TestShrink_StepDraw(t *testing.T) {
+	t.Parallel()
+
+	draws := map[string]int{}
+	cfg := shrinkTestSettings(1)
+	OnShrink(func(step ShrinkStep) { draws[step.Draw]++ })(cfg)
+
+	_, _, seed, _, err1, err2 := doCheck(t, cfg, baseSeed(), func(t *T) {
+		n := Int().Draw(t, "n").(int)
+		s := SliceOf(Int()).Draw(t, "s").([]int)
+		if n > 10 && len(s) > 3 {
+			t.Fail()
+		}
+	})
+	if err1 == nil || err2 == nil {
+		t.Fatalf("shrink test did not fail (seed %v)", seed)
+	}
+
+	for draw := range draws {
+		if draw != "n" && draw != "s" {
+			t.Fatalf("shrink step affected unknown draw %q", draw)
+		}
+	}
+	if draws["n"] == 0 || draws["s"] == 0 {
+		t.Fatalf("not all draws were shrunk: %v", draws)
+	}
+}
+
+func TestShrink_Memoization(t *testing.T) {
+	t.Parallel()
+
+	runs := 0
+	prop := func(t *T) {
+		runs++
+		if Bool().Draw(t, "b").(bool) {
+			t.Fail()
+		}
+	}
+
+	nt := newT(t, newBufBitStream([]uint64{1}, true), false, nil)
+	err := checkOnce(nt, prop)
+	if err == nil {
+		t.Fatalf("property did not fail")
+	}
+
+	s := &shrinker{
+		tb:      t,
+		rec:     nt.s.(*bufBitStream).recordedBits,
+		err:     err,
+		prop:    prop,
+		workers: 1,
+		tries:   map[string]int{},
+		cache:   map[uint64][]uint64{},
+	}
+	s.rec.prune()
+
+	buf := []uint64{0}
+	for i := 0; i < 3; i++ {
+		if s.accept(buf, "test", "", "try passing test case") {
+			t.Fatalf("passing test case accepted")
+		}
+	}
+	if runs != 2 {
+		t.Fatalf("property was executed %v times instead of 2", runs)
+	}
+	if s.hits != 2 {
+		t.Fatalf("got %v cache hits instead of 2", s.hits)
+	}
+
+	// a different candidate with the same hash is not skipped
+	s.cache[dataHash([]uint64{0})] = []uint64{2}
+	s.hits = 0
+	if s.accept(buf, "test", "", "try passing test case") || runs != 3 || s.hits != 0 {
+		t.Fatalf("hash collision skipped the candidate (%v runs, %v cache hits)", runs, s.hits)
+	}
+}
+
+type shrinkPassTestInt int
+
+func TestShrink_FilterRejected(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		Bool().Filter(func(b bool) bool { return b }).Draw(t, "b")
+		t.Fail()
+	}
+
+	nt := newT(t, newBufBitStream([]uint64{1}, true), false, nil)
+	err := checkOnce(nt, prop)
+	if err == nil {
+		t.Fatalf("property did not fail")
+	}
+
+	s := &shrinker{
+		tb:      t,
+		rec:     nt.s.(*bufBitStream).recordedBits,
+		err:     err,
+		prop:    prop,
+		workers: 1,
+		tries:   map[string]int{},
+		cache:   map[uint64][]uint64{},
+	}
+	s.rec.prune()
+
+	if s.accept([]uint64{0}, "test", "", "try value rejected by filter") {
+		t.Fatalf("value rejected by filter accepted")
+	}
+	if s.filtered != 1 {
+		t.Fatalf("got %v filter rejections instead of 1", s.filtered)
+	}
+}
+
+func TestShrink_History(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		if Bool().Draw(t, "b").(bool) {
+			t.Fail()
+		}
+	}
+
+	nt := newT(t, newBufBitStream([]uint64{1}, true), false, nil)
+	err := checkOnce(nt, prop)
+	if err == nil {
+		t.Fatalf("property did not fail")
+	}
+
+	var history bytes.Buffer
+	s := &shrinker{
+		tb:      t,
+		rec:     nt.s.(*bufBitStream).recordedBits,
+		err:     err,
+		prop:    prop,
+		workers: 1,
+		tries:   map[string]int{},
+		cache:   map[uint64][]uint64{},
+		history: &history,
+	}
+	s.rec.prune()
+
+	s.accept([]uint64{0}, "test", "", "try passing test case")
+	s.accept([]uint64{0}, "test", "", "try passing test case again")
+
+	want := "test: try passing test case: passed\n\t[0, 1) [1] -> [0]\n" +
+		"test: try passing test case again: skipped, already tried\n\t[0, 1) [1] -> [0]\n"
+	if history.String() != want {
+		t.Fatalf("got shrink history %q instead of %q", history.String(), want)
+	}
+}
+
+func TestShrink_RegisterShrinkPass(t *testing.T) {
+	t.Parallel()
+
+	g := Custom(func(t *T) shrinkPassTestInt { return shrinkPassTestInt(Int().Draw(t, "i").(int)) })
+	label := g.String()
+
+	RegisterShrinkPass(ShrinkPass{
+		Name: "test_zero_group",
+		Run: func(st *ShrinkState) {
+			for _, g := range st.Groups() {
+				if g.Label != label {
+					continue
+				}
+				buf := st.Data()
+				for i := g.Begin; i < g.End; i++ {
+					buf[i] = 0
+				}
+				st.Try(buf, "zero group")
+			}
+		},
+	})
+
+	prop := func(t *T) {
+		i := g.Draw(t, "i").(shrinkPassTestInt)
+		if i != 1000000 {
+			t.Fail()
+		}
+	}
+
+	for i := 0; i < shrinkTestRuns; i++ {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			cfg := shrinkTestSettings(1)
+			ShrinkPasses("test_zero_group")(cfg)
+
+			_, _, seed, buf, err1, err2 := doCheck(t, cfg, baseSeed(), prop)
+			if err1 == nil || err2 == nil {
+				t.Fatalf("shrink test did not fail (seed %v)", seed)
+			}
+			for j, u := range buf {
+				if u != 0 {
+					t.Fatalf("block %v not zeroed: %v (seed %v)", j, buf, seed)
+				}
+			}
+		})
+	}
+}
+
+type idempotentMachine struct {
+	set bool
+}
+
+func (m *idempotentMachine) Set(*T)   { m.set = true }
+func (m *idempotentMachine) Other(*T) {}
+func (m *idempotentMachine) Poke(t *T) {
+	if m.set {
+		t.Fail()
+	}
+}
+func (m *idempotentMachine) Check(*T) {}
+
+func TestShrink_MergeSteps(t *testing.T) {
+	t.Parallel()
+
+	prop := Run(&idempotentMachine{})
+	for i := 0; i < shrinkTestRuns; i++ {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			cfg := shrinkTestSettings(1)
+			ShrinkPasses(passMergeSteps)(cfg)
+
+			_, _, seed, buf, err1, err2 := doCheck(t, cfg, baseSeed(), prop)
+			if err1 == nil || err2 == nil {
+				t.Fatalf("shrink test did not fail (seed %v)", seed)
+			}
+
+			draws, _ := topLevelDraws(t, prop, buf)
+			seen := map[value]bool{}
+			for _, d := range draws {
+				if seen[d.v] && d.v != "Poke" { // merging Pokes can remove the failing one
+					t.Fatalf("action %v not merged: %v (seed %v)", d.v, draws, seed)
+				}
+				seen[d.v] = true
+			}
+		})
+	}
+}
+
+type sessionMachine struct {
+	sessions *Actors
+}
+
+func (m *sessionMachine) Init(*T)   { m.sessions = NewActors("session") }
+func (m *sessionMachine) Open(t *T) { m.sessions.Create(t, new(int)) }
+func (m *sessionMachine) Close(t *T) {
+	id, _ := m.sessions.Draw(t)
+	m.sessions.Destroy(id)
+}
+func (m *sessionMachine) Write(t *T) {
+	_, n := m.sessions.Draw(t)
+	*n.(*int)++
+	if *n.(*int) == 2 {
+		t.Fail()
+	}
+}
+func (m *sessionMachine) Check(*T) {}
+
+func TestShrink_RemoveActors(t *testing.T) {
+	t.Parallel()
+
+	prop := Run(&sessionMachine{})
+	for i := 0; i < shrinkTestRuns; i++ {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			cfg := shrinkTestSettings(1)
+			ShrinkPasses(passRemoveActors)(cfg)
+
+			_, _, seed, buf, err1, err2 := doCheck(t, cfg, baseSeed(), prop)
+			if err1 == nil || err2 == nil {
+				t.Fatalf("shrink test did not fail (seed %v)", seed)
+			}
+
+			draws, _ := topLevelDraws(t, prop, buf)
+			opens := 0
+			for _, d := range draws {
+				if d.v == "Open" {
+					opens++
+				}
+			}
+			if opens != 1 {
+				t.Fatalf("%v sessions not removed: %v (seed %v)", opens-1, draws, seed)
+			}
+		})
+	}
+}
+
 func TestMinimize_UnsetBits(t *testing.T) {
 	t.Parallel()
 
@@ -206,11 +711,19 @@ func TestMinimize_LowerBound(t *testing.T) {
 func checkShrink(t *testing.T, prop func(*T), draws ...value) {
 	t.Helper()
 
+	checkShrinkChecks(t, 100, prop, draws...)
+}
+
+func checkShrinkChecks(t *testing.T, checks int, prop func(*T), draws ...value) {
+	t.Helper()
+
 	for i := 0; i < shrinkTestRuns; i++ {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			t.Helper()
 
-			_, _, seed, buf, err1, err2 := doCheck(t, "", 100, baseSeed(), prop)
+			cfg := shrinkTestSettings(1)
+			cfg.checks = checks
+			_, _, seed, buf, err1, err2 := doCheck(t, cfg, baseSeed(), prop)
 			if seed != 0 && err1 == nil && err2 == nil {
 				t.Fatalf("shrink test did not fail (seed %v)", seed)
 			}
@@ -227,6 +740,14 @@ func checkShrink(t *testing.T, prop func(*T), draws ...value) {
 	}
 }
 
+func shrinkTestSettings(workers int) *settings {
+	cfg := newSettings([]Option{ShrinkParallel(workers)})
+	cfg.checks = 100
+	cfg.failfile = ""
+
+	return cfg
+}
+
 func bin(u uint64) string {
 	return "0b" + strconv.FormatUint(u, 2)
 }
@@ -0,0 +1,190 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package sim implements deterministic simulation of distributed systems
+// inside a rapid property.
+//
+// Nodes of the simulated system communicate only by sending messages through
+// the simulator, and measure time only by its logical clock. The order in
+// which messages are delivered, and whether they are dropped or duplicated,
+// are drawn from the test case data, so every failure is reproducible
+// and is minimized by rapid like any other failing test case:
+//
+//	rapid.Check(t, func(t *rapid.T) {
+//	    s := sim.New(t)
+//	    s.Node("server", server.Handle)
+//	    s.Node("client", client.Handle)
+//	    client.Start(s)
+//	    s.Run(1000)
+//	    // check the invariants
+//	})
+package sim
+
+import (
+	"fmt"
+	"sort"
+
+	"pgregory.net/rapid"
+)
+
+// Time is the logical time of the simulation, in ticks.
+type Time int64
+
+// Fate is the decision on what happens to a message in flight.
+type Fate int
+
+const (
+	Deliver   Fate = iota // message is delivered
+	Drop                  // message is lost
+	Duplicate             // message is delivered, and stays in flight to be delivered again
+)
+
+func (f Fate) String() string {
+	switch f {
+	case Deliver:
+		return "deliver"
+	case Drop:
+		return "drop"
+	case Duplicate:
+		return "duplicate"
+	default:
+		return fmt.Sprintf("Fate(%d)", int(f))
+	}
+}
+
+// GoString returns the name of the fate, to make the draws of fates readable.
+func (f Fate) GoString() string {
+	return f.String()
+}
+
+// fates delivers most of the messages, and shrinks towards delivering all of them.
+var fates = rapid.Custom(func(t *rapid.T) Fate {
+	switch rapid.IntRange(0, 9).Draw(t, "n").(int) {
+	case 8:
+		return Drop
+	case 9:
+		return Duplicate
+	default:
+		return Deliver
+	}
+})
+
+// Message is sent from one node to another.
+type Message struct {
+	From    string
+	To      string
+	Payload interface{}
+	Sent    Time
+}
+
+func (m Message) String() string {
+	return fmt.Sprintf("%v->%v %v", m.From, m.To, m.Payload)
+}
+
+// Handler is called when a message is delivered to the node.
+type Handler func(m Message)
+
+type timer struct {
+	at Time
+	id int
+	f  func()
+}
+
+// Sim is a simulation of a single test case. It is not safe for concurrent use.
+type Sim struct {
+	t        *rapid.T
+	now      Time
+	nodes    map[string]Handler
+	inflight []Message
+	timers   []timer
+	timerID  int
+}
+
+// New creates a simulation which draws its decisions from t.
+func New(t *rapid.T) *Sim {
+	return &Sim{
+		t:     t,
+		nodes: map[string]Handler{},
+	}
+}
+
+// Now returns the current logical time.
+func (s *Sim) Now() Time {
+	return s.now
+}
+
+// Node adds a node with the given name, which handles the messages sent to it.
+func (s *Sim) Node(name string, h Handler) {
+	if _, ok := s.nodes[name]; ok {
+		panic(fmt.Sprintf("node %q already exists", name))
+	}
+
+	s.nodes[name] = h
+}
+
+// Send puts a message in flight.
+func (s *Sim) Send(from string, to string, payload interface{}) {
+	if _, ok := s.nodes[to]; !ok {
+		panic(fmt.Sprintf("message sent to unknown node %q", to))
+	}
+
+	s.inflight = append(s.inflight, Message{From: from, To: to, Payload: payload, Sent: s.now})
+}
+
+// After makes f run when the logical time reaches Now()+d.
+func (s *Sim) After(d Time, f func()) {
+	s.timers = append(s.timers, timer{at: s.now + d, id: s.timerID, f: f})
+	s.timerID++
+	sort.SliceStable(s.timers, func(i, j int) bool { return s.timers[i].at < s.timers[j].at })
+}
+
+// Step chooses one of the messages in flight (and what happens to it), or fires
+// the earliest timer, and returns false when there is nothing left to do.
+// Delivery of a message takes a tick; firing a timer advances the time to its deadline.
+func (s *Sim) Step() bool {
+	n := len(s.inflight)
+	if len(s.timers) > 0 {
+		n++
+	}
+	if n == 0 {
+		return false
+	}
+
+	i := rapid.IntRange(0, n-1).Draw(s.t, "event").(int)
+	if i == len(s.inflight) {
+		tm := s.timers[0]
+		s.timers = s.timers[1:]
+		if tm.at > s.now {
+			s.now = tm.at
+		}
+		s.t.Logf("[sim] %v: timer %v", s.now, tm.id)
+		tm.f()
+		return true
+	}
+
+	m := s.inflight[i]
+	fate := fates.Draw(s.t, "fate").(Fate)
+	if fate != Duplicate {
+		s.inflight = append(s.inflight[:i], s.inflight[i+1:]...)
+	}
+	s.now++
+	s.t.Logf("[sim] %v: %v %v", s.now, fate, m)
+	if fate != Drop {
+		s.nodes[m.To](m)
+	}
+
+	return true
+}
+
+// Run makes at most maxSteps steps, and returns the number of steps made.
+func (s *Sim) Run(maxSteps int) int {
+	n := 0
+	for n < maxSteps && s.Step() {
+		n++
+	}
+
+	return n
+}
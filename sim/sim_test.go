@@ -0,0 +1,105 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package sim
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+)
+
+type request struct{ id int }
+type ack struct{ id int }
+
+// counterServer increments its counter once per request, ignoring duplicates.
+type counterServer struct {
+	s     *Sim
+	seen  map[int]bool
+	count int
+}
+
+func (c *counterServer) handle(m Message) {
+	id := m.Payload.(request).id
+	if !c.seen[id] {
+		c.seen[id] = true
+		c.count++
+	}
+	c.s.Send("server", m.From, ack{id})
+}
+
+// retryingClient resends every request until it is acknowledged.
+type retryingClient struct {
+	s     *Sim
+	acked map[int]bool
+}
+
+func (c *retryingClient) send(id int) {
+	if c.acked[id] {
+		return
+	}
+	c.s.Send("client", "server", request{id})
+	c.s.After(10, func() { c.send(id) })
+}
+
+func (c *retryingClient) handle(m Message) {
+	c.acked[m.Payload.(ack).id] = true
+}
+
+func runCounter(t *rapid.T) (*counterServer, *retryingClient) {
+	s := New(t)
+	server := &counterServer{s: s, seen: map[int]bool{}}
+	client := &retryingClient{s: s, acked: map[int]bool{}}
+	s.Node("server", server.handle)
+	s.Node("client", client.handle)
+
+	n := rapid.IntRange(1, 5).Draw(t, "requests").(int)
+	for id := 0; id < n; id++ {
+		client.send(id)
+	}
+	s.Run(200)
+
+	return server, client
+}
+
+func TestSim_RetriesAreIdempotent(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		server, client := runCounter(t)
+		if server.count < len(client.acked) {
+			t.Fatalf("%v requests acknowledged, but only %v counted", len(client.acked), server.count)
+		}
+		if server.count != len(server.seen) {
+			t.Fatalf("%v distinct requests counted %v times", len(server.seen), server.count)
+		}
+	})
+}
+
+func TestSim_Deterministic(t *testing.T) {
+	gen := rapid.Custom(func(t *rapid.T) string {
+		server, client := runCounter(t)
+		return fmt.Sprint(server.seen, client.acked)
+	})
+
+	for seed := 0; seed < 10; seed++ {
+		a := gen.Example(seed).(string)
+		b := gen.Example(seed).(string)
+		if a != b {
+			t.Errorf("seed %v: %q vs %q", seed, a, b)
+		}
+	}
+}
+
+func TestFate_GoString(t *testing.T) {
+	var names []string
+	for _, f := range []Fate{Deliver, Drop, Duplicate} {
+		names = append(names, fmt.Sprintf("%#v", f))
+	}
+	if s := strings.Join(names, " "); s != "deliver drop duplicate" {
+		t.Errorf("got %q", s)
+	}
+}
@@ -7,8 +7,12 @@
 package rapid
 
 import (
+	"fmt"
 	"reflect"
+	"runtime"
 	"sort"
+	"strings"
+	"time"
 )
 
 const (
@@ -19,17 +23,37 @@ const (
 	checkMethodName   = "Check"
 	cleanupMethodName = "Cleanup"
 
+	preconditionSuffix  = "Precondition"
+	postconditionSuffix = "Postcondition"
+	weightSuffix        = "Weight"
+	invariantSuffix     = "Invariant"
+
 	noValidActionsMsg = "can't find a valid action"
 )
 
 type StateMachine interface {
 	// Check is ran after every action and should contain invariant checks.
+	// When Check fails, the action it has been ran after is reported,
+	// which is the action that has broken the invariant.
 	//
 	// Other public methods are treated as follows:
 	// - Init(t *rapid.T), if present, is ran at the beginning of each test case
 	//   to initialize the state machine instance;
-	// - Cleanup(), if present, is called at the end of each test case;
-	// - All other public methods should have a form ActionName(t *rapid.T)
+	// - Cleanup(), if present, is called at the end of each test case
+	//   (resources acquired before Init has finished should instead be
+	//   released using (*rapid.T).Cleanup, which is called even if Init fails);
+	// - ActionNamePrecondition() bool, if present, is called before choosing
+	//   the next action; ActionName is only chosen when it returns true;
+	// - ActionNameWeight() int, if present, is called before choosing the next
+	//   action, and makes ActionName that many times more likely to be chosen
+	//   than an action with the default weight of 1 (0 prevents ActionName
+	//   from being chosen);
+	// - ActionNamePostcondition(t *rapid.T, result interface{}), if present,
+	//   is called after ActionName with its result, and should check it;
+	// - NameInvariant() error, if present, is called after Check (in the order
+	//   of the names), and fails the test case when it returns an error;
+	// - All other public methods should have a form ActionName(t *rapid.T),
+	//   or ActionName(t *rapid.T) interface{} for actions with a result,
 	//   and are used as possible actions. At least one action has to be specified.
 	//
 	Check(*T)
@@ -62,110 +86,315 @@ func Run(m StateMachine) func(*T) {
 		repeat := newRepeat(0, flags.steps, maxInt)
 
 		sm := newStateMachine(typ)
+		t.temporal = nil
+		t.trace.begin(t, initMethodName)
 		if sm.init != nil {
 			sm.init(t)
 			t.failOnError()
 		}
+		t.trace.end(t, sm.value)
 		if sm.cleanup != nil {
 			defer sm.cleanup()
 		}
 
-		sm.check(t)
-		t.failOnError()
-		for repeat.more(t.s, typ.String()) {
-			ok := sm.executeAction(t)
+		for _, name := range sm.actionKeys {
+			t.recordAction(name, actionDeclared)
+		}
+
+		sm.checkAfter(t, 0, initMethodName)
+		t.checkTemporal(0, initMethodName)
+		for step := 1; sm.more(t, repeat, step); {
+			keys, weights := sm.enabled(t)
+			if len(keys) == 0 {
+				repeat.forceStop = true // no action can be chosen anymore
+				repeat.reject()
+				continue
+			}
+			name, ok := sm.executeAction(t, keys, weights, step)
 			if ok {
-				sm.check(t)
-				t.failOnError()
+				t.trace.end(t, sm.value)
+				sm.checkAfter(t, step, name)
+				t.checkTemporal(step, name)
+				step++
 			} else {
+				t.plan.abort(step)
+				t.trace.abort()
+				t.abortTemporal()
 				repeat.reject()
 			}
 		}
+		t.checkEventually()
 	}
 }
 
 type stateMachine struct {
+	typ        reflect.Type
+	value      interface{}
 	init       func(*T)
 	cleanup    func()
 	check      func(*T)
-	actionKeys *Generator
-	actions    map[string]func(*T)
+	actionKeys []string
+	actionGen  *Generator
+	actions    map[string]*action
+	invariants []invariant // sorted by name
+	dynamic    bool        // action choice depends on the state
+}
+
+type invariant struct {
+	name  string
+	check func() error
+}
+
+type action struct {
+	run    func(*T) interface{}
+	pre    func() bool
+	post   func(*T, interface{})
+	weight func() int
 }
 
 func newStateMachine(typ reflect.Type) *stateMachine {
 	assertf(typ.Kind() == reflect.Ptr, "state machine type should be a pointer, not %v", typ.Kind())
 
 	var (
-		v          = reflect.New(typ.Elem())
-		n          = typ.NumMethod()
-		init       func(*T)
-		cleanup    func()
-		actionKeys []string
-		actions    = map[string]func(*T){}
+		v       = reflect.New(typ.Elem())
+		n       = typ.NumMethod()
+		init    func(*T)
+		cleanup func()
+		pre     = map[string]func() bool{}
+		post    = map[string]func(*T, interface{}){}
+		weight  = map[string]func() int{}
+		sm      = &stateMachine{actions: map[string]*action{}}
 	)
 
 	for i := 0; i < n; i++ {
 		name := typ.Method(i).Name
-		m, ok := v.Method(i).Interface().(func(*T))
-		if ok {
+		switch m := v.Method(i).Interface().(type) {
+		case func(*T):
 			if name == initMethodName {
 				init = m
 			} else if name != checkMethodName {
-				actionKeys = append(actionKeys, name)
-				actions[name] = m
+				sm.actions[name] = &action{run: func(t *T) interface{} { m(t); return nil }}
+			}
+		case func(*T) interface{}:
+			sm.actions[name] = &action{run: m}
+		case func() bool:
+			if strings.HasSuffix(name, preconditionSuffix) {
+				pre[strings.TrimSuffix(name, preconditionSuffix)] = m
+			}
+		case func() int:
+			if strings.HasSuffix(name, weightSuffix) {
+				weight[strings.TrimSuffix(name, weightSuffix)] = m
+			}
+		case func(*T, interface{}):
+			if strings.HasSuffix(name, postconditionSuffix) {
+				post[strings.TrimSuffix(name, postconditionSuffix)] = m
+			}
+		case func() error:
+			if strings.HasSuffix(name, invariantSuffix) {
+				sm.invariants = append(sm.invariants, invariant{name, m}) // methods are sorted by name
+			}
+		default:
+			if name == cleanupMethodName {
+				m, ok := v.Method(i).Interface().(func())
+				assertf(ok, "method %v should have type func(), not %v", cleanupMethodName, v.Method(i).Type())
+				cleanup = m
 			}
-		} else if name == cleanupMethodName {
-			m, ok := v.Method(i).Interface().(func())
-			assertf(ok, "method %v should have type func(), not %v", cleanupMethodName, v.Method(i).Type())
-			cleanup = m
 		}
 	}
 
-	assertf(len(actions) > 0, "state machine of type %v has no actions specified", typ)
-	sort.Strings(actionKeys)
+	assertf(len(sm.actions) > 0, "state machine of type %v has no actions specified", typ)
+	for name, m := range pre {
+		a, ok := sm.actions[name]
+		assertf(ok, "method %v%v of %v has no matching action", name, preconditionSuffix, typ)
+		a.pre = m
+		sm.dynamic = true
+	}
+	for name, m := range weight {
+		a, ok := sm.actions[name]
+		assertf(ok, "method %v%v of %v has no matching action", name, weightSuffix, typ)
+		a.weight = m
+		sm.dynamic = true
+	}
+	for name, m := range post {
+		a, ok := sm.actions[name]
+		assertf(ok, "method %v%v of %v has no matching action", name, postconditionSuffix, typ)
+		a.post = m
+	}
+	for name := range sm.actions {
+		sm.actionKeys = append(sm.actionKeys, name)
+	}
+	sort.Strings(sm.actionKeys)
+
+	sm.typ = typ
+	sm.init = init
+	sm.cleanup = cleanup
+	sm.value = v.Interface()
+	sm.check = sm.value.(StateMachine).Check
+	sm.actionGen = SampledFrom(sm.actionKeys)
+
+	return sm
+}
 
-	return &stateMachine{
-		init:       init,
-		cleanup:    cleanup,
-		check:      v.Interface().(StateMachine).Check,
-		actionKeys: SampledFrom(actionKeys),
-		actions:    actions,
+// enabled returns the names of the actions whose preconditions hold, and their
+// cumulative weights (nil when all the actions are always enabled, with the same weight).
+func (sm *stateMachine) enabled(t *T) ([]string, []int) {
+	if !sm.dynamic {
+		return sm.actionKeys, nil
 	}
+
+	var (
+		keys  []string
+		cum   []int
+		total int
+	)
+	for _, name := range sm.actionKeys {
+		a := sm.actions[name]
+		w := 1
+		if a.pre != nil && !a.pre() {
+			w = 0
+		} else if a.weight != nil {
+			w = a.weight()
+			assertf(w >= 0, "method %v%v has returned negative weight %v", name, weightSuffix, w)
+		}
+		if w == 0 {
+			t.recordAction(name, actionDisabled)
+			continue
+		}
+		total += w
+		keys = append(keys, name)
+		cum = append(cum, total)
+	}
+	return keys, cum
+}
+
+// checkAfter runs Check and the invariants after the step (0 for Init),
+// logging the step when one of them fails.
+func (sm *stateMachine) checkAfter(t *T, step int, name string) {
+	failed := checkMethodName
+	defer func() {
+		if failed != "" {
+			t.Logf("[rapid] %v has failed after step %v (%v)", failed, step, name)
+		}
+	}()
+
+	sm.check(t)
+	t.failOnError()
+	for _, inv := range sm.invariants {
+		failed = inv.name
+		if err := inv.check(); err != nil {
+			t.Fatalf("%v: %v", inv.name, err)
+		}
+	}
+	failed = ""
+}
+
+// more decides whether to execute another step, as planned when the sequences
+// of actions are enumerated.
+func (sm *stateMachine) more(t *T, repeat *repeat, step int) bool {
+	t.plan.forceMore(t, sm, repeat, step)
+	return repeat.more(t.s, sm.typ.String())
 }
 
-func (sm *stateMachine) executeAction(t *T) bool {
+func (sm *stateMachine) executeAction(t *T, keys []string, weights []int, step int) (string, bool) {
 	t.Helper()
 
+	gen := sm.actionGen
+	if weights != nil {
+		gen = weightedSampledFrom(keys, weights)
+	}
+
 	for n := 0; n < validActionTries; n++ {
+		t.plan.forceAction(t, sm, keys, weights, step, n)
 		i := t.s.beginGroup(actionLabel, false)
-		action := sm.actions[sm.actionKeys.Draw(t, "action").(string)]
-		invalid, skipped := runAction(t, action)
+		name := gen.Draw(t, "action").(string)
+		t.trace.begin(t, name)
+		t.recordAction(name, actionChosen)
+		invalid, skipped := runAction(t, name, sm.actions[name])
 		t.s.endGroup(i, false)
 
 		if skipped {
 			continue
 		} else {
-			return !invalid
+			if !invalid {
+				t.recordAction(name, actionExecuted)
+			}
+			return name, !invalid
 		}
 	}
 
 	panic(stopTest(noValidActionsMsg))
 }
 
-func runAction(t *T, action func(*T)) (invalid bool, skipped bool) {
-	defer func(draws int) {
+func runAction(t *T, name string, action *action) (invalid bool, skipped bool) {
+	defer func(draws int, depth int, path string) {
 		if r := recover(); r != nil {
 			if _, ok := r.(invalidData); ok {
 				invalid = true
 				skipped = t.draws == draws
+				t.depth, t.path = depth, path // of the interrupted draw
 			} else {
 				panic(r)
 			}
 		}
-	}(t.draws)
+	}(t.draws, t.depth, t.path)
 
-	action(t)
+	result := runBlocking(t, name, action.run)
+	t.trace.result(result)
 	t.failOnError()
+	if action.post != nil {
+		action.post(t, result)
+		t.failOnError()
+	}
 
 	return false, false
 }
+
+// actionTimeout is the failure of an action which has blocked for longer than
+// the action timeout.
+type actionTimeout string
+
+// runBlocking runs the action, failing the test case when it blocks for longer
+// than the action timeout. Then the action is left running in its own goroutine,
+// still using t and its data, so the check is stopped without touching t again.
+func runBlocking(t *T, name string, run func(*T) interface{}) interface{} {
+	d := t.actionTimeout
+	if d <= 0 {
+		return run(t)
+	}
+
+	type outcome struct {
+		result   interface{}
+		panic    interface{}
+		returned bool
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		var o outcome
+		defer func() {
+			if !o.returned {
+				o.panic = recover()
+			}
+			done <- o
+		}()
+		o.result = run(t)
+		o.returned = true
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case o := <-done:
+		if !o.returned {
+			if o.panic == nil {
+				t.Fatalf("action %v has called runtime.Goexit", name)
+			}
+			panic(o.panic)
+		}
+		return o.result
+	case <-timer.C:
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		panic(actionTimeout(fmt.Sprintf("action %v has not finished in %v, goroutines:\n%s", name, d, buf)))
+	}
+}
@@ -6,7 +6,17 @@
 
 package rapid
 
-import "testing"
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
 
 // https://github.com/leanovate/gopter/blob/master/commands/example_circularqueue_test.go
 var gopterBug = false
@@ -73,6 +83,79 @@ func TestStateMachine_Counter(t *testing.T) {
 	)
 }
 
+func TestStateMachine_CheckLogsStep(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, Run(&counterMachine{}))
+	removeFailFiles(t.Name())
+
+	if !tb.failed {
+		t.Fatal("check did not fail")
+	}
+	out := tb.out.String()
+	if line := "[rapid] Check has failed after step 5 (Dec)\n"; !strings.Contains(out, line) {
+		t.Errorf("%q not found in output:\n%v", line, out)
+	}
+}
+
+// invariantMachine lets the counter go below zero.
+type invariantMachine struct {
+	n int
+}
+
+func (m *invariantMachine) Inc(*T)   { m.n++ }
+func (m *invariantMachine) Dec(*T)   { m.n-- }
+func (m *invariantMachine) Check(*T) {}
+func (m *invariantMachine) NonNegativeInvariant() error {
+	if m.n < 0 {
+		return fmt.Errorf("counter is %v", m.n)
+	}
+	return nil
+}
+
+func TestStateMachine_Invariant(t *testing.T) {
+	t.Parallel()
+
+	checkShrink(t, Run(&invariantMachine{}),
+		"Dec",
+	)
+}
+
+func TestStateMachine_InvariantLogsStep(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, Run(&invariantMachine{}))
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	for _, s := range []string{"NonNegativeInvariant: counter is -1", "[rapid] NonNegativeInvariant has failed after step 1 (Dec)\n"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in output:\n%v", s, out)
+		}
+	}
+}
+
+func TestStateMachine_Graph(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rapid-dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	tb := &logTB{T: t}
+	checkTB(tb, Run(&counterMachine{}), ExportStateMachineGraphs(dir))
+	removeFailFiles(t.Name())
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, persistFileName(t.Name(), "dot")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dot := string(b)
+	for _, s := range []string{"s0 -> s1 [label=\"Init\"];\n", "s5 -> s6 [label=\"Dec\"];\n", "color=red"} {
+		if !strings.Contains(dot, s) {
+			t.Errorf("%q not found in graph:\n%v", s, dot)
+		}
+	}
+}
+
 type haltingMachine struct {
 	a []int
 	b []int
@@ -207,6 +290,110 @@ func TestStateMachine_Queue(t *testing.T) {
 	)
 }
 
+func TestStateMachine_Script(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, Run(&queueMachine{}))
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	script := "0. Init(size=1) → ok\n1. Put(n=0) → ok\n2. Get() → ok\n3. Put(n=0) → ok\nfailed after step 3: queue size mismatch"
+	if !strings.Contains(out, script) {
+		t.Errorf("%q not found in output:\n%v", script, out)
+	}
+	if strings.Contains(out, "rapid.Replay") {
+		t.Errorf("reproduction code printed for a state machine:\n%v", out)
+	}
+}
+
+type alwaysMachine struct {
+	n int
+}
+
+func (m *alwaysMachine) Init(t *T) {
+	t.Always("n >= 0", func() bool { return m.n >= 0 })
+}
+
+func (m *alwaysMachine) Inc(t *T) {
+	m.n++
+}
+
+func (m *alwaysMachine) Dec(t *T) {
+	m.n--
+}
+
+func (m *alwaysMachine) Check(t *T) {}
+
+type eventuallyMachine struct {
+	started bool
+	stopped bool
+}
+
+func (m *eventuallyMachine) Start(t *T) {
+	if !m.started {
+		m.started = true
+		t.Eventually("stopped", func() bool { return m.stopped })
+	}
+}
+
+func (m *eventuallyMachine) Stop(t *T) {
+	m.stopped = !m.started // bug: only stops what has not been started
+}
+
+func (m *eventuallyMachine) Check(t *T) {}
+
+func TestStateMachine_Temporal(t *testing.T) {
+	for m, s := range map[StateMachine]string{
+		&alwaysMachine{}:     "n >= 0 does not hold after step 1 (Dec)",
+		&eventuallyMachine{}: "stopped has not held since step 1 (Start)",
+	} {
+		tb := &logTB{T: t}
+		checkTB(tb, Run(m))
+		removeFailFiles(t.Name())
+
+		if !strings.Contains(tb.out.String(), s) {
+			t.Errorf("%q not found in output:\n%v", s, tb.out.String())
+		}
+	}
+}
+
+func TestStateMachine_Trace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rapid-trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	tb := &logTB{T: t}
+	checkTB(tb, Run(&queueMachine{}), ExportStateMachineTraces(dir))
+	removeFailFiles(t.Name())
+
+	filename := filepath.Join(dir, persistFileName(t.Name(), "trace"))
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := "Init\n\tsize: 1\nPut\n\tn: 0\nGet\nPut\n\tn: 0\n"
+	if !strings.HasSuffix(string(b), steps) {
+		t.Fatalf("trace file does not end with %q:\n%s", steps, b)
+	}
+
+	trace, err := loadMachineTrace(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nt := newT(t, newBufBitStream(nil, false), false, nil)
+	err1 := checkOnce(nt, func(t *T) { replayMachine(t, reflect.TypeOf(&queueMachine{}), trace) })
+	if err1 == nil || !strings.Contains(err1.Error(), "queue size mismatch") {
+		t.Fatalf("trace did not reproduce the failure: %v", err1)
+	}
+
+	edited := filepath.Join(dir, "edited.trace")
+	if err := ioutil.WriteFile(edited, []byte("Init\n\tsize: 2\nPut\n\tn: 0\nGet\nPut\n\tn: 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ReplayTrace(t, &queueMachine{}, edited)
+}
+
 type garbageMachine struct {
 	a []int
 	b []int
@@ -263,8 +450,204 @@ func TestStateMachine_DiscardGarbage(t *testing.T) {
 	)
 }
 
+// fifoStackMachine tests a stack which returns the elements in the wrong order.
+type fifoStackMachine struct {
+	stack []int
+	model []int
+}
+
+func (m *fifoStackMachine) Push(t *T) {
+	if len(m.stack) == 3 {
+		t.Fatal("Push called with full stack")
+	}
+
+	n := IntMin(0).Draw(t, "n").(int)
+	m.stack = append(m.stack, n)
+	m.model = append(m.model, n)
+}
+
+func (m *fifoStackMachine) PushPrecondition() bool {
+	return len(m.model) < 3
+}
+
+func (m *fifoStackMachine) Pop(t *T) interface{} {
+	if len(m.stack) == 0 {
+		t.Fatal("Pop called with empty stack")
+	}
+
+	n := m.stack[0]
+	m.stack = m.stack[1:]
+	return n
+}
+
+func (m *fifoStackMachine) PopPrecondition() bool {
+	return len(m.model) > 0
+}
+
+func (m *fifoStackMachine) PopPostcondition(t *T, result interface{}) {
+	n := m.model[len(m.model)-1]
+	m.model = m.model[:len(m.model)-1]
+	if result != n {
+		t.Fatalf("got %v instead of %v", result, n)
+	}
+}
+
+func (m *fifoStackMachine) Check(*T) {}
+
+func TestStateMachine_Conditions(t *testing.T) {
+	t.Parallel()
+
+	checkShrink(t, Run(&fifoStackMachine{}),
+		"Push", 0,
+		"Push", 1,
+		"Pop",
+	)
+}
+
+var weightedActions = map[string]int{}
+
+type weightedMachine struct{}
+
+func (m *weightedMachine) Common(*T) { weightedActions["Common"]++ }
+func (m *weightedMachine) Heavy(*T)  { weightedActions["Heavy"]++ }
+func (m *weightedMachine) HeavyWeight() int {
+	return 9
+}
+func (m *weightedMachine) Never(t *T) { t.Fatal("action with zero weight has been chosen") }
+func (m *weightedMachine) NeverWeight() int {
+	return 0
+}
+func (m *weightedMachine) Check(*T) {}
+
+func TestStateMachine_Weights(t *testing.T) {
+	_, _, _, _, err := findBug(t, &settings{checks: 100}, &corpus{}, baseSeed(), Run(&weightedMachine{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	common, heavy := weightedActions["Common"], weightedActions["Heavy"]
+	if heavy < 5*common {
+		t.Fatalf("action with weight 9 chosen %v times, action with weight 1 %v times", heavy, common)
+	}
+}
+
+type hugeWeightMachine struct{}
+
+func (m *hugeWeightMachine) Common(*T)        {}
+func (m *hugeWeightMachine) Heavy(*T)         {}
+func (m *hugeWeightMachine) HeavyWeight() int { return 1000000 }
+func (m *hugeWeightMachine) Check(*T)         {}
+
+func TestStateMachine_HugeWeight(t *testing.T) {
+	sm := newStateMachine(reflect.TypeOf(&hugeWeightMachine{}))
+	keys, weights := sm.enabled(newT(t, newRandomBitStream(baseSeed(), false), false, nil))
+	if fmt.Sprint(keys) != "[Common Heavy]" || fmt.Sprint(weights) != "[1 1000001]" {
+		t.Fatalf("got actions %v with cumulative weights %v", keys, weights)
+	}
+
+	valid, _, err := findBugExhaustive(t, &settings{exhaustiveSteps: 2}, baseSeed(), Run(&hugeWeightMachine{}))
+	if err != nil || valid != 1+2+4 {
+		t.Fatalf("%v sequences of at most 2 of 2 weighted actions checked (%v)", valid, err)
+	}
+}
+
+var openResources int64
+
+type resourceMachine struct{}
+
+func (m *resourceMachine) Init(t *T) {
+	atomic.AddInt64(&openResources, 1)
+	t.Cleanup(func() { atomic.AddInt64(&openResources, -1) })
+
+	if IntRange(0, 10).Draw(t, "init").(int) == 5 {
+		panic("init failed")
+	}
+}
+
+func (m *resourceMachine) Use(t *T) {
+	if IntRange(0, 10).Draw(t, "use").(int) == 5 {
+		panic("use failed")
+	}
+}
+
+func (m *resourceMachine) Check(*T) {}
+
+func TestStateMachine_CleanupResources(t *testing.T) {
+	tb := &logTB{T: t}
+	checkTB(tb, Run(&resourceMachine{}))
+	removeFailFiles(t.Name())
+
+	if !tb.failed {
+		t.Fatal("check did not fail")
+	}
+	if n := atomic.LoadInt64(&openResources); n != 0 {
+		t.Fatalf("%v resources left open", n)
+	}
+}
+
+// lockRelease unblocks the actions of lockMachine at the end of the test,
+// which then call lockAfterRelease, if not nil.
+var (
+	lockRelease      chan struct{}
+	lockAfterRelease func(*T)
+)
+
+// lockMachine deadlocks when Lock is called twice.
+type lockMachine struct {
+	sem chan struct{}
+}
+
+func (m *lockMachine) Init(*T) { m.sem = make(chan struct{}, 1) }
+func (m *lockMachine) Lock(t *T) {
+	select {
+	case m.sem <- struct{}{}:
+	case <-lockRelease:
+		if lockAfterRelease != nil {
+			lockAfterRelease(t)
+		}
+	}
+}
+func (m *lockMachine) Unlock(*T) {
+	select {
+	case <-m.sem:
+	default:
+	}
+}
+func (m *lockMachine) Check(*T) {}
+
+func TestStateMachine_ActionTimeout(t *testing.T) {
+	lockRelease = make(chan struct{})
+	lockDone := make(chan struct{})
+	lockAfterRelease = func(t *T) {
+		_ = Int().Draw(t, "after") // the abandoned action keeps using t
+		close(lockDone)
+	}
+	defer func() {
+		close(lockRelease)
+		<-lockDone
+		lockAfterRelease = nil
+	}()
+
+	tb := &logTB{T: t}
+	checkTB(tb, Run(&lockMachine{}), ActionTimeout(10*time.Millisecond))
+	removeFailFiles(t.Name())
+
+	if !tb.failed {
+		t.Fatal("deadlock not detected")
+	}
+	out := tb.out.String()
+	for _, s := range []string{"action Lock has not finished in 10ms", "lockMachine).Lock", "-rapid.seed="} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in output:\n%v", s, out)
+		}
+	}
+	if strings.Contains(out, "[rapid] minimized") {
+		t.Errorf("test case with a running action has been minimized:\n%v", out)
+	}
+}
+
 func BenchmarkCheckQueue(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		_, _, _, _, _, _ = doCheck(b, "", 100, baseSeed(), Run(&queueMachine{}))
+		_, _, _, _, _, _ = doCheck(b, shrinkTestSettings(1), baseSeed(), Run(&queueMachine{}))
 	}
 }
@@ -0,0 +1,733 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	complexityMinCases  = 10
+	distributionMaxRows = 20
+	degenerateMinDraws  = 20
+	degenerateRatio     = 0.9
+	degenerateMaxValues = 256
+	exhaustMaxDistinct  = 1 << 14
+	hashMaxDepth        = 16
+)
+
+// caseStats collects the statistics reported by a single test case.
+type caseStats struct {
+	events    []string
+	collected []collectedValue
+	observed  []observation
+	filtered  []filterResult
+	drawn     []drawnValue
+	domain    float64 // product of the domain sizes of the draws (see domainSize), if any
+	actions   []actionResult
+}
+
+type filterResult struct {
+	filter   string
+	rejected bool
+}
+
+type observation struct {
+	label string
+	value float64
+}
+
+type collectedValue struct {
+	label string
+	value string
+}
+
+func (cs *caseStats) reset() {
+	cs.events = cs.events[:0]
+	cs.collected = cs.collected[:0]
+	cs.observed = cs.observed[:0]
+	cs.filtered = cs.filtered[:0]
+	cs.drawn = cs.drawn[:0]
+	cs.actions = cs.actions[:0]
+}
+
+// runStats collects statistics about the valid test cases of a run.
+type runStats struct {
+	cases     int
+	events    map[string]int            // number of test cases with every event
+	labels    []string                  // labels of the collected values, in order of appearance
+	collected map[string]map[string]int // number of times every value was collected, by label
+	obsLabels []string                  // labels of the observations, in order of appearance
+	observed  map[string][]float64      // observed values, by label
+	discarded int                       // number of invalid test cases
+	filters   []string                  // filters, in order of appearance
+	filtered  map[string]*filterStats   // values tried and rejected, by filter
+	draws     []string                  // labels of the draws, in order of appearance
+	drawn     map[string]*drawStats     // drawn values, by label
+	distinct  map[uint64]bool           // hashes of the values drawn by the test cases, up to exhaustMaxDistinct
+	domain    float64                   // largest domain of the test cases, +Inf if any is unknown
+	sizes     []float64                 // amount of data used by every test case
+	durations []float64                 // running time of every test case, in seconds
+	actions   map[string]*actionStats   // state machine action statistics, by action
+}
+
+// drawStats counts the values drawn under a label by their hashes. Only the first
+// degenerateMaxValues distinct values are counted and printed.
+type drawStats struct {
+	total   int
+	counts  map[uint64]int
+	printed map[uint64]string
+}
+
+type filterStats struct {
+	tried    int
+	rejected int
+}
+
+// addDiscarded accounts for the invalid test case.
+func (st *runStats) addDiscarded(cs *caseStats) {
+	st.discarded++
+	st.addFiltered(cs)
+	st.addActions(cs)
+}
+
+func (st *runStats) addFiltered(cs *caseStats) {
+	for _, f := range cs.filtered {
+		if st.filtered == nil {
+			st.filtered = map[string]*filterStats{}
+		}
+		fs, ok := st.filtered[f.filter]
+		if !ok {
+			fs = &filterStats{}
+			st.filtered[f.filter] = fs
+			st.filters = append(st.filters, f.filter)
+		}
+		fs.tried++
+		if f.rejected {
+			fs.rejected++
+		}
+	}
+}
+
+func (st *runStats) addCase(cs *caseStats) {
+	st.cases++
+	st.addFiltered(cs)
+	st.addActions(cs)
+
+	h := fnv.New64a()
+	for _, d := range cs.drawn {
+		_, _ = h.Write([]byte(d.label))
+		_, _ = h.Write([]byte{0})
+		_ = binary.Write(h, binary.LittleEndian, valueHash(d.v))
+	}
+	if st.distinct == nil {
+		st.distinct = map[uint64]bool{}
+	}
+	if k := h.Sum64(); !st.distinct[k] && len(st.distinct) < exhaustMaxDistinct {
+		st.distinct[k] = true
+	}
+	if len(cs.drawn) == 0 {
+		st.domain = math.Inf(1) // nothing is known about the test case
+	} else if cs.domain > st.domain {
+		st.domain = cs.domain
+	}
+	for i, e := range cs.events {
+		if indexOf(cs.events[:i], e) >= 0 {
+			continue // count every event once per test case
+		}
+		if st.events == nil {
+			st.events = map[string]int{}
+		}
+		st.events[e]++
+	}
+
+	for _, c := range cs.collected {
+		if st.collected == nil {
+			st.collected = map[string]map[string]int{}
+		}
+		counts, ok := st.collected[c.label]
+		if !ok {
+			counts = map[string]int{}
+			st.collected[c.label] = counts
+			st.labels = append(st.labels, c.label)
+		}
+		counts[c.value]++
+	}
+
+	for _, d := range cs.drawn {
+		if d.label == "" {
+			continue
+		}
+		if st.drawn == nil {
+			st.drawn = map[string]*drawStats{}
+		}
+		ds, ok := st.drawn[d.label]
+		if !ok {
+			ds = &drawStats{counts: map[uint64]int{}, printed: map[uint64]string{}}
+			st.drawn[d.label] = ds
+			st.draws = append(st.draws, d.label)
+		}
+		ds.total++
+		k := valueHash(d.v)
+		if _, ok := ds.counts[k]; ok || len(ds.counts) < degenerateMaxValues {
+			if !ok {
+				ds.printed[k] = d.String()
+			}
+			ds.counts[k]++
+		}
+	}
+
+	for _, o := range cs.observed {
+		if st.observed == nil {
+			st.observed = map[string][]float64{}
+		}
+		if _, ok := st.observed[o.label]; !ok {
+			st.obsLabels = append(st.obsLabels, o.label)
+		}
+		st.observed[o.label] = append(st.observed[o.label], o.value)
+	}
+}
+
+func indexOf(ss []string, s string) int {
+	for i, t := range ss {
+		if t == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func (st *runStats) addTiming(size int, dt time.Duration) {
+	st.sizes = append(st.sizes, float64(size))
+	st.durations = append(st.durations, dt.Seconds())
+}
+
+type complexityClass struct {
+	name string
+	fn   func(n float64) float64
+}
+
+var complexityClasses = []complexityClass{
+	{"O(1)", func(n float64) float64 { return 1 }},
+	{"O(log n)", func(n float64) float64 { return math.Log(n + 1) }},
+	{"O(n)", func(n float64) float64 { return n }},
+	{"O(n log n)", func(n float64) float64 { return n * math.Log(n+1) }},
+	{"O(n²)", func(n float64) float64 { return n * n }},
+	{"O(n³)", func(n float64) float64 { return n * n * n }},
+}
+
+// fitComplexity returns the complexity class which describes the durations
+// as a function of sizes best (in the least squares sense), or an empty string
+// if there is not enough data to tell.
+func fitComplexity(sizes []float64, durations []float64) string {
+	distinct := map[float64]bool{}
+	for _, n := range sizes {
+		distinct[n] = true
+	}
+	if len(sizes) < complexityMinCases || len(distinct) < 3 {
+		return ""
+	}
+
+	best, bestErr := "", math.Inf(1)
+	for _, c := range complexityClasses {
+		var tf, ff float64
+		for i, n := range sizes {
+			f := c.fn(n)
+			tf += durations[i] * f
+			ff += f * f
+		}
+		k := tf / ff
+		var e float64
+		for i, n := range sizes {
+			d := durations[i] - k*c.fn(n)
+			e += d * d
+		}
+		if e < bestErr {
+			best, bestErr = c.name, e
+		}
+	}
+
+	return best
+}
+
+func complexityFileName(testName string) string {
+	return filepath.Join(failFileDir(testName), "complexity")
+}
+
+// checkComplexityBaseline compares the complexity class with the one stored
+// in the baseline file, returning the stored class if it differs. The class
+// is saved as the baseline when there is none yet.
+func checkComplexityBaseline(filename string, class string) (string, error) {
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return "", writeFileAtomic(filename, []byte(class+"\n"))
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read complexity baseline %q: %w", filename, err)
+	}
+
+	if base := strings.TrimSpace(string(b)); base != class {
+		return base, nil
+	}
+	return "", nil
+}
+
+func reportComplexity(tb tb, st *runStats) {
+	tb.Helper()
+
+	class := fitComplexity(st.sizes, st.durations)
+	if class == "" {
+		tb.Logf("[rapid] not enough test cases of different size to estimate the complexity")
+		return
+	}
+	tb.Logf("[rapid] apparent complexity is %v (n is the amount of data used by the test case, %v test cases)", class, len(st.sizes))
+
+	filename := complexityFileName(tb.Name())
+	base, err := checkComplexityBaseline(filename, class)
+	if err != nil {
+		tb.Logf("[rapid] %v", err)
+	} else if base != "" {
+		tb.Logf("[rapid] WARNING: apparent complexity has changed from %v to %v since the baseline %q", base, class, filename)
+	}
+}
+
+type eventRequirement struct {
+	event   string
+	percent float64
+}
+
+// Event marks the current test case as exercising the scenario described
+// by event (e.g. "overflow"). At the end of the run, rapid reports the percentage
+// of test cases with every event, and checks the requirements set with RequireEvent.
+func (t *T) Event(event string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stats != nil {
+		t.stats.events = append(t.stats.events, event)
+	}
+}
+
+// Classify marks the current test case as belonging to the class name when cond
+// is true; it is equivalent to calling Event(name) then. Use RequireEvent to make sure
+// the run has enough test cases of the class (e.g. that trivial cases do not dominate).
+func (t *T) Classify(cond bool, name string) {
+	if cond {
+		t.Event(name)
+	}
+}
+
+func percentage(n int, total int) float64 {
+	return 100 * float64(n) / float64(total)
+}
+
+// reportEvents logs the distribution of the events, and fails the test
+// if some of the events have occurred less often than required.
+func reportEvents(tb tb, st *runStats, required []eventRequirement) {
+	tb.Helper()
+
+	if st.cases == 0 {
+		return
+	}
+
+	if len(st.events) > 0 {
+		tb.Logf("[rapid] events (%v test cases):%v", st.cases, formatDistribution(st.events, st.cases))
+	}
+
+	for _, r := range required {
+		if p := percentage(st.events[r.event], st.cases); p < r.percent {
+			tb.Errorf("[rapid] event %q occurred in %.1f%% of test cases, less than the required %v%%", r.event, p, r.percent)
+		}
+	}
+}
+
+// Collect classifies the current test case by the value under the label
+// (e.g. the length of a generated slice). At the end of the run, rapid reports
+// how often every value was collected, to show what the generators actually produce.
+func (t *T) Collect(label string, value interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stats != nil {
+		t.stats.collected = append(t.stats.collected, collectedValue{label, fmt.Sprint(value)})
+	}
+}
+
+// reportCollected logs the frequency table of the values collected under every label.
+func reportCollected(tb tb, st *runStats) {
+	tb.Helper()
+
+	for _, label := range st.labels {
+		total := 0
+		for _, n := range st.collected[label] {
+			total += n
+		}
+		tb.Logf("[rapid] collected %q (%v values):%v", label, total, formatDistribution(st.collected[label], total))
+	}
+}
+
+// Observe records the numeric value (e.g. the length of a generated slice)
+// under the label. At the end of the run, rapid reports the minimum, median,
+// 90th percentile and maximum of the values observed under every label.
+func (t *T) Observe(label string, value float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stats != nil {
+		t.stats.observed = append(t.stats.observed, observation{label, value})
+	}
+}
+
+// reportObserved logs the summary of the values observed under every label.
+func reportObserved(tb tb, st *runStats) {
+	tb.Helper()
+
+	for _, label := range st.obsLabels {
+		values := append([]float64(nil), st.observed[label]...)
+		sort.Float64s(values)
+		tb.Logf("[rapid] observed %q (%v values): min %v, median %v, p90 %v, max %v",
+			label, len(values), values[0], percentile(values, 50), percentile(values, 90), values[len(values)-1])
+	}
+}
+
+// percentile returns the p-th percentile (using the nearest-rank method)
+// of the sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// mostFrequent returns the keys of the counts, most frequent first.
+func mostFrequent(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
+
+// formatDistribution formats the counts as table rows, most frequent first.
+func formatDistribution(counts map[string]int, total int) string {
+	keys := mostFrequent(counts)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i == distributionMaxRows {
+			fmt.Fprintf(&b, "\n  ... (%v more)", len(keys)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n  %v: %.1f%% (%v)", k, percentage(counts[k], total), counts[k])
+	}
+
+	return b.String()
+}
+
+func (t *T) recordFilter(filter string, rejected bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stats != nil {
+		t.stats.filtered = append(t.stats.filtered, filterResult{filter, rejected})
+	}
+}
+
+// reportDiscarded logs how many test cases were discarded (and how many values
+// every filter has rejected), and fails the test if the ratio of discarded
+// test cases is higher than maxRatio (when it is positive).
+func reportDiscarded(tb tb, st *runStats, maxRatio float64) {
+	tb.Helper()
+
+	total := st.cases + st.discarded
+	if total == 0 || (st.discarded == 0 && len(st.filters) == 0) {
+		return
+	}
+
+	var b strings.Builder
+	for _, f := range st.filters {
+		fs := st.filtered[f]
+		fmt.Fprintf(&b, "\n  %v: rejected %v of %v values (%.1f%%)", f, fs.rejected, fs.tried, percentage(fs.rejected, fs.tried))
+	}
+	tb.Logf("[rapid] discarded %v of %v test cases (%.1f%%)%v", st.discarded, total, percentage(st.discarded, total), b.String())
+
+	if ratio := float64(st.discarded) / float64(total); maxRatio > 0 && ratio > maxRatio {
+		tb.Errorf("[rapid] discarded %.1f%% of test cases, more than the maximum of %v%%", 100*ratio, 100*maxRatio)
+	}
+}
+
+func (t *T) recordDraw(g *Generator, d drawnValue) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.stats.drawn) == 0 {
+		t.stats.domain = 1
+	}
+	t.stats.drawn = append(t.stats.drawn, d)
+	t.stats.domain *= domainSize(g)
+}
+
+// valueHash hashes the value without formatting it.
+func valueHash(v value) uint64 {
+	h := fnv.New64a()
+	hashValue(h, reflect.ValueOf(v), 0)
+	return h.Sum64()
+}
+
+func hashValue(h hash.Hash64, rv reflect.Value, depth int) {
+	var b [8]byte
+	put := func(u uint64) {
+		binary.LittleEndian.PutUint64(b[:], u)
+		_, _ = h.Write(b[:])
+	}
+
+	if !rv.IsValid() || depth > hashMaxDepth {
+		put(0)
+		return
+	}
+	put(uint64(rv.Kind()))
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			put(1)
+		} else {
+			put(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		put(uint64(rv.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		put(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		put(math.Float64bits(rv.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		c := rv.Complex()
+		put(math.Float64bits(real(c)))
+		put(math.Float64bits(imag(c)))
+	case reflect.String:
+		put(uint64(rv.Len()))
+		_, _ = h.Write([]byte(rv.String()))
+	case reflect.Slice, reflect.Array:
+		put(uint64(rv.Len()))
+		for i := 0; i < rv.Len(); i++ {
+			hashValue(h, rv.Index(i), depth+1)
+		}
+	case reflect.Map:
+		// the iteration order is random, so the entries are combined with a commutative sum
+		var sum uint64
+		it := rv.MapRange()
+		for it.Next() {
+			e := fnv.New64a()
+			hashValue(e, it.Key(), depth+1)
+			hashValue(e, it.Value(), depth+1)
+			sum += e.Sum64()
+		}
+		put(uint64(rv.Len()))
+		put(sum)
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			hashValue(h, rv.Field(i), depth+1)
+		}
+	case reflect.Ptr, reflect.Interface:
+		hashValue(h, rv.Elem(), depth+1)
+	default:
+		// channels, functions and unsafe pointers are only distinguished by their kind
+	}
+}
+
+// exhausted reports whether the test cases have exhausted the domain of the
+// generators: all the draws are from generators with finite domains of known size,
+// and as many distinct test cases have been generated as the largest domain allows.
+func (st *runStats) exhausted() bool {
+	return st.domain >= 1 && st.domain <= exhaustMaxDistinct && float64(len(st.distinct)) >= st.domain
+}
+
+// domainSize returns the number of distinct values g can produce (or an upper
+// bound of it), or +Inf when it is not known without sampling the values.
+func domainSize(g *Generator) float64 {
+	switch impl := g.impl.(type) {
+	case *boolGen, *integerGen, *sampledGen, *faultGen:
+		return impl.(cardinalityEstimator).cardinality()
+	case *mappedGen:
+		return domainSize(impl.g) // the function can only merge the values
+	case *filteredGen:
+		return domainSize(impl.g)
+	case *shrinkHintGen:
+		return domainSize(impl.g) // candidates are values of g
+	case *sensitiveGen:
+		return domainSize(impl.g)
+	case *ptrGen:
+		n := domainSize(impl.elem)
+		if impl.allowNil {
+			n++
+		}
+		return n
+	case *oneOfGen:
+		sum := 0.0
+		for _, gen := range impl.gens {
+			sum += domainSize(gen)
+		}
+		return sum
+	case *arrayGen:
+		return math.Pow(domainSize(impl.elem), float64(impl.count))
+	default:
+		return math.Inf(1)
+	}
+}
+
+// reportDegenerate warns about (or, when fail is true, fails the test because of)
+// the labels under which the same value was drawn in more than ratio of the draws,
+// which usually means that the generator ignores its input.
+func reportDegenerate(tb tb, st *runStats, ratio float64, fail bool) {
+	tb.Helper()
+
+	for _, label := range st.draws {
+		if label == "" {
+			continue
+		}
+		ds := st.drawn[label]
+		total, max, maxValue := ds.total, 0, ""
+		for k, n := range ds.counts {
+			if v := ds.printed[k]; n > max || (n == max && v < maxValue) {
+				max, maxValue = n, v
+			}
+		}
+		if total < degenerateMinDraws || float64(max) <= ratio*float64(total) {
+			continue
+		}
+
+		msg := fmt.Sprintf("draw %v produced the same value %v in %.1f%% of %v draws, is the generator ignoring its input?", label, maxValue, percentage(max, total), total)
+		if fail {
+			tb.Errorf("[rapid] %v", msg)
+		} else {
+			tb.Logf("[rapid] WARNING: %v", msg)
+		}
+	}
+}
+
+// reportCoverage logs the statement coverage of the code under test before
+// and after the run. Which blocks were not reached is not available to a running
+// test, so the user is pointed to the coverage profile instead.
+func reportCoverage(tb tb, before float64, after float64) {
+	tb.Helper()
+
+	tb.Logf("[rapid] statement coverage is %.1f%% (%+.1f%% during the run); to see the code which was not reached, use -coverprofile and go tool cover -html",
+		100*after, 100*(after-before))
+}
+
+type actionOutcome int
+
+const (
+	actionDeclared actionOutcome = iota
+	actionDisabled
+	actionChosen
+	actionExecuted
+)
+
+type actionResult struct {
+	action  string
+	outcome actionOutcome
+}
+
+type actionStats struct {
+	disabled int // number of steps the action could not be chosen at
+	chosen   int
+	executed int // number of times the action was chosen and not skipped
+}
+
+type actionRequirement struct {
+	action  string
+	percent float64
+}
+
+func (t *T) recordAction(action string, outcome actionOutcome) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stats != nil {
+		t.stats.actions = append(t.stats.actions, actionResult{action, outcome})
+	}
+}
+
+func (st *runStats) addActions(cs *caseStats) {
+	for _, a := range cs.actions {
+		if st.actions == nil {
+			st.actions = map[string]*actionStats{}
+		}
+		as, ok := st.actions[a.action]
+		if !ok {
+			as = &actionStats{}
+			st.actions[a.action] = as
+		}
+		switch a.outcome {
+		case actionDisabled:
+			as.disabled++
+		case actionChosen:
+			as.chosen++
+		case actionExecuted:
+			as.executed++
+		}
+	}
+}
+
+// reportActions logs how often every state machine action was chosen,
+// executed and prevented from being chosen (by its precondition or zero weight),
+// and fails the test if some of the actions were executed less often than required.
+func reportActions(tb tb, st *runStats, required []actionRequirement) {
+	tb.Helper()
+
+	if len(st.actions) == 0 {
+		return
+	}
+
+	var names []string
+	steps := 0
+	for name, as := range st.actions {
+		names = append(names, name)
+		steps += as.executed
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		as := st.actions[name]
+		fmt.Fprintf(&b, "\n  %v: chosen %v, executed %v (%.1f%%), disabled at %v steps", name, as.chosen, as.executed, percentage(as.executed, steps), as.disabled)
+	}
+	tb.Logf("[rapid] actions (%v steps executed):%v", steps, b.String())
+
+	for _, r := range required {
+		as, ok := st.actions[r.action]
+		if !ok {
+			tb.Errorf("[rapid] action %q is not an action of the state machine", r.action)
+			continue
+		}
+		p := 0.0
+		if steps > 0 {
+			p = percentage(as.executed, steps)
+		}
+		if p < r.percent {
+			tb.Errorf("[rapid] action %q was executed in %.1f%% of steps, less than the required %v%%", r.action, p, r.percent)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFitComplexity(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range complexityClasses {
+		var sizes, durations []float64
+		for n := 1; n <= 100; n++ {
+			noise := 1 + 0.05*float64(n%3-1)
+			sizes = append(sizes, float64(n))
+			durations = append(durations, 1e-6*c.fn(float64(n))*noise)
+		}
+		if got := fitComplexity(sizes, durations); got != c.name {
+			t.Errorf("got %v instead of %v", got, c.name)
+		}
+	}
+
+	if got := fitComplexity([]float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 2}, make([]float64, 10)); got != "" {
+		t.Errorf("got %v with too few sizes", got)
+	}
+}
+
+func TestComplexityBaseline(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(filepath.Dir(tempFailFileName(t, t.Name())), "complexity")
+	for _, c := range []struct {
+		class string
+		base  string
+	}{
+		{"O(n)", ""},
+		{"O(n)", ""},
+		{"O(n²)", "O(n)"},
+	} {
+		base, err := checkComplexityBaseline(filename, c.class)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if base != c.base {
+			t.Fatalf("got baseline %q instead of %q for %v", base, c.base, c.class)
+		}
+	}
+}
+
+func TestRequireEvent(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		if IntRange(0, 99).Draw(t, "x").(int) < 10 {
+			t.Event("small")
+		}
+		t.Event("any")
+		t.Event("any")
+	}
+
+	for _, c := range []struct {
+		percent float64
+		failed  bool
+	}{
+		{1, false},
+		{90, true},
+	} {
+		tb := &logTB{T: t}
+		checkTB(tb, prop, RequireEvent("small", c.percent), RequireEvent("any", 100))
+		if tb.failed != c.failed {
+			t.Fatalf("got failed %v instead of %v with %v%% required:\n%v", tb.failed, c.failed, c.percent, tb.out.String())
+		}
+		out := tb.out.String()
+		if !strings.Contains(out, "test cases):\n  any: 100.0% (100)\n  small: ") {
+			t.Fatalf("events not found in output:\n%v", out)
+		}
+	}
+}
+
+type boundedCounterMachine struct {
+	n int
+}
+
+func (m *boundedCounterMachine) Inc(*T)                { m.n++ }
+func (m *boundedCounterMachine) IncPrecondition() bool { return m.n < 2 }
+func (m *boundedCounterMachine) Dec(*T)                { m.n-- }
+func (m *boundedCounterMachine) DecPrecondition() bool { return m.n > 0 }
+func (m *boundedCounterMachine) Reset(*T)              { m.n = 0 }
+func (m *boundedCounterMachine) ResetWeight() int      { return 0 }
+func (m *boundedCounterMachine) Check(*T)              {}
+
+func TestRequireAction(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range []struct {
+		action string
+		failed bool
+	}{
+		{"Inc", false},
+		{"Reset", true},
+	} {
+		tb := &logTB{T: t}
+		checkTB(tb, Run(&boundedCounterMachine{}), RequireAction(c.action, 10))
+		if tb.failed != c.failed {
+			t.Fatalf("got failed %v instead of %v with %v required:\n%v", tb.failed, c.failed, c.action, tb.out.String())
+		}
+		out := tb.out.String()
+		if !strings.Contains(out, "steps executed):\n  Dec: chosen ") || !strings.Contains(out, "\n  Reset: chosen 0, executed 0 (0.0%), disabled at ") {
+			t.Fatalf("actions not found in output:\n%v", out)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		t.Collect("b", Bool().Draw(t, "b"))
+		t.Collect("mod", IntRange(0, 8).Draw(t, "x").(int)%3)
+	})
+	if tb.failed {
+		t.Fatalf("check failed:\n%v", tb.out.String())
+	}
+
+	out := tb.out.String()
+	// the domain is exhausted before 100 test cases, with every value drawn
+	for _, s := range []string{`collected "b" (`, "\n  true: ", "\n  false: ", `collected "mod" (`, "\n  2: "} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in output:\n%v", s, out)
+		}
+	}
+}
+
+func TestFormatDistribution(t *testing.T) {
+	t.Parallel()
+
+	counts := map[string]int{"a": 2, "b": 1, "c": 1}
+	if s := formatDistribution(counts, 4); s != "\n  a: 50.0% (2)\n  b: 25.0% (1)\n  c: 25.0% (1)" {
+		t.Fatalf("got %q", s)
+	}
+
+	for i := 0; i < 2*distributionMaxRows; i++ {
+		counts[fmt.Sprint(i)] = 1
+	}
+	if s := formatDistribution(counts, 100); !strings.HasSuffix(s, fmt.Sprintf("\n  ... (%v more)", len(counts)-distributionMaxRows)) {
+		t.Fatalf("got %q", s)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		s := SliceOf(Int()).Draw(t, "s").([]int)
+		t.Classify(len(s) == 0, "empty")
+		t.Classify(len(s) > 1000, "huge")
+	}, RequireEvent("empty", 1), RequireEvent("huge", 1))
+
+	out := tb.out.String()
+	if !tb.failed || !strings.Contains(out, `event "huge" occurred in 0.0% of test cases`) || strings.Contains(out, `event "empty" occurred`) {
+		t.Fatalf("unexpected output:\n%v", out)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	t.Parallel()
+
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		t.Observe("x", float64(IntRange(0, 10).Draw(t, "x").(int)))
+	})
+
+	out := tb.out.String()
+	if !strings.Contains(out, " values): min 0, median ") || !strings.Contains(out, ", max 10\n") {
+		t.Fatalf("observations not found in output:\n%v", out)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for _, c := range []struct {
+		p    float64
+		want float64
+	}{
+		{0, 1},
+		{50, 5},
+		{90, 9},
+		{100, 10},
+	} {
+		if got := percentile(values, c.p); got != c.want {
+			t.Errorf("got p%v %v instead of %v", c.p, got, c.want)
+		}
+	}
+}
+
+func TestMaxDiscardRatio(t *testing.T) {
+	t.Parallel()
+
+	prop := func(t *T) {
+		x := IntRange(0, 9).Filter(func(x int) bool { return x < 5 }).Draw(t, "x").(int)
+		if x == 0 {
+			t.Skip("zero")
+		}
+	}
+
+	for _, c := range []struct {
+		ratio  float64
+		failed bool
+	}{
+		{0, false},
+		{0.99, false},
+		{0.001, true},
+	} {
+		tb := &logTB{T: t}
+		checkTB(tb, prop, MaxDiscardRatio(c.ratio))
+		out := tb.out.String()
+		if tb.failed != c.failed {
+			t.Fatalf("got failed %v instead of %v with maximum discard ratio %v:\n%v", tb.failed, c.failed, c.ratio, out)
+		}
+		if !strings.Contains(out, "[rapid] discarded ") || !strings.Contains(out, "\n  IntRange(0, 9).Filter(...): rejected ") {
+			t.Fatalf("discards not found in output:\n%v", out)
+		}
+	}
+}
+
+func TestDegenerateGenerator(t *testing.T) {
+	t.Parallel()
+
+	broken := Custom(func(t *T) int {
+		Int().Draw(t, "ignored")
+		return 42
+	})
+	prop := func(t *T) {
+		broken.Draw(t, "broken")
+		Int().Draw(t, "fine")
+	}
+
+	for _, opts := range [][]Option{nil, {FailOnDegenerate(0.5)}} {
+		tb := &logTB{T: t}
+		checkTB(tb, prop, opts...)
+		out := tb.out.String()
+		if tb.failed != (len(opts) > 0) {
+			t.Fatalf("got failed %v with %v options:\n%v", tb.failed, len(opts), out)
+		}
+		if !strings.Contains(out, "draw broken produced the same value 42 in 100.0% of 100 draws") || strings.Contains(out, "draw fine produced") {
+			t.Fatalf("unexpected output:\n%v", out)
+		}
+	}
+}
+
+func TestExhaustedDomain(t *testing.T) {
+	t.Parallel()
+
+	n := 0
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		Bool().Draw(t, "b")
+		IntRange(0, 2).Draw(t, "i")
+		n++
+	}, func(s *settings) { s.checks = 1000 })
+
+	out := tb.out.String()
+	if tb.failed || n >= 1000 || !strings.Contains(out, "covering all 6 distinct test cases") {
+		t.Fatalf("got %v test cases, output:\n%v", n, out)
+	}
+}
+
+func TestExhaustedDomain_Skewed(t *testing.T) {
+	t.Parallel()
+
+	rare := Custom(func(t *T) int {
+		if IntRange(0, 99).Draw(t, "p").(int) > 0 {
+			return 0
+		}
+		return Int().Draw(t, "v").(int)
+	})
+	for _, g := range []*Generator{rare, IntRange(0, 1000).Filter(func(i int) bool { return i < 3 })} {
+		n := 0
+		tb := &logTB{T: t}
+		checkTB(tb, func(t *T) {
+			g.Draw(t, "i")
+			n++
+		}, func(s *settings) { s.checks = 1000 })
+
+		out := tb.out.String()
+		if tb.failed || n != 1000 || strings.Contains(out, "covering all") {
+			t.Fatalf("%v: got %v test cases, output:\n%v", g, n, out)
+		}
+	}
+}
+
+func TestReportCoverage(t *testing.T) {
+	t.Parallel()
+
+	cov := 0.25
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		Int().Draw(t, "x")
+	}, func(s *settings) {
+		s.coverage = func() float64 {
+			cov += 0.001
+			return cov
+		}
+	})
+
+	if out := tb.out.String(); !strings.Contains(out, "[rapid] statement coverage is 35.") || !strings.Contains(out, "(+10.") {
+		t.Fatalf("coverage not found in output:\n%v", out)
+	}
+}
+
+func TestValueHash(t *testing.T) {
+	t.Parallel()
+
+	type pair struct {
+		a int
+		b string
+	}
+	same := [][2]value{
+		{map[int]string{1: "a", 2: "b", 3: "c"}, map[int]string{3: "c", 2: "b", 1: "a"}},
+		{&pair{1, "x"}, &pair{1, "x"}},
+		{[]int{1, 2}, []int{1, 2}},
+	}
+	for _, vv := range same {
+		if valueHash(vv[0]) != valueHash(vv[1]) {
+			t.Errorf("different hashes of %v and %v", vv[0], vv[1])
+		}
+	}
+	different := [][2]value{
+		{1, int8(1)},
+		{pair{1, "x"}, pair{1, "y"}},
+		{[]string{"ab", "c"}, []string{"a", "bc"}},
+		{math.Copysign(0, -1), 0.0},
+	}
+	for _, vv := range different {
+		if valueHash(vv[0]) == valueHash(vv[1]) {
+			t.Errorf("same hash of %v and %v", vv[0], vv[1])
+		}
+	}
+}
+
+func TestDrawStatsCapped(t *testing.T) {
+	t.Parallel()
+
+	st := &runStats{}
+	for i := 0; i < 2*degenerateMaxValues; i++ {
+		st.addCase(&caseStats{drawn: []drawnValue{{label: "i", v: i}}})
+	}
+	ds := st.drawn["i"]
+	if ds.total != 2*degenerateMaxValues || len(ds.counts) != degenerateMaxValues || len(ds.printed) != degenerateMaxValues {
+		t.Fatalf("got %v draws, %v counted and %v printed values", ds.total, len(ds.counts), len(ds.printed))
+	}
+}
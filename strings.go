@@ -18,6 +18,8 @@ import (
 	"unicode/utf8"
 )
 
+const runeLabel = "rune"
+
 var (
 	stringType    = reflect.TypeOf("")
 	byteSliceType = reflect.TypeOf([]byte(nil))
@@ -131,6 +133,7 @@ func (g *runeGen) type_() reflect.Type {
 }
 
 func (g *runeGen) value(t *T) value {
+	i := t.s.beginGroup(runeLabel, false)
 	n := g.die.roll(t.s)
 
 	runes := g.runes
@@ -140,7 +143,10 @@ func (g *runeGen) value(t *T) value {
 		runes = g.tables[n-1]
 	}
 
-	return runes[genIndex(t.s, len(runes), true)]
+	r := runes[genIndex(t.s, len(runes), true)]
+	t.s.endGroup(i, false)
+
+	return r
 }
 
 func String() *Generator {
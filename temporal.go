@@ -0,0 +1,99 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+// temporalProp is a property over the sequence of the states
+// of a state machine test case.
+type temporalProp struct {
+	name   string
+	cond   func() bool
+	always bool
+	step   int // step the property has been registered in, -1 if the step has not finished
+	action string
+}
+
+// Always registers a property which should hold after every step of the state
+// machine test case (see Run), starting with the step which registers it
+// (e.g. Init). A violation fails the test case, reporting the step after which
+// the property does not hold.
+func (t *T) Always(name string, cond func() bool) {
+	t.addTemporal(temporalProp{name: name, cond: cond, always: true})
+}
+
+// Eventually registers a property which should hold after some step of the state
+// machine test case (see Run), starting with the step which registers it.
+// For example, an action which closes a file can register that eventually,
+// all the writes to it fail. A test case which ends before the property
+// has held fails, reporting the step which has registered it.
+func (t *T) Eventually(name string, cond func() bool) {
+	t.addTemporal(temporalProp{name: name, cond: cond})
+}
+
+func (t *T) addTemporal(p temporalProp) {
+	assertf(p.cond != nil, "condition of %v should not be nil", p.name)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p.step = -1
+	t.temporal = append(t.temporal, p)
+}
+
+// checkTemporal checks the properties after the step, and forgets
+// the ones which have eventually held.
+func (t *T) checkTemporal(step int, action string) {
+	t.mu.Lock()
+	props := t.temporal
+	t.mu.Unlock()
+
+	var left []temporalProp
+	for _, p := range props {
+		if p.step < 0 {
+			p.step, p.action = step, action
+		}
+		holds := p.cond()
+		if p.always && !holds {
+			t.Fatalf("%v does not hold after step %v (%v)", p.name, step, action)
+		}
+		if p.always || !holds {
+			left = append(left, p)
+		}
+	}
+
+	t.mu.Lock()
+	t.temporal = append(left, t.temporal[len(props):]...)
+	t.mu.Unlock()
+}
+
+// abortTemporal forgets the properties registered by the step,
+// which has been aborted.
+func (t *T) abortTemporal() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	props := t.temporal[:0]
+	for _, p := range t.temporal {
+		if p.step >= 0 {
+			props = append(props, p)
+		}
+	}
+	t.temporal = props
+}
+
+// checkEventually fails the test case, which has ended, when a property
+// has not eventually held.
+func (t *T) checkEventually() {
+	t.mu.Lock()
+	props := t.temporal
+	t.mu.Unlock()
+
+	for _, p := range props {
+		if !p.always {
+			t.Fatalf("%v has not held since step %v (%v)", p.name, p.step, p.action)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const traceArgSep = ": "
+
+// traceStep is a step of a state machine trace file: an action,
+// and the values of the top-level draws it makes.
+type traceStep struct {
+	action string
+	args   []traceArg
+}
+
+// traceArg is a value read from a trace file, which is decoded
+// when the type of the draw it is replayed for is known.
+type traceArg struct {
+	label string
+	raw   json.RawMessage
+}
+
+// traceFile formats the steps of the trace (including the failed one,
+// if any) as a trace file. Every step is a line with the name of the action,
+// followed by an indented line for every value it has drawn, encoded as JSON.
+func (tr *machineTrace) traceFile(t *T, name string) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# rapid state machine trace of %v\n", name)
+	b.WriteString("# edit the steps and values, and run them using rapid.ReplayTrace\n")
+	for _, step := range tr.all(t) {
+		fmt.Fprintf(&b, "%v\n", step.action)
+		for _, d := range step.args {
+			raw, err := json.Marshal(d.v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode value of draw %v of %v: %w", d.label, step.action, err)
+			}
+			fmt.Fprintf(&b, "\t%v%v%s\n", d.label, traceArgSep, raw)
+		}
+	}
+
+	return []byte(b.String()), nil
+}
+
+func saveMachineTrace(filename string, t *T, name string) error {
+	data, err := t.trace.traceFile(t, name)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, data)
+}
+
+func loadMachineTrace(filename string) ([]traceStep, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trace file %q: %w", filename, err)
+	}
+
+	var steps []traceStep
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case trimmed != line:
+			i := strings.Index(trimmed, traceArgSep)
+			if i < 0 || len(steps) == 0 {
+				return nil, fmt.Errorf("%v:%v: invalid value line %q", filename, n, line)
+			}
+			step := &steps[len(steps)-1]
+			step.args = append(step.args, traceArg{label: trimmed[:i], raw: json.RawMessage(trimmed[i+len(traceArgSep):])})
+		default:
+			steps = append(steps, traceStep{action: trimmed})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trace file %q: %w", filename, err)
+	}
+	if len(steps) == 0 || steps[0].action != initMethodName {
+		return nil, fmt.Errorf("trace file %q should start with the %v step", filename, initMethodName)
+	}
+
+	return steps, nil
+}
+
+// ReplayTrace runs the steps of the state machine trace file (written for
+// a failing test case by ExportStateMachineTraces) against a new instance
+// of the state machine type of m, returning the values from the file
+// from the top-level draws of Init and of the actions, and calling Check
+// after every step. The file can be edited by hand, e.g. to change a value
+// or to remove a step, to see how the system under test reacts.
+//
+// Every step starts with a line with the name of the action, followed by
+// indented "label: value" lines for the values it draws, encoded as JSON.
+// Values which can not be encoded as JSON (e.g. values of interface types)
+// can not be replayed.
+func ReplayTrace(t *testing.T, m StateMachine, filename string) {
+	t.Helper()
+
+	steps, err := loadMachineTrace(filename)
+	if err != nil {
+		t.Fatalf("[rapid] %v", err)
+	}
+
+	s := newBufBitStream(nil, false)
+	s.zeroPad = true // for the data drawn without generators
+	nt := newT(t, s, true, nil)
+	typ := reflect.TypeOf(m)
+	err1 := checkOnce(nt, func(t *T) { replayMachine(t, typ, steps) })
+	if err1 != nil {
+		if err1.isStopTest() {
+			t.Fatalf("[rapid] replay failed: %v", err1)
+		} else {
+			t.Fatalf("[rapid] replay panicked: %v\nTraceback:\n%v", err1, traceback(err1))
+		}
+	}
+}
+
+func replayMachine(t *T, typ reflect.Type, steps []traceStep) {
+	t.Helper()
+
+	sm := newStateMachine(typ)
+	t.temporal = nil
+	replayStep(t, 0, steps[0], func() {
+		if sm.init != nil {
+			sm.init(t)
+			t.failOnError()
+		}
+	})
+	if sm.cleanup != nil {
+		defer sm.cleanup()
+	}
+	sm.checkAfter(t, 0, initMethodName)
+	t.checkTemporal(0, initMethodName)
+
+	for i, step := range steps[1:] {
+		a, ok := sm.actions[step.action]
+		if !ok {
+			t.Fatalf("[rapid] step %v: unknown action %v", i+1, step.action)
+		}
+		if a.pre != nil && !a.pre() {
+			t.Fatalf("[rapid] step %v: precondition of %v does not hold", i+1, step.action)
+		}
+
+		invalid := false
+		replayStep(t, i+1, step, func() {
+			invalid, _ = runAction(t, step.action, a)
+		})
+		if invalid {
+			t.Fatalf("[rapid] step %v: %v has rejected the replayed values", i+1, step.action)
+		}
+		sm.checkAfter(t, i+1, step.action)
+		t.checkTemporal(i+1, step.action)
+	}
+	t.checkEventually()
+}
+
+// replayStep runs the step, replaying its values.
+func replayStep(t *T, n int, step traceStep, run func()) {
+	t.Helper()
+	t.Logf("[rapid] step %v: %v", n, step.action)
+
+	t.replay = make([]value, 0, len(step.args))
+	for _, arg := range step.args {
+		t.replay = append(t.replay, arg)
+	}
+	run()
+	left := len(t.replay)
+	t.replay = nil // Check draws new values
+
+	if left != 0 {
+		t.Fatalf("[rapid] step %v: %v used only %v of %v values", n, step.action, len(step.args)-left, len(step.args))
+	}
+}
+
+// decode returns the value of the argument as a value of type typ.
+func (arg traceArg) decode(typ reflect.Type) (value, error) {
+	p := reflect.New(typ)
+	if err := json.Unmarshal(arg.raw, p.Interface()); err != nil {
+		return nil, err
+	}
+
+	return p.Elem().Interface(), nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// outputLimits bounds the output of a test case.
+type outputLimits struct {
+	maxValue  int    // maximum rendered length of a drawn value, 0 for no limit
+	maxReport int    // maximum length of the logged output, 0 for no limit
+	valueDir  string // if not empty, directory to write the full truncated values to
+	mu        sync.Mutex
+	logged    int
+	omitted   int
+}
+
+func withOutputLimits(prop func(*T), maxValue int, maxReport int, valueDir string) func(*T) {
+	return func(t *T) {
+		t.limits = &outputLimits{maxValue: maxValue, maxReport: maxReport, valueDir: valueDir}
+		prop(t)
+	}
+}
+
+// allow reports whether the message fits into the output of the test case,
+// counting the omitted bytes otherwise.
+func (l *outputLimits) allow(msg string) bool {
+	if l == nil || l.maxReport <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.omitted > 0 || l.logged+len(msg) > l.maxReport {
+		l.omitted += len(msg)
+		return false
+	}
+	l.logged += len(msg)
+	return true
+}
+
+// omittedBytes returns the length of the output which has not been logged.
+func (l *outputLimits) omittedBytes() int {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.omitted
+}
+
+// printDrawn renders the drawn value, truncated to the maximum length of a value.
+func (t *T) printDrawn(d drawnValue) string {
+	if t.limits == nil {
+		return d.String()
+	}
+	return d.truncated(t.limits.maxValue)
+}
+
+// truncated renders the drawn value in about max bytes (see truncateValue).
+func (d drawnValue) truncated(max int) string {
+	if d.sensitive {
+		return d.String()
+	}
+	return truncateValue(d.v, max)
+}
+
+// logDrawn renders the drawn value for the log. Truncated values are written
+// in full to a file, if the directory for them is set.
+func (t *T) logDrawn(d drawnValue) string {
+	s := t.printDrawn(d)
+	if t.limits == nil || t.limits.valueDir == "" || t.tb == nil {
+		return s
+	}
+	if full := d.String(); full != s {
+		filename := filepath.Join(t.limits.valueDir, persistFileName(fmt.Sprintf("%v-%v", t.tb.Name(), t.draws), "txt"))
+		if err := writeFileAtomic(filename, []byte(full+"\n")); err != nil {
+			return fmt.Sprintf("%v (%v)", s, err)
+		}
+		return fmt.Sprintf("%v (full value written to %q)", s, filename)
+	}
+	return s
+}
+
+// truncateValue renders v like printValue, in about max bytes (or fully, if max is 0).
+// Slices, arrays and maps lose their last elements, and other values their last bytes,
+// with a marker of how much has been omitted.
+func truncateValue(v interface{}, max int) string {
+	s := printValue(v)
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		elems := make([]string, rv.Len())
+		for i := range elems {
+			elems[i] = printReflect(rv.Index(i))
+		}
+		return truncateElems(rv.Type().String(), elems, max)
+	case reflect.Map:
+		elems := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			elems = append(elems, printReflect(k)+":"+printReflect(rv.MapIndex(k)))
+		}
+		sort.Strings(elems)
+		return truncateElems(rv.Type().String(), elems, max)
+	case reflect.String:
+		str := rv.String()
+		n := max
+		for n > 0 && !utf8.RuneStart(str[n]) {
+			n--
+		}
+		return fmt.Sprintf("%#v… %d more bytes", str[:n], len(str)-n)
+	default:
+		n := max
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		return fmt.Sprintf("%v… %d more bytes", s[:n], len(s)-n)
+	}
+}
+
+func truncateElems(typ string, elems []string, max int) string {
+	n, size := 0, len(typ)
+	for ; n < len(elems) && size+len(elems[n]) <= max; n++ {
+		size += len(elems[n]) + len(", ")
+	}
+
+	head := append(elems[:n:n], fmt.Sprintf("… %d more elements", len(elems)-n))
+	return fmt.Sprintf("%v{%v}", typ, strings.Join(head, ", "))
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTruncateValue(t *testing.T) {
+	t.Parallel()
+
+	type point struct{ X, Y int }
+	tests := []struct {
+		v    interface{}
+		max  int
+		want string
+	}{
+		{[]int{1, 2, 3}, 0, "[]int{1, 2, 3}"},
+		{[]int{1, 2, 3}, 100, "[]int{1, 2, 3}"},
+		{[]int{10, 20, 30, 40, 50}, 12, "[]int{10, 20, … 3 more elements}"},
+		{[]int{12345678}, 5, "[]int{… 1 more elements}"},
+		{map[string]int{"a": 1, "b": 2, "c": 3}, 30, `map[string]int{"a":1, "b":2, … 1 more elements}`},
+		{"abcdefgh", 3, `"abc"… 5 more bytes`},
+		{"ééé", 3, `"é"… 4 more bytes`},
+		{point{123, 456}, 12, "rapid.point{… 13 more bytes"},
+	}
+
+	for _, tt := range tests {
+		if got := truncateValue(tt.v, tt.max); got != tt.want {
+			t.Errorf("truncateValue(%#v, %v) = %q, want %q", tt.v, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestOutputLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rapid-values")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	tb := &logTB{T: t}
+	checkTB(tb, func(t *T) {
+		s := SliceOfN(Int(), 100, 100).Draw(t, "s").([]int)
+		for i := range s {
+			t.Logf("element %v", i)
+		}
+		t.Fatal("fail")
+	}, MaxValueLength(50), MaxReportLength(200), ExportTruncatedValues(dir))
+	removeFailFiles(t.Name())
+
+	out := tb.out.String()
+	for _, s := range []string{"more elements} (full value written to", "more bytes of failed test output omitted"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in output:\n%v", s, out)
+		}
+	}
+	if strings.Contains(out, "element 99") {
+		t.Errorf("output has not been truncated:\n%v", out)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if len(files) == 0 {
+		t.Fatalf("no full values written to %v", dir)
+	}
+	b, err := ioutil.ReadFile(files[0])
+	if err != nil || !strings.HasPrefix(string(b), "[]int{0, 0, ") {
+		t.Errorf("full value %q is not written (%v)", b, err)
+	}
+}
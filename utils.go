@@ -61,6 +61,26 @@ func genUintNUnbiased(s bitStream, max uint64) uint64 {
 	}
 }
 
+// coinFlipWord returns the data which makes flipBiasedCoin(s, p) return heads
+// when p > 0, or tails when p < 1.
+func coinFlipWord(heads bool) uint64 {
+	if heads {
+		return math.MaxUint64
+	}
+	return 0
+}
+
+// uintNBiasedWords returns the data which makes genUintNBiased(s, max) return u,
+// without reporting an overflow.
+func uintNBiasedWords(max uint64, u uint64) []uint64 {
+	bitlen := bits.Len64(max)
+	m := math.Max(8, (float64(bitlen)+48)/7)
+	n := math.Max(1, float64(bitlen))   // all the bits are drawn, and the value is never a left overflow
+	f := 1 - math.Pow(1-1/(m+1), n+0.5) // genGeom returns n
+
+	return []uint64{uint64(f * (1 << 53)), u}
+}
+
 func genUintNBiased(s bitStream, max uint64) (uint64, bool, bool) {
 	bitlen := bits.Len64(max)
 	i := s.beginGroup(biasLabel, false)
@@ -139,6 +159,69 @@ func genIntRange(s bitStream, min int64, max int64, bias bool) (int64, bool, boo
 	}
 }
 
+// intRangeWords returns the data which makes genIntRange(s, min, max, true) return v.
+func intRangeWords(min int64, max int64, v int64) []uint64 {
+	// same choices as genIntRange
+	switch {
+	case min >= 0:
+		return append([]uint64{coinFlipWord(false)}, uintNBiasedWords(uint64(max-min), uint64(v-min))...)
+	case max <= 0:
+		return append([]uint64{coinFlipWord(true)}, uintNBiasedWords(uint64(-min)-uint64(-max), uint64(-v)-uint64(-max))...)
+	case v < 0:
+		return append([]uint64{coinFlipWord(true)}, uintNBiasedWords(uint64(-min)-1, uint64(-v)-1)...)
+	default:
+		return append([]uint64{coinFlipWord(false)}, uintNBiasedWords(uint64(max), uint64(v))...)
+	}
+}
+
+// genOffset generates an offset from some central value, at most below
+// in the negative direction, and at most above in the positive one.
+// Zero offset is the simplest one.
+func genOffset(s bitStream, below uint64, above uint64, bias bool) (bool, uint64) {
+	var negMin uint64
+	var pNeg float64
+	if below == 0 {
+		pNeg = 0
+	} else if above == 0 {
+		pNeg = 1
+	} else {
+		negMin = 1
+		pNeg = float64(below) / (float64(below) + float64(above) + 1)
+		if bias {
+			pNeg = 0.5
+		}
+	}
+
+	if flipBiasedCoin(s, pNeg) {
+		u, _, _ := genUintRange(s, negMin, below, bias)
+		return true, u
+	} else {
+		u, _, _ := genUintRange(s, 0, above, bias)
+		return false, u
+	}
+}
+
+// genShrinkHint draws a hint, which should precede the value it applies to, and
+// returns the index of the domain-specific shrink candidate to use instead of the
+// value, or -1 to use the value as-is. A single block from 1 to shrinkHintMax
+// selects a candidate; otherwise the hint is two blocks. So the data selecting a
+// candidate is always shorter (and simpler, for the shrinker) than the data
+// selecting the value, and only the shrinker selects candidates (see shrinkHints):
+// random data (wide draws, which do not consume the seed), zeroed data and
+// almost any other data select the value, and leave the rest of the data (and so
+// the distribution of the values) unchanged.
+func genShrinkHint(s bitStream) int {
+	i := s.beginGroup(shrinkHintLabel, false)
+	h := s.drawBits(shrinkHintBits)
+	if h == 0 || h > shrinkHintMax {
+		_ = s.drawBits(shrinkHintBits)
+		h = 0
+	}
+	s.endGroup(i, false)
+
+	return int(h) - 1
+}
+
 func genIndex(s bitStream, n int, bias bool) int {
 	assert(n > 0)
 
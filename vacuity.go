@@ -0,0 +1,172 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"math"
+	"reflect"
+)
+
+const corruptionKinds = 3
+
+// mutation is a known bug the property should detect (see KnownBadMutation).
+type mutation struct {
+	name   string
+	inject func() func()
+}
+
+// corruption replaces the value of a top-level draw of the test case
+// with a corrupted one (see corruptValue).
+type corruption struct {
+	draw    int
+	kind    int
+	applied bool
+}
+
+func (c *corruption) apply(draw int, v value) value {
+	if draw != c.draw {
+		return v
+	}
+	if cv, ok := corruptValue(v, c.kind); ok {
+		c.applied = true
+		return cv
+	}
+	return v
+}
+
+// corruptValue returns a value of the type of v which its generator is
+// unlikely to produce: the zero value (kind 0), or an extreme one.
+func corruptValue(v value, kind int) (value, bool) {
+	r := reflect.ValueOf(v)
+	c := reflect.New(r.Type()).Elem()
+
+	if kind > 0 {
+		switch r.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if kind == 1 {
+				c.SetInt(math.MaxInt64 >> (64 - r.Type().Bits()))
+			} else {
+				c.SetInt(math.MinInt64 >> (64 - r.Type().Bits()))
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if kind != 1 {
+				return nil, false
+			}
+			c.SetUint(math.MaxUint64 >> (64 - r.Type().Bits()))
+		case reflect.Float32, reflect.Float64:
+			if kind == 1 {
+				c.SetFloat(math.Inf(1))
+			} else {
+				c.SetFloat(math.NaN())
+			}
+		case reflect.Bool:
+			if kind != 1 {
+				return nil, false
+			}
+			c.SetBool(!r.Bool())
+		case reflect.String:
+			if kind != 1 {
+				return nil, false
+			}
+			c.SetString(r.String() + "\x00\xff") // invalid UTF-8
+		case reflect.Slice:
+			if r.Len() == 0 {
+				return nil, false
+			}
+			if kind == 1 {
+				c.Set(reflect.Append(reflect.AppendSlice(reflect.MakeSlice(r.Type(), 0, r.Len()+1), r), r.Index(0)))
+			} else {
+				c.Set(r.Slice(1, r.Len()))
+			}
+		default:
+			return nil, false
+		}
+	}
+
+	cv := c.Interface()
+	if reflect.DeepEqual(cv, v) {
+		return nil, false
+	}
+	return cv, true
+}
+
+// checkVacuity verifies that the property, which has passed the check, can
+// fail: with a corrupted top-level draw (if cfg.vacuity is set), and with each
+// of the known bad mutations.
+func checkVacuity(tb tb, cfg *settings, seed uint64, prop func(*T)) {
+	tb.Helper()
+
+	if cfg.vacuity {
+		n, err := findCorruptedFailure(tb, cfg.checks, seed, prop)
+		if err == nil {
+			tb.Errorf("[rapid] property may be vacuous: it has passed all %v test cases with corrupted draws (its assertions may be tautologies)", n)
+		} else {
+			tb.Logf("[rapid] property fails with corrupted draws: %v", err)
+		}
+	}
+
+	for _, m := range cfg.mutations {
+		mcfg := *cfg
+		mcfg.stats = nil
+		valid, err := findMutationFailure(tb, &mcfg, seed, prop, m)
+		if err == nil {
+			tb.Errorf("[rapid] property is vacuous: it has passed %v tests with the known bad mutation %q", valid, m.name)
+		} else {
+			tb.Logf("[rapid] property fails with the known bad mutation %q: %v", m.name, err)
+		}
+	}
+}
+
+// findCorruptedFailure checks the property with the test cases of at most checks
+// seeds, corrupting one top-level draw at a time, until one of them fails.
+// It returns the number of the corrupted test cases.
+func findCorruptedFailure(tb tb, checks int, seed uint64, prop func(*T)) (int, *testError) {
+	tb.Helper()
+
+	var (
+		s = newRandomBitStream(0, false)
+		n = 0
+	)
+	for i := 0; i < checks && n < checks; i++ {
+		s.init(seed + uint64(i))
+		t := newT(tb, s, false, nil)
+		if err := checkOnce(t, prop); err != nil {
+			continue
+		}
+
+		draws := t.draws
+		for d := 0; d < draws && n < checks; d++ {
+			for kind := 0; kind < corruptionKinds && n < checks; kind++ {
+				s.init(seed + uint64(i))
+				t := newT(tb, s, false, nil)
+				t.corrupt = &corruption{draw: d, kind: kind}
+				err := checkOnce(t, prop)
+				if !t.corrupt.applied {
+					continue
+				}
+				n++
+				if err != nil && !err.isInvalidData() {
+					return n, err
+				}
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// findMutationFailure checks the property with the mutation injected.
+// It returns the number of valid test cases, and the failure, if any.
+func findMutationFailure(tb tb, cfg *settings, seed uint64, prop func(*T), m mutation) (int, *testError) {
+	tb.Helper()
+
+	undo := m.inject()
+	defer undo()
+
+	_, _, valid, _, err := findBug(tb, cfg, &corpus{}, seed, prop)
+	return valid, err
+}
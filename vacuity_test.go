@@ -0,0 +1,108 @@
+// Copyright 2026 Gregory Petrosyan <gregory.petrosyan@gmail.com>
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rapid
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var absInt = func(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func TestCheckVacuity(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		prop    func(*T)
+		opts    []Option
+		failed  bool
+		message string
+	}{
+		{"tautology", func(t *T) {
+			s := SliceOf(Int()).Draw(t, "s").([]int)
+			if len(s) < 0 {
+				t.Fatalf("negative length")
+			}
+		}, []Option{CheckVacuity()}, true, "property may be vacuous"},
+		{"depends on draws", func(t *T) {
+			i := IntRange(-100, 100).Draw(t, "i").(int)
+			if absInt(i) > 100 {
+				t.Fatalf("%v is out of range", i)
+			}
+		}, []Option{CheckVacuity()}, false, "property fails with corrupted draws"},
+		{"detects mutation", func(t *T) {
+			if absInt(IntRange(-100, 100).Draw(t, "i").(int)) < 0 {
+				t.Fatalf("negative")
+			}
+		}, []Option{KnownBadMutation("identity", func() func() {
+			orig := absInt
+			absInt = func(i int) int { return i }
+			return func() { absInt = orig }
+		})}, false, `property fails with the known bad mutation "identity"`},
+		{"misses mutation", func(t *T) {
+			i := Int().Draw(t, "i").(int)
+			if absInt(i) != absInt(i) {
+				t.Fatalf("not deterministic")
+			}
+		}, []Option{KnownBadMutation("identity", func() func() {
+			orig := absInt
+			absInt = func(i int) int { return i }
+			return func() { absInt = orig }
+		})}, true, `property is vacuous: it has passed 100 tests with the known bad mutation "identity"`},
+	} {
+		tb := &logTB{T: t}
+		checkTB(tb, tt.prop, tt.opts...)
+		removeFailFiles(t.Name())
+
+		out := tb.out.String()
+		if tb.failed != tt.failed || !strings.Contains(out, tt.message) {
+			t.Errorf("%v: failed %v, %q not found in output:\n%v", tt.name, tb.failed, tt.message, out)
+		}
+	}
+	if absInt(-1) != 1 {
+		t.Errorf("mutation has not been undone")
+	}
+}
+
+func TestCorruptValue(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		v    interface{}
+		kind int
+		want interface{}
+		ok   bool
+	}{
+		{5, 0, 0, true},
+		{0, 0, nil, false},
+		{int8(5), 1, int8(math.MaxInt8), true},
+		{int16(5), 2, int16(math.MinInt16), true},
+		{uint32(5), 1, uint32(math.MaxUint32), true},
+		{uint32(5), 2, nil, false},
+		{true, 1, false, true},
+		{"a", 1, "a\x00\xff", true},
+		{[]int{1, 2}, 1, []int{1, 2, 1}, true},
+		{[]int{1, 2}, 2, []int{2}, true},
+		{[]int{}, 1, nil, false},
+		{struct{ A int }{1}, 0, struct{ A int }{}, true},
+		{struct{ A int }{1}, 1, nil, false},
+	} {
+		got, ok := corruptValue(tt.v, tt.kind)
+		if ok != tt.ok || ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("corruptValue(%#v, %v) = %#v, %v; want %#v, %v", tt.v, tt.kind, got, ok, tt.want, tt.ok)
+		}
+	}
+	if f, _ := corruptValue(1.5, 2); !math.IsNaN(f.(float64)) {
+		t.Errorf("got %v instead of NaN", f)
+	}
+}